@@ -0,0 +1,146 @@
+// Command simulate estimates the return-to-player of a game config by
+// playing many rounds through the same engine code as real bets.
+//
+// The config is the game's current one when MONGODB_URI is set (read only,
+// from MONGODB_DB like the server) and the built-in default otherwise.
+// -config replaces it with a proposed config read from a JSON file, and
+// -house-edge overrides the house edge:
+//
+//	go run ./cmd/simulate -game dice -choices '{"target": 50, "direction": "over"}'
+//	go run ./cmd/simulate -game slot -config proposed_slot.json -rounds 5000000
+//	go run ./cmd/simulate -game aviation -choices '{"cashout_at": 2}'
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"betting-app-backend-go/models"
+	"betting-app-backend-go/services"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func main() {
+	gameType := flag.String("game", "", "game type to simulate")
+	rounds := flag.Int64("rounds", 1000000, "number of rounds")
+	choicesJSON := flag.String("choices", "", "player choices as a JSON object")
+	configPath := flag.String("config", "", "JSON file with a proposed config")
+	houseEdge := flag.Float64("house-edge", -1, "house edge in percent (default: from the settings)")
+	seed := flag.Uint64("seed", uint64(time.Now().UnixNano()), "random seed, to reproduce a run")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	if *gameType == "" {
+		log.Fatal("[Simulate] ❌ -game is required")
+	}
+
+	ctx := context.Background()
+	settings, err := loadSettings(ctx, *gameType)
+	if err != nil {
+		log.Fatalf("[Simulate] ❌ %v", err)
+	}
+
+	if *configPath != "" {
+		raw, err := os.ReadFile(*configPath)
+		if err != nil {
+			log.Fatalf("[Simulate] ❌ Failed to read config: %v", err)
+		}
+		settings.Config = nil
+		if err := json.Unmarshal(raw, &settings.Config); err != nil {
+			log.Fatalf("[Simulate] ❌ Invalid config: %v", err)
+		}
+		settings.Version = 0
+	}
+	if *houseEdge >= 0 {
+		settings.HouseEdge = *houseEdge
+		settings.Version = 0
+	}
+	if err := services.ValidateGameSettings(*gameType, settings); err != nil {
+		log.Fatalf("[Simulate] ❌ %v", err)
+	}
+
+	var choices map[string]interface{}
+	if *choicesJSON != "" {
+		if err := json.Unmarshal([]byte(*choicesJSON), &choices); err != nil {
+			log.Fatalf("[Simulate] ❌ Invalid choices: %v", err)
+		}
+	}
+
+	games := services.NewGameService(nil, nil, nil, nil)
+	report, err := games.Simulate(ctx, settings, services.SimulationOptions{
+		Rounds:  *rounds,
+		Choices: choices,
+		Seed:    *seed,
+	})
+	if err != nil {
+		log.Fatalf("[Simulate] ❌ %v", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+		return
+	}
+	printReport(report)
+}
+
+// loadSettings returns the game's current settings from MongoDB, or its
+// built-in defaults when no database is configured
+func loadSettings(ctx context.Context, gameType string) (*models.GameSettings, error) {
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		for _, settings := range services.DefaultGameSettings() {
+			if settings.GameType == gameType {
+				log.Printf("[Simulate] MONGODB_URI not set, using the default %s settings\n", gameType)
+				return &settings, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown game type: %s", gameType)
+	}
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Disconnect(ctx)
+
+	dbName := os.Getenv("MONGODB_DB")
+	if dbName == "" {
+		dbName = "betting"
+	}
+	settings, err := services.NewGameSettingsService(client.Database(dbName)).GetGameSettings(ctx, gameType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s settings: %w", gameType, err)
+	}
+	log.Printf("[Simulate] Using %s settings version %d\n", gameType, settings.Version)
+	return settings, nil
+}
+
+func printReport(r *models.SimulationReport) {
+	fmt.Printf("Game:          %s (house edge %g%%)\n", r.GameType, r.HouseEdge)
+	fmt.Printf("Rounds:        %d in %s (seed %d)\n", r.Rounds, time.Duration(r.DurationMs)*time.Millisecond, r.Seed)
+	fmt.Printf("RTP:           %.4f%% ± %.4f%% (target %.4f%%)\n", r.RTP*100, r.RTPMargin*100, r.TargetRTP*100)
+	fmt.Printf("Avg stake:     %.4fx\n", r.AvgStake)
+	fmt.Printf("Hit frequency: %.4f%% (above stake %.4f%%)\n", r.HitFrequency*100, r.WinFrequency*100)
+	fmt.Printf("Variance:      %.4f (std dev %.4f)\n", r.Variance, r.StdDev)
+	fmt.Printf("Max payout:    %gx\n", r.MaxMultiplier)
+	fmt.Println()
+	fmt.Printf("%-16s %12s %10s %10s\n", "Payout", "Rounds", "Share", "Return")
+	for _, b := range r.Histogram {
+		label := fmt.Sprintf("%gx+", b.From)
+		if b.To != nil && *b.To == b.From {
+			label = fmt.Sprintf("%gx", b.From)
+		} else if b.To != nil {
+			label = fmt.Sprintf("%gx-%gx", b.From, *b.To)
+		}
+		fmt.Printf("%-16s %12d %9.4f%% %9.4f%%\n", label, b.Rounds, b.Share*100, b.Return*100)
+	}
+}
@@ -3,31 +3,35 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
 
+	"betting-app-backend-go/middleware"
 	"betting-app-backend-go/models"
 	"betting-app-backend-go/services"
 )
 
 type AdminHandler struct {
 	walletService *services.WalletService
+	ledgerService *services.LedgerService
 }
 
-func NewAdminHandler(walletService *services.WalletService) *AdminHandler {
-	return &AdminHandler{walletService: walletService}
+func NewAdminHandler(walletService *services.WalletService, ledgerService *services.LedgerService) *AdminHandler {
+	return &AdminHandler{walletService: walletService, ledgerService: ledgerService}
 }
 
 // GetAllPaymentRequests handles GET /api/admin/payment-requests
 func (h *AdminHandler) GetAllPaymentRequests(w http.ResponseWriter, r *http.Request) {
 	status := r.URL.Query().Get("status")
+	requestType := r.URL.Query().Get("type")
 	
 	log.Printf("[Admin] Getting all payment requests (status: %s)\n", status)
 	
 	// Pass empty userID and isAdmin=true to get all requests
-	requests, err := h.walletService.GetPaymentRequests(context.Background(), "", true, status)
+	requests, err := h.walletService.GetPaymentRequests(context.Background(), "", true, status, requestType)
 	if err != nil {
 		log.Printf("[Admin] ❌ Failed to get payment requests: %v\n", err)
 		http.Error(w, "failed to get payment requests", http.StatusInternalServerError)
@@ -114,3 +118,63 @@ func (h *AdminHandler) UpdatePaymentDetails(w http.ResponseWriter, r *http.Reque
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(details)
 }
+
+// GetTrialBalance handles GET /api/admin/ledger/trial-balance
+func (h *AdminHandler) GetTrialBalance(w http.ResponseWriter, r *http.Request) {
+	log.Println("[Admin] Computing ledger trial balance")
+	
+	trial, err := h.ledgerService.TrialBalance(context.Background())
+	if err != nil {
+		log.Printf("[Admin] ❌ Failed to compute trial balance: %v\n", err)
+		http.Error(w, "failed to compute trial balance", http.StatusInternalServerError)
+		return
+	}
+	
+	if !trial.Balanced || len(trial.WalletMismatches) > 0 {
+		log.Printf("[Admin] ⚠️ Ledger out of balance: %d unbalanced entries, %d wallet mismatches\n", trial.UnbalancedEntries, len(trial.WalletMismatches))
+	} else {
+		log.Println("[Admin] ✅ Ledger is balanced")
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trial)
+}
+
+// AdjustBalance handles POST /api/admin/wallet/adjust
+func (h *AdminHandler) AdjustBalance(w http.ResponseWriter, r *http.Request) {
+	adminUID, _ := middleware.GetUserID(r)
+	
+	var body struct {
+		UserID  string       `json:"userId"`
+		Amount  models.Money `json:"amount"` // Positive credits, negative debits
+		Account string       `json:"account"`
+		Reason  string       `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Printf("[Admin] ❌ Invalid request body: %v\n", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if body.UserID == "" {
+		http.Error(w, "userId required", http.StatusBadRequest)
+		return
+	}
+	
+	log.Printf("[Admin] Adjusting wallet of %s by %s (%s)\n", body.UserID, body.Amount, body.Reason)
+	
+	wallet, err := h.walletService.AdjustBalance(context.Background(), body.UserID, body.Amount, body.Account, body.Reason, adminUID)
+	if err != nil {
+		log.Printf("[Admin] ❌ Failed to adjust wallet: %v\n", err)
+		if errors.Is(err, services.ErrInsufficientBalance) {
+			http.Error(w, err.Error(), http.StatusPaymentRequired)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+	
+	log.Printf("[Admin] ✅ Wallet of %s adjusted, balance %s\n", body.UserID, wallet.Balance)
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wallet)
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"betting-app-backend-go/middleware"
+	"betting-app-backend-go/models"
+	"betting-app-backend-go/services"
+)
+
+// autoBetKeepAlive is how often an idle progress stream sends a comment so
+// proxies don't close it
+const autoBetKeepAlive = 15 * time.Second
+
+type AutoBetHandler struct {
+	service *services.AutoBetService
+}
+
+func NewAutoBetHandler(service *services.AutoBetService) *AutoBetHandler {
+	return &AutoBetHandler{service: service}
+}
+
+// writeAutoBetError maps auto-bet errors to status codes
+func writeAutoBetError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrGameDisabled):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrBetBelowMinimum), errors.Is(err, services.ErrBetAboveMaximum):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, services.ErrNoEngine), errors.Is(err, services.ErrInvalidChoice):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrAutoBetNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrAutoBetActive), errors.Is(err, services.ErrAutoBetFinished):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "failed to process auto-bet", http.StatusInternalServerError)
+	}
+}
+
+func writeAutoBet(w http.ResponseWriter, status int, job *models.AutoBet) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(job)
+}
+
+// AutoBets handles GET and POST /api/game/autobet
+func (h *AutoBetHandler) AutoBets(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		job, err := h.service.GetActive(context.Background(), userID)
+		if err != nil {
+			log.Printf("[AutoBet] ❌ Failed to get active auto-bet: %v\n", err)
+			writeAutoBetError(w, err)
+			return
+		}
+		if job == nil {
+			http.Error(w, "no running auto-bet", http.StatusNotFound)
+			return
+		}
+		writeAutoBet(w, http.StatusOK, job)
+
+	case http.MethodPost:
+		var body struct {
+			GameType     string                 `json:"gameType"`
+			BetAmount    models.Money           `json:"betAmount"`
+			Choices      map[string]interface{} `json:"choices,omitempty"`
+			Rounds       int                    `json:"rounds"`
+			OnWin        models.AutoBetAdjust   `json:"onWin"`
+			OnLoss       models.AutoBetAdjust   `json:"onLoss"`
+			StopOnProfit models.Money           `json:"stopOnProfit"`
+			StopOnLoss   models.Money           `json:"stopOnLoss"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			log.Printf("[AutoBet] ❌ Invalid request body: %v\n", err)
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		log.Printf("[AutoBet] User %s starting %d rounds of %s - bet: %s\n", userID, body.Rounds, body.GameType, body.BetAmount)
+
+		job := &models.AutoBet{
+			UserID:       userID,
+			GameType:     body.GameType,
+			BaseBet:      body.BetAmount,
+			Choices:      body.Choices,
+			Rounds:       body.Rounds,
+			OnWin:        body.OnWin,
+			OnLoss:       body.OnLoss,
+			StopOnProfit: body.StopOnProfit,
+			StopOnLoss:   body.StopOnLoss,
+		}
+		if err := h.service.Start(context.Background(), job); err != nil {
+			log.Printf("[AutoBet] ❌ Failed to start auto-bet: %v\n", err)
+			writeAutoBetError(w, err)
+			return
+		}
+
+		log.Printf("[AutoBet] ✅ Auto-bet started: %s\n", job.ID)
+		writeAutoBet(w, http.StatusCreated, job)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// AutoBet handles GET /api/game/autobet/:id, GET /api/game/autobet/:id/stream
+// and POST /api/game/autobet/:id/cancel
+func (h *AutoBetHandler) AutoBet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/game/autobet/"), "/")
+	if parts[0] == "" || len(parts) > 2 {
+		http.Error(w, "invalid URL format", http.StatusBadRequest)
+		return
+	}
+	id := parts[0]
+
+	switch {
+	case len(parts) == 1:
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		job, err := h.service.Get(context.Background(), userID, id)
+		if err != nil {
+			writeAutoBetError(w, err)
+			return
+		}
+		writeAutoBet(w, http.StatusOK, job)
+
+	case parts[1] == "stream":
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.stream(w, r, userID, id)
+
+	case parts[1] == "cancel":
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		log.Printf("[AutoBet] User %s cancelling auto-bet %s\n", userID, id)
+		job, err := h.service.Cancel(context.Background(), userID, id)
+		if err != nil {
+			log.Printf("[AutoBet] ❌ Failed to cancel auto-bet %s: %v\n", id, err)
+			writeAutoBetError(w, err)
+			return
+		}
+		writeAutoBet(w, http.StatusOK, job)
+
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+// stream sends a job's progress as server-sent events: its current state,
+// then one event per round until it finishes
+func (h *AutoBetHandler) stream(w http.ResponseWriter, r *http.Request, userID, id string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe before reading the state so no round falls in between
+	events, unsubscribe := h.service.Subscribe(id)
+	defer unsubscribe()
+
+	job, err := h.service.Get(r.Context(), userID, id)
+	if err != nil {
+		writeAutoBetError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeAutoBetEvent(w, services.AutoBetEvent{Type: "state", Job: job}); err != nil {
+		return
+	}
+	flusher.Flush()
+	if job.Status != "running" {
+		return
+	}
+
+	keepAlive := time.NewTicker(autoBetKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			// Rounds already reflected in the initial state are skipped
+			if event.Type == "round" && event.Job.Played <= job.Played {
+				continue
+			}
+			if err := writeAutoBetEvent(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+			if event.Type == "finished" {
+				return
+			}
+		}
+	}
+}
+
+func writeAutoBetEvent(w http.ResponseWriter, event services.AutoBetEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"golang.org/x/net/websocket"
+
+	"betting-app-backend-go/middleware"
+	"betting-app-backend-go/models"
+	"betting-app-backend-go/services"
+)
+
+type AviationHandler struct {
+	service *services.AviationService
+	// allowedOrigin restricts which pages may open the live stream; empty
+	// or "*" allows any
+	allowedOrigin string
+}
+
+func NewAviationHandler(service *services.AviationService, allowedOrigin string) *AviationHandler {
+	return &AviationHandler{service: service, allowedOrigin: allowedOrigin}
+}
+
+// writeAviationError maps aviation errors to status codes
+func writeAviationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrGameDisabled), errors.Is(err, services.ErrLimitExceeded):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrBetBelowMinimum), errors.Is(err, services.ErrBetAboveMaximum):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, services.ErrInvalidChoice):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrInsufficientBalance):
+		http.Error(w, "insufficient balance", http.StatusPaymentRequired)
+	case errors.Is(err, services.ErrAviationNoBet):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrAviationBettingClosed), errors.Is(err, services.ErrAviationNotFlying), errors.Is(err, services.ErrAviationBetPlaced):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "failed to process aviation bet", http.StatusInternalServerError)
+	}
+}
+
+// Bet handles POST /api/aviation/bet
+func (h *AviationHandler) Bet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		BetAmount   models.Money `json:"betAmount"`
+		AutoCashout float64      `json:"autoCashout,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Printf("[Aviation] ❌ Invalid request body: %v\n", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("[Aviation] User %s betting %s (auto cash-out: %g)\n", userID, body.BetAmount, body.AutoCashout)
+
+	bet, err := h.service.PlaceBet(context.Background(), userID, body.BetAmount, body.AutoCashout)
+	if err != nil {
+		log.Printf("[Aviation] ❌ Failed to place bet: %v\n", err)
+		writeAviationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(bet)
+}
+
+// CashOut handles POST /api/aviation/cashout
+func (h *AviationHandler) CashOut(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	bet, err := h.service.CashOut(context.Background(), userID)
+	if err != nil {
+		log.Printf("[Aviation] ❌ Failed to cash out for user %s: %v\n", userID, err)
+		writeAviationError(w, err)
+		return
+	}
+
+	log.Printf("[Aviation] ✅ User %s cashed out at %gx\n", userID, bet.CashoutMultiplier)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bet)
+}
+
+// GetRound handles GET /api/aviation/round
+func (h *AviationHandler) GetRound(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.service.Snapshot())
+}
+
+// GetRounds handles GET /api/aviation/rounds
+func (h *AviationHandler) GetRounds(w http.ResponseWriter, r *http.Request) {
+	limitStr := r.URL.Query().Get("limit")
+	limit := int64(20) // default
+	if limitStr != "" {
+		if l, err := strconv.ParseInt(limitStr, 10, 64); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	rounds, err := h.service.GetRounds(context.Background(), limit)
+	if err != nil {
+		log.Printf("[Aviation] ❌ Failed to get rounds: %v\n", err)
+		http.Error(w, "failed to get rounds", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rounds)
+}
+
+// Stream handles the WebSocket at /api/aviation/ws. It sends the current
+// state, then every round event until the client disconnects.
+func (h *AviationHandler) Stream() http.Handler {
+	return websocket.Server{
+		Handshake: h.checkOrigin,
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+
+			events, unsubscribe := h.service.Subscribe()
+			defer unsubscribe()
+
+			// Clients only listen; reading fails once they disconnect
+			closed := make(chan struct{})
+			go func() {
+				io.Copy(io.Discard, ws)
+				close(closed)
+			}()
+
+			if err := websocket.JSON.Send(ws, h.service.Snapshot()); err != nil {
+				return
+			}
+			for {
+				select {
+				case <-closed:
+					return
+				case event, ok := <-events:
+					if !ok {
+						return
+					}
+					if err := websocket.JSON.Send(ws, event); err != nil {
+						return
+					}
+				}
+			}
+		},
+	}
+}
+
+func (h *AviationHandler) checkOrigin(config *websocket.Config, r *http.Request) error {
+	if h.allowedOrigin == "" || h.allowedOrigin == "*" {
+		return nil
+	}
+	if origin := r.Header.Get("Origin"); origin != h.allowedOrigin {
+		return fmt.Errorf("origin %q not allowed", origin)
+	}
+	return nil
+}
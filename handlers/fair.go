@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"betting-app-backend-go/middleware"
+	"betting-app-backend-go/models"
+	"betting-app-backend-go/services"
+)
+
+type FairHandler struct {
+	fairService *services.FairService
+	gameService *services.GameService
+}
+
+func NewFairHandler(fairService *services.FairService, gameService *services.GameService) *FairHandler {
+	return &FairHandler{fairService: fairService, gameService: gameService}
+}
+
+// revealedSeed exposes the server seed of a pair that is no longer active
+func revealedSeed(pair models.SeedPair) map[string]interface{} {
+	return map[string]interface{}{
+		"id":             pair.ID,
+		"serverSeed":     pair.ServerSeed,
+		"serverSeedHash": pair.ServerSeedHash,
+		"clientSeed":     pair.ClientSeed,
+		"nonce":          pair.Nonce,
+		"createdAt":      pair.CreatedAt,
+		"revealedAt":     pair.RevealedAt,
+	}
+}
+
+// GetSeeds handles GET /api/fair/seeds
+func (h *FairHandler) GetSeeds(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	log.Printf("[Fair] Getting seed pairs for user: %s\n", userID)
+
+	active, err := h.fairService.GetActiveSeed(context.Background(), userID)
+	if err != nil {
+		log.Printf("[Fair] ❌ Failed to get active seed: %v\n", err)
+		http.Error(w, "failed to get seeds", http.StatusInternalServerError)
+		return
+	}
+
+	previous, err := h.fairService.GetRevealedSeeds(context.Background(), userID, 20)
+	if err != nil {
+		log.Printf("[Fair] ❌ Failed to get revealed seeds: %v\n", err)
+		http.Error(w, "failed to get seeds", http.StatusInternalServerError)
+		return
+	}
+
+	revealed := make([]map[string]interface{}, 0, len(previous))
+	for _, pair := range previous {
+		revealed = append(revealed, revealedSeed(pair))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"active":   active,
+		"previous": revealed,
+	})
+}
+
+// RotateSeed handles POST /api/fair/rotate
+func (h *FairHandler) RotateSeed(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		ClientSeed string `json:"clientSeed"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		// Client seed is optional
+		body.ClientSeed = ""
+	}
+
+	log.Printf("[Fair] Rotating seed pair for user: %s\n", userID)
+
+	revealed, next, err := h.fairService.RotateSeed(context.Background(), userID, body.ClientSeed)
+	if err != nil {
+		log.Printf("[Fair] ❌ Failed to rotate seed: %v\n", err)
+		switch {
+		case errors.Is(err, services.ErrInvalidChoice):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrSessionActive):
+			http.Error(w, "finish your game session before rotating your seed", http.StatusConflict)
+		case errors.Is(err, services.ErrSeedRotating):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "failed to rotate seed", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	log.Printf("[Fair] ✅ Seed pair rotated, revealed %s\n", revealed.ServerSeedHash)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"revealed": revealedSeed(*revealed),
+		"active":   next,
+	})
+}
+
+// Verify handles POST /api/fair/verify
+func (h *FairHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		GameID string `json:"gameId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.GameID == "" {
+		http.Error(w, "gameId required", http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("[Fair] Verifying game %s\n", body.GameID)
+
+	result, err := h.gameService.VerifyGame(context.Background(), body.GameID)
+	if err != nil {
+		log.Printf("[Fair] ❌ Verification failed: %v\n", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("[Fair] ✅ Game %s verified: %v\n", body.GameID, result.Verified)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
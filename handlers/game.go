@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -28,37 +29,48 @@ func (h *GameHandler) PlayGame(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	var game models.Game
-	if err := json.NewDecoder(r.Body).Decode(&game); err != nil {
+	// Only the stake and the player's choices are accepted; the outcome is
+	// produced by the server-side engine.
+	var body struct {
+		GameType  string                 `json:"gameType"`
+		BetAmount models.Money           `json:"betAmount"`
+		Choices   map[string]interface{} `json:"choices,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		log.Printf("[Game] ❌ Invalid request body: %v\n", err)
 		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
 	
-	game.UserID = userID
-	
-	log.Printf("[Game] User %s playing %s - bet: %.2f\n", userID, game.GameType, game.BetAmount)
-	
-	// Validate game result
-	if err := h.service.ValidateGameResult(&game); err != nil {
-		log.Printf("[Game] ❌ Invalid game result: %v\n", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-	
-	// Record game (handles wallet transactions)
-	err := h.service.RecordGame(context.Background(), &game)
+	log.Printf("[Game] User %s playing %s - bet: %s\n", userID, body.GameType, body.BetAmount)
+	
+	// Play and record game (handles wallet transactions)
+	game, err := h.service.RecordGame(context.Background(), &services.PlayRequest{
+		UserID:    userID,
+		GameType:  body.GameType,
+		BetAmount: body.BetAmount,
+		Choices:   body.Choices,
+		
+		IdempotencyKey: middleware.GetIdempotencyKey(r),
+	})
 	if err != nil {
 		log.Printf("[Game] ❌ Failed to record game: %v\n", err)
-		if err.Error() == "insufficient balance" {
+		switch {
+		case errors.Is(err, services.ErrGameDisabled), errors.Is(err, services.ErrLimitExceeded):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, services.ErrBetBelowMinimum), errors.Is(err, services.ErrBetAboveMaximum):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		case errors.Is(err, services.ErrNoEngine), errors.Is(err, services.ErrInvalidChoice):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrInsufficientBalance):
 			http.Error(w, "insufficient balance", http.StatusPaymentRequired)
-		} else {
+		default:
 			http.Error(w, "failed to record game", http.StatusInternalServerError)
 		}
 		return
 	}
 	
-	log.Printf("[Game] ✅ Game recorded: %s - win: %.2f\n", game.ID, game.WinAmount)
+	log.Printf("[Game] ✅ Game recorded: %s - win: %s\n", game.ID, game.WinAmount)
 	
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
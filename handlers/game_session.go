@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"betting-app-backend-go/middleware"
+	"betting-app-backend-go/models"
+	"betting-app-backend-go/services"
+)
+
+type GameSessionHandler struct {
+	service *services.GameSessionService
+}
+
+func NewGameSessionHandler(service *services.GameSessionService) *GameSessionHandler {
+	return &GameSessionHandler{service: service}
+}
+
+// writeSessionError maps game session errors to status codes
+func writeSessionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrGameDisabled), errors.Is(err, services.ErrLimitExceeded):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrBetBelowMinimum), errors.Is(err, services.ErrBetAboveMaximum):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, services.ErrNoEngine), errors.Is(err, services.ErrInvalidChoice):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrInsufficientBalance):
+		http.Error(w, "insufficient balance", http.StatusPaymentRequired)
+	case errors.Is(err, services.ErrSessionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrSessionActive), errors.Is(err, services.ErrSessionClosed), errors.Is(err, services.ErrSessionConflict),
+		errors.Is(err, services.ErrSeedRotating):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "failed to process game session", http.StatusInternalServerError)
+	}
+}
+
+func writeSession(w http.ResponseWriter, status int, session *models.GameSession) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(session.Public())
+}
+
+// Sessions handles GET and POST /api/game/session
+func (h *GameSessionHandler) Sessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		session, err := h.service.GetActive(context.Background(), userID)
+		if err != nil {
+			log.Printf("[Session] ❌ Failed to get active session: %v\n", err)
+			writeSessionError(w, err)
+			return
+		}
+		if session == nil {
+			http.Error(w, "no active game session", http.StatusNotFound)
+			return
+		}
+		writeSession(w, http.StatusOK, session)
+
+	case http.MethodPost:
+		var body struct {
+			GameType  string                 `json:"gameType"`
+			BetAmount models.Money           `json:"betAmount"`
+			Choices   map[string]interface{} `json:"choices,omitempty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			log.Printf("[Session] ❌ Invalid request body: %v\n", err)
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		log.Printf("[Session] User %s starting %s - bet: %s\n", userID, body.GameType, body.BetAmount)
+
+		session, err := h.service.Start(context.Background(), &services.PlayRequest{
+			UserID:    userID,
+			GameType:  body.GameType,
+			BetAmount: body.BetAmount,
+			Choices:   body.Choices,
+		})
+		if err != nil {
+			log.Printf("[Session] ❌ Failed to start session: %v\n", err)
+			writeSessionError(w, err)
+			return
+		}
+
+		log.Printf("[Session] ✅ Session started: %s\n", session.ID)
+		writeSession(w, http.StatusCreated, session)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Session handles GET /api/game/session/:id and POST
+// /api/game/session/:id/:action
+func (h *GameSessionHandler) Session(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/game/session/"), "/")
+	if parts[0] == "" || len(parts) > 2 {
+		http.Error(w, "invalid URL format", http.StatusBadRequest)
+		return
+	}
+	id := parts[0]
+
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		session, err := h.service.Get(context.Background(), userID, id)
+		if err != nil {
+			writeSessionError(w, err)
+			return
+		}
+		writeSession(w, http.StatusOK, session)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	action := parts[1]
+
+	var params map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		// Actions like cashout take no parameters
+		params = nil
+	}
+
+	log.Printf("[Session] User %s: %s on session %s\n", userID, action, id)
+
+	session, err := h.service.Act(context.Background(), userID, id, action, params)
+	if err != nil {
+		log.Printf("[Session] ❌ Failed to %s on session %s: %v\n", action, id, err)
+		writeSessionError(w, err)
+		return
+	}
+
+	if session.Status == "settled" {
+		log.Printf("[Session] ✅ Session %s settled: %s at %gx\n", id, session.Outcome, session.Multiplier)
+	}
+	writeSession(w, http.StatusOK, session)
+}
@@ -1,26 +1,48 @@
 package handlers
 
 import (
+	"betting-app-backend-go/middleware"
 	"betting-app-backend-go/models"
 	"betting-app-backend-go/services"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
 
-	"github.com/gorilla/mux"
+const (
+	// defaultSimulationRounds and maxSimulationRounds bound the rounds of
+	// a simulation run over HTTP; the simulate command has no limit
+	defaultSimulationRounds = 1000000
+	maxSimulationRounds     = 5000000
 )
 
 type GameSettingsHandler struct {
-	service *services.GameSettingsService
+	service   *services.GameSettingsService
+	scheduler *services.SettingsScheduler
+	games     *services.GameService
+
+	// simulating allows one simulation at a time so admins can't starve
+	// live bets of CPU
+	simulating sync.Mutex
+}
+
+func NewGameSettingsHandler(service *services.GameSettingsService, scheduler *services.SettingsScheduler, games *services.GameService) *GameSettingsHandler {
+	return &GameSettingsHandler{service: service, scheduler: scheduler, games: games}
 }
 
-func NewGameSettingsHandler(service *services.GameSettingsService) *GameSettingsHandler {
-	return &GameSettingsHandler{service: service}
+// gameTypeFromPath extracts the game type from /api/game-settings/:gameType
+func gameTypeFromPath(r *http.Request) string {
+	return strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/game-settings/"), "/")
 }
 
 // GetGameSettings retrieves settings for a specific game
 func (h *GameSettingsHandler) GetGameSettings(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	gameType := vars["gameType"]
+	gameType := gameTypeFromPath(r)
 
 	settings, err := h.service.GetGameSettings(r.Context(), gameType)
 	if err != nil {
@@ -46,11 +68,10 @@ func (h *GameSettingsHandler) GetAllGameSettings(w http.ResponseWriter, r *http.
 
 // UpdateGameSettings updates settings for a specific game (Admin only)
 func (h *GameSettingsHandler) UpdateGameSettings(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	gameType := vars["gameType"]
+	gameType := gameTypeFromPath(r)
 
 	// Get admin UID from context (set by auth middleware)
-	adminUID, ok := r.Context().Value("userID").(string)
+	adminUID, ok := middleware.GetUserID(r)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -63,10 +84,273 @@ func (h *GameSettingsHandler) UpdateGameSettings(w http.ResponseWriter, r *http.
 	}
 
 	if err := h.service.UpdateGameSettings(r.Context(), gameType, &settings, adminUID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeSettingsError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Game settings updated successfully"})
 }
+
+// AdminGameSettings handles the settings history endpoints:
+// GET  /api/admin/game-settings/:gameType/versions
+// GET  /api/admin/game-settings/:gameType/diff?from=1&to=2
+// POST /api/admin/game-settings/:gameType/rollback
+// POST /api/admin/game-settings/:gameType/simulate
+func (h *GameSettingsHandler) AdminGameSettings(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/game-settings/"), "/")
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 {
+		http.Error(w, "invalid URL format", http.StatusBadRequest)
+		return
+	}
+	gameType, action := parts[0], parts[1]
+
+	switch {
+	case action == "versions" && r.Method == http.MethodGet:
+		h.listVersions(w, r, gameType)
+	case action == "diff" && r.Method == http.MethodGet:
+		h.diffVersions(w, r, gameType)
+	case action == "rollback" && r.Method == http.MethodPost:
+		h.rollback(w, r, gameType)
+	case action == "simulate" && r.Method == http.MethodPost:
+		h.simulate(w, r, gameType)
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+func (h *GameSettingsHandler) listVersions(w http.ResponseWriter, r *http.Request, gameType string) {
+	limit := int64(50)
+	if l, err := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64); err == nil && l > 0 {
+		limit = l
+	}
+
+	versions, err := h.service.ListVersions(r.Context(), gameType, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
+func (h *GameSettingsHandler) diffVersions(w http.ResponseWriter, r *http.Request, gameType string) {
+	from, errFrom := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+	to, errTo := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+	if errFrom != nil || errTo != nil {
+		http.Error(w, "from and to versions required", http.StatusBadRequest)
+		return
+	}
+
+	changes, err := h.service.DiffVersions(r.Context(), gameType, from, to)
+	if err != nil {
+		writeSettingsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"game_type": gameType,
+		"from":      from,
+		"to":        to,
+		"changes":   changes,
+	})
+}
+
+func (h *GameSettingsHandler) rollback(w http.ResponseWriter, r *http.Request, gameType string) {
+	adminUID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		Version int64 `json:"version"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Version <= 0 {
+		http.Error(w, "version required", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.service.Rollback(r.Context(), gameType, body.Version, adminUID)
+	if err != nil {
+		writeSettingsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// simulate reports the RTP of the game's current settings, or of a proposed
+// config and house edge, over many simulated rounds. Nothing is saved.
+func (h *GameSettingsHandler) simulate(w http.ResponseWriter, r *http.Request, gameType string) {
+	var body struct {
+		Rounds    int64                  `json:"rounds"`
+		Choices   map[string]interface{} `json:"choices"`
+		Seed      *uint64                `json:"seed"`
+		HouseEdge *float64               `json:"house_edge"`
+		Config    map[string]interface{} `json:"config"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if body.Rounds == 0 {
+		body.Rounds = defaultSimulationRounds
+	}
+	if body.Rounds < 0 || body.Rounds > maxSimulationRounds {
+		http.Error(w, "rounds must be between 1 and "+strconv.Itoa(maxSimulationRounds), http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.service.GetGameSettings(r.Context(), gameType)
+	if err != nil {
+		writeSettingsError(w, err)
+		return
+	}
+	if body.Config != nil {
+		settings.Config = body.Config
+		settings.Version = 0
+	}
+	if body.HouseEdge != nil {
+		settings.HouseEdge = *body.HouseEdge
+		settings.Version = 0
+	}
+	if err := services.ValidateGameSettings(gameType, settings); err != nil {
+		writeSettingsError(w, err)
+		return
+	}
+
+	seed := uint64(time.Now().UnixNano())
+	if body.Seed != nil {
+		seed = *body.Seed
+	}
+
+	if !h.simulating.TryLock() {
+		http.Error(w, "another simulation is running", http.StatusTooManyRequests)
+		return
+	}
+	defer h.simulating.Unlock()
+
+	// Leave half the CPUs to live traffic
+	report, err := h.games.Simulate(r.Context(), settings, services.SimulationOptions{
+		Rounds:  body.Rounds,
+		Choices: body.Choices,
+		Seed:    seed,
+		Workers: max(runtime.GOMAXPROCS(0)/2, 1),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNoEngine), errors.Is(err, services.ErrInvalidChoice):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case r.Context().Err() != nil:
+			// The admin went away
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// Schedules handles GET and POST /api/admin/game-settings/schedules
+func (h *GameSettingsHandler) Schedules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		schedules, err := h.scheduler.ListSchedules(r.Context(), r.URL.Query().Get("status"), r.URL.Query().Get("game_type"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(schedules)
+
+	case http.MethodPost:
+		adminUID, ok := middleware.GetUserID(r)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var schedule models.GameSettingsSchedule
+		if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.scheduler.CreateSchedule(r.Context(), &schedule, adminUID); err != nil {
+			writeSettingsError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(schedule)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// CancelSchedule handles POST /api/admin/game-settings/schedules/:id/cancel
+func (h *GameSettingsHandler) CancelSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	adminUID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/game-settings/schedules/"), "/")
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 || parts[1] != "cancel" {
+		http.Error(w, "invalid URL format", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.scheduler.CancelSchedule(r.Context(), parts[0], adminUID); err != nil {
+		if errors.Is(err, services.ErrScheduleNotPending) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Schedule cancelled"})
+}
+
+// writeSettingsError maps settings service errors to HTTP responses
+func writeSettingsError(w http.ResponseWriter, err error) {
+	var verr *services.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		writeValidationError(w, verr)
+	case errors.Is(err, services.ErrSettingsConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrVersionNotFound), errors.Is(err, services.ErrSettingsNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeValidationError responds with 400 and the list of invalid fields
+func writeValidationError(w http.ResponseWriter, verr *services.ValidationError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":  "invalid game settings",
+		"fields": verr.Fields,
+	})
+}
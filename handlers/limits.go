@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"betting-app-backend-go/middleware"
+	"betting-app-backend-go/models"
+	"betting-app-backend-go/services"
+)
+
+type LimitHandler struct {
+	service *services.LimitService
+}
+
+func NewLimitHandler(service *services.LimitService) *LimitHandler {
+	return &LimitHandler{service: service}
+}
+
+// writeLimitError maps limit errors to status codes
+func writeLimitError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidLimit):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrLimitConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "failed to process limits", http.StatusInternalServerError)
+	}
+}
+
+// Limits handles GET and PUT /api/user/limits. Both return every limit
+// with the player's usage in its current period.
+func (h *LimitHandler) Limits(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var body struct {
+			Kind   string       `json:"kind"`
+			Period string       `json:"period"`
+			Amount models.Money `json:"amount"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			log.Printf("[Limits] ❌ Invalid request body: %v\n", err)
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		log.Printf("[Limits] Setting %s %s limit to %s for user: %s\n", body.Period, body.Kind, body.Amount, userID)
+
+		if err := h.service.SetLimit(context.Background(), userID, body.Kind, body.Period, body.Amount); err != nil {
+			log.Printf("[Limits] ❌ Failed to set limit: %v\n", err)
+			writeLimitError(w, err)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	statuses, err := h.service.Status(context.Background(), userID)
+	if err != nil {
+		log.Printf("[Limits] ❌ Failed to get limits: %v\n", err)
+		writeLimitError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"betting-app-backend-go/middleware"
+	"betting-app-backend-go/models"
+	"betting-app-backend-go/services"
+)
+
+type PayoutMethodHandler struct {
+	service *services.PayoutMethodService
+}
+
+func NewPayoutMethodHandler(service *services.PayoutMethodService) *PayoutMethodHandler {
+	return &PayoutMethodHandler{service: service}
+}
+
+// writePayoutMethodError maps payout method errors to status codes
+func writePayoutMethodError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidPayoutMethod):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrPayoutMethodNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrPayoutMethodExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "failed to process payout method", http.StatusInternalServerError)
+	}
+}
+
+// PayoutMethods handles GET and POST /api/user/payout-methods
+func (h *PayoutMethodHandler) PayoutMethods(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		log.Printf("[PayoutMethod] Listing payout methods for user: %s\n", userID)
+
+		methods, err := h.service.List(context.Background(), userID)
+		if err != nil {
+			log.Printf("[PayoutMethod] ❌ Failed to list payout methods: %v\n", err)
+			writePayoutMethodError(w, err)
+			return
+		}
+
+		masked := make([]models.PayoutMethod, len(methods))
+		for i, m := range methods {
+			masked[i] = m.Masked()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(masked)
+
+	case http.MethodPost:
+		var body struct {
+			Type              string `json:"type"`
+			Label             string `json:"label"`
+			AccountHolderName string `json:"accountHolderName"`
+			AccountNumber     string `json:"accountNumber"`
+			IFSCCode          string `json:"ifscCode"`
+			UPIID             string `json:"upiId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			log.Printf("[PayoutMethod] ❌ Invalid request body: %v\n", err)
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		method := models.PayoutMethod{
+			UserID:            userID,
+			Type:              body.Type,
+			Label:             body.Label,
+			AccountHolderName: body.AccountHolderName,
+			AccountNumber:     body.AccountNumber,
+			IFSCCode:          body.IFSCCode,
+			UPIID:             body.UPIID,
+		}
+
+		log.Printf("[PayoutMethod] Saving %s payout method for user: %s\n", method.Type, userID)
+
+		if err := h.service.Create(context.Background(), &method); err != nil {
+			log.Printf("[PayoutMethod] ❌ Failed to save payout method: %v\n", err)
+			writePayoutMethodError(w, err)
+			return
+		}
+
+		log.Printf("[PayoutMethod] ✅ Payout method saved: %s\n", method.ID)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(method.Masked())
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// PayoutMethod handles GET, PUT and DELETE /api/user/payout-methods/:id
+func (h *PayoutMethodHandler) PayoutMethod(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/api/user/payout-methods/")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "invalid URL format", http.StatusBadRequest)
+		return
+	}
+
+	var method *models.PayoutMethod
+	var err error
+
+	switch r.Method {
+	case http.MethodGet:
+		method, err = h.service.Get(context.Background(), userID, id)
+
+	case http.MethodPut:
+		var body struct {
+			Label string `json:"label"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		log.Printf("[PayoutMethod] Renaming payout method %s\n", id)
+		method, err = h.service.UpdateLabel(context.Background(), userID, id, body.Label)
+
+	case http.MethodDelete:
+		log.Printf("[PayoutMethod] Deleting payout method %s\n", id)
+		if err := h.service.Delete(context.Background(), userID, id); err != nil {
+			log.Printf("[PayoutMethod] ❌ Failed to delete payout method: %v\n", err)
+			writePayoutMethodError(w, err)
+			return
+		}
+		log.Printf("[PayoutMethod] ✅ Payout method %s deleted\n", id)
+		w.WriteHeader(http.StatusNoContent)
+		return
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err != nil {
+		log.Printf("[PayoutMethod] ❌ Failed to process payout method %s: %v\n", id, err)
+		writePayoutMethodError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(method.Masked())
+}
+
+// AdminListPayoutMethods handles GET /api/admin/payout-methods
+func (h *PayoutMethodHandler) AdminListPayoutMethods(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = "pending"
+	}
+
+	log.Printf("[Admin] Getting payout methods (status: %s)\n", status)
+
+	methods, err := h.service.ListByStatus(context.Background(), status)
+	if err != nil {
+		log.Printf("[Admin] ❌ Failed to get payout methods: %v\n", err)
+		writePayoutMethodError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(methods)
+}
+
+// AdminReviewPayoutMethod handles POST /api/admin/payout-methods/:id/verify
+// and /api/admin/payout-methods/:id/reject
+func (h *PayoutMethodHandler) AdminReviewPayoutMethod(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	adminUID, _ := middleware.GetUserID(r)
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/admin/payout-methods/"), "/")
+	if len(parts) != 2 || parts[0] == "" || (parts[1] != "verify" && parts[1] != "reject") {
+		http.Error(w, "invalid URL format", http.StatusBadRequest)
+		return
+	}
+	id, action := parts[0], parts[1]
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		// Reason is only needed when rejecting
+		body.Reason = ""
+	}
+
+	log.Printf("[Admin] Reviewing payout method %s: %s\n", id, action)
+
+	method, err := h.service.Review(context.Background(), id, action == "verify", body.Reason, adminUID)
+	if err != nil {
+		log.Printf("[Admin] ❌ Failed to review payout method: %v\n", err)
+		writePayoutMethodError(w, err)
+		return
+	}
+
+	log.Printf("[Admin] ✅ Payout method %s %s\n", id, method.Status)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(method)
+}
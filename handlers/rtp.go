@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"betting-app-backend-go/middleware"
+	"betting-app-backend-go/services"
+)
+
+type RTPHandler struct {
+	monitor *services.RTPMonitor
+}
+
+func NewRTPHandler(monitor *services.RTPMonitor) *RTPHandler {
+	return &RTPHandler{monitor: monitor}
+}
+
+// GetReport handles GET /api/admin/rtp. ?refresh=true recomputes it instead
+// of returning the latest one.
+func (h *RTPHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	get := h.monitor.Report
+	if r.URL.Query().Get("refresh") == "true" {
+		get = h.monitor.Refresh
+	}
+	report, err := get(r.Context())
+	if err != nil {
+		log.Printf("[Admin] ❌ Failed to get RTP report: %v\n", err)
+		http.Error(w, "failed to get RTP report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetAlerts handles GET /api/admin/rtp/alerts?status=open&limit=50
+func (h *RTPHandler) GetAlerts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := int64(50)
+	if l, err := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64); err == nil && l > 0 && l <= 200 {
+		limit = l
+	}
+
+	alerts, err := h.monitor.ListAlerts(context.Background(), r.URL.Query().Get("status"), limit)
+	if err != nil {
+		log.Printf("[Admin] ❌ Failed to get RTP alerts: %v\n", err)
+		http.Error(w, "failed to get alerts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(alerts)
+}
+
+// AcknowledgeAlert handles POST /api/admin/rtp/alerts/:id/acknowledge
+func (h *RTPHandler) AcknowledgeAlert(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	adminUID, _ := middleware.GetUserID(r)
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/admin/rtp/alerts/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] != "acknowledge" {
+		http.Error(w, "invalid URL format", http.StatusBadRequest)
+		return
+	}
+
+	alert, err := h.monitor.AcknowledgeAlert(context.Background(), parts[0], adminUID)
+	if err != nil {
+		log.Printf("[Admin] ❌ Failed to acknowledge RTP alert: %v\n", err)
+		if errors.Is(err, services.ErrAlertNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to acknowledge alert", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[Admin] ✅ RTP alert %s acknowledged by %s\n", alert.ID, adminUID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(alert)
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 
 	"betting-app-backend-go/middleware"
+	"betting-app-backend-go/models"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -40,6 +41,13 @@ func (h *UserHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	// Amounts are stored in paise; convert so they encode as rupees
+	for _, field := range []string{"total_wagered", "total_won"} {
+		if v, ok := user[field]; ok {
+			user[field] = models.MoneyFromAny(v)
+		}
+	}
+	
 	log.Println("[User] ✅ Profile retrieved")
 	
 	w.Header().Set("Content-Type", "application/json")
@@ -125,8 +133,8 @@ func (h *UserHandler) GetStats(w http.ResponseWriter, r *http.Request) {
 	
 	stats := map[string]interface{}{
 		"totalGamesPlayed": user["total_games_played"],
-		"totalWagered":     user["total_wagered"],
-		"totalWon":         user["total_won"],
+		"totalWagered":     models.MoneyFromAny(user["total_wagered"]),
+		"totalWon":         models.MoneyFromAny(user["total_won"]),
 	}
 	
 	log.Println("[User] ✅ Stats retrieved")
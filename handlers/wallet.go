@@ -3,9 +3,11 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"betting-app-backend-go/middleware"
 	"betting-app-backend-go/models"
@@ -37,7 +39,7 @@ func (h *WalletHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	log.Printf("[Wallet] ✅ Balance retrieved: %.2f %s\n", wallet.Balance, wallet.Currency)
+	log.Printf("[Wallet] ✅ Balance retrieved: %s %s\n", wallet.Balance, wallet.Currency)
 	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(wallet)
@@ -59,8 +61,9 @@ func (h *WalletHandler) CreatePaymentRequest(w http.ResponseWriter, r *http.Requ
 	}
 	
 	req.UserID = userID
+	req.IdempotencyKey = middleware.GetIdempotencyKey(r)
 	
-	log.Printf("[Wallet] Creating payment request: %.2f INR via %s\n", req.Amount, req.PaymentMethod)
+	log.Printf("[Wallet] Creating payment request: %s INR via %s\n", req.Amount, req.PaymentMethod)
 	
 	if req.Amount <= 0 {
 		http.Error(w, "amount must be positive", http.StatusBadRequest)
@@ -75,6 +78,10 @@ func (h *WalletHandler) CreatePaymentRequest(w http.ResponseWriter, r *http.Requ
 	err := h.service.CreatePaymentRequest(context.Background(), &req)
 	if err != nil {
 		log.Printf("[Wallet] ❌ Failed to create payment request: %v\n", err)
+		if errors.Is(err, services.ErrLimitExceeded) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		http.Error(w, "failed to create payment request", http.StatusInternalServerError)
 		return
 	}
@@ -86,6 +93,106 @@ func (h *WalletHandler) CreatePaymentRequest(w http.ResponseWriter, r *http.Requ
 	json.NewEncoder(w).Encode(req)
 }
 
+// CreateWithdrawalRequest handles POST /api/wallet/withdrawal-request
+func (h *WalletHandler) CreateWithdrawalRequest(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	var body struct {
+		Amount         models.Money `json:"amount"`
+		PayoutMethodID string       `json:"payoutMethodId"`
+		Notes          string       `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Printf("[Wallet] ❌ Invalid request body: %v\n", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	log.Printf("[Wallet] Creating withdrawal request: %s INR to %s\n", body.Amount, body.PayoutMethodID)
+	
+	if body.Amount <= 0 {
+		http.Error(w, "amount must be positive", http.StatusBadRequest)
+		return
+	}
+	if body.PayoutMethodID == "" {
+		http.Error(w, "payoutMethodId required", http.StatusBadRequest)
+		return
+	}
+	
+	req := models.PaymentRequest{
+		UserID:         userID,
+		Amount:         body.Amount,
+		PayoutMethodID: body.PayoutMethodID,
+		Notes:          body.Notes,
+		IdempotencyKey: middleware.GetIdempotencyKey(r),
+	}
+	
+	err := h.service.CreateWithdrawalRequest(context.Background(), &req)
+	if err != nil {
+		log.Printf("[Wallet] ❌ Failed to create withdrawal request: %v\n", err)
+		switch {
+		case errors.Is(err, services.ErrInsufficientBalance):
+			http.Error(w, "insufficient balance", http.StatusPaymentRequired)
+		case errors.Is(err, services.ErrPayoutMethodNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, services.ErrPayoutMethodNotVerified):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		default:
+			http.Error(w, "failed to create withdrawal request", http.StatusInternalServerError)
+		}
+		return
+	}
+	
+	log.Printf("[Wallet] ✅ Withdrawal request created: %s\n", req.ID)
+	
+	req.PayoutDetails = req.PayoutDetails.Masked()
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(req)
+}
+
+// CancelWithdrawalRequest handles POST /api/wallet/withdrawal-request/:id/cancel
+func (h *WalletHandler) CancelWithdrawalRequest(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	path := strings.TrimPrefix(r.URL.Path, "/api/wallet/withdrawal-request/")
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] != "cancel" {
+		http.Error(w, "invalid URL format", http.StatusBadRequest)
+		return
+	}
+	requestID := parts[0]
+	
+	log.Printf("[Wallet] Cancelling withdrawal request %s for user %s\n", requestID, userID)
+	
+	req, err := h.service.CancelWithdrawalRequest(context.Background(), userID, requestID)
+	if err != nil {
+		log.Printf("[Wallet] ❌ Failed to cancel withdrawal request: %v\n", err)
+		if errors.Is(err, services.ErrPaymentRequestProcessed) {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+	
+	log.Printf("[Wallet] ✅ Withdrawal request %s cancelled\n", requestID)
+	
+	req.PayoutDetails = req.PayoutDetails.Masked()
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(req)
+}
+
 // GetPaymentRequests handles GET /api/wallet/payment-requests
 func (h *WalletHandler) GetPaymentRequests(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r)
@@ -98,10 +205,11 @@ func (h *WalletHandler) GetPaymentRequests(w http.ResponseWriter, r *http.Reques
 	isAdmin := role == "admin"
 	
 	status := r.URL.Query().Get("status")
+	requestType := r.URL.Query().Get("type")
 	
 	log.Printf("[Wallet] Getting payment requests (user: %s, admin: %v, status: %s)\n", userID, isAdmin, status)
 	
-	requests, err := h.service.GetPaymentRequests(context.Background(), userID, isAdmin, status)
+	requests, err := h.service.GetPaymentRequests(context.Background(), userID, isAdmin, status, requestType)
 	if err != nil {
 		log.Printf("[Wallet] ❌ Failed to get payment requests: %v\n", err)
 		http.Error(w, "failed to get payment requests", http.StatusInternalServerError)
@@ -110,6 +218,12 @@ func (h *WalletHandler) GetPaymentRequests(w http.ResponseWriter, r *http.Reques
 	
 	log.Printf("[Wallet] ✅ Retrieved %d payment requests\n", len(requests))
 	
+	if !isAdmin {
+		for i := range requests {
+			requests[i].PayoutDetails = requests[i].PayoutDetails.Masked()
+		}
+	}
+	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(requests)
 }
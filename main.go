@@ -73,24 +73,72 @@ func main() {
 	mux := http.NewServeMux()
 
 	// Initialize services
+	var ledgerService *services.LedgerService
+	var limitService *services.LimitService
 	var walletService *services.WalletService
+	var payoutMethodService *services.PayoutMethodService
+	var idempotencyService *services.IdempotencyService
 	var gameService *services.GameService
+	var gameSessionService *services.GameSessionService
+	var autoBetService *services.AutoBetService
+	var rtpMonitor *services.RTPMonitor
+	var aviationService *services.AviationService
 	var gameSettingsService *services.GameSettingsService
+	var fairService *services.FairService
+	var settingsScheduler *services.SettingsScheduler
 	var walletHandler *handlers.WalletHandler
 	var gameHandler *handlers.GameHandler
+	var gameSessionHandler *handlers.GameSessionHandler
+	var autoBetHandler *handlers.AutoBetHandler
+	var rtpHandler *handlers.RTPHandler
+	var aviationHandler *handlers.AviationHandler
 	var adminHandler *handlers.AdminHandler
 	var userHandler *handlers.UserHandler
 	var gameSettingsHandler *handlers.GameSettingsHandler
+	var fairHandler *handlers.FairHandler
+	var payoutMethodHandler *handlers.PayoutMethodHandler
+	var limitHandler *handlers.LimitHandler
 
 	if mongoDB != nil {
-		walletService = services.NewWalletService(mongoDB)
-		gameService = services.NewGameService(mongoDB, walletService)
+		ledgerService = services.NewLedgerService(mongoDB)
+		payoutMethodService = services.NewPayoutMethodService(mongoDB)
+		limitService = services.NewLimitService(mongoDB)
+		walletService = services.NewWalletService(mongoDB, ledgerService, payoutMethodService, limitService)
+		idempotencyService = services.NewIdempotencyService(mongoDB)
 		gameSettingsService = services.NewGameSettingsService(mongoDB)
+		settingsScheduler = services.NewSettingsScheduler(mongoDB, gameSettingsService)
+		fairService = services.NewFairService(mongoDB)
+		gameService = services.NewGameService(mongoDB, walletService, gameSettingsService, fairService)
+		gameSessionService = services.NewGameSessionService(mongoDB, gameService)
+		autoBetService = services.NewAutoBetService(mongoDB, gameService)
+		
+		// RTP alerts go to a webhook when one is configured, the log otherwise
+		var rtpNotifier services.AlertNotifier = &services.LogNotifier{}
+		if url := os.Getenv("RTP_ALERT_WEBHOOK_URL"); url != "" {
+			rtpNotifier = services.NewWebhookNotifier(url)
+		}
+		rtpMonitor = services.NewRTPMonitor(mongoDB, gameService, rtpNotifier)
+		
+		aviationService = services.NewAviationService(mongoDB, gameService)
+		
+		// Every game is settled by a server-side engine; never serve one
+		// that would have to trust the client
+		if unserved := gameService.UnservedGameTypes(); len(unserved) > 0 {
+			log.Fatalf("[Init] ❌ No engine plays game types %v\n", unserved)
+		}
+		
 		walletHandler = handlers.NewWalletHandler(walletService)
 		gameHandler = handlers.NewGameHandler(gameService)
-		adminHandler = handlers.NewAdminHandler(walletService)
+		gameSessionHandler = handlers.NewGameSessionHandler(gameSessionService)
+		autoBetHandler = handlers.NewAutoBetHandler(autoBetService)
+		rtpHandler = handlers.NewRTPHandler(rtpMonitor)
+		aviationHandler = handlers.NewAviationHandler(aviationService, os.Getenv("FRONTEND_ORIGIN"))
+		adminHandler = handlers.NewAdminHandler(walletService, ledgerService)
 		userHandler = handlers.NewUserHandler(mongoDB)
-		gameSettingsHandler = handlers.NewGameSettingsHandler(gameSettingsService)
+		gameSettingsHandler = handlers.NewGameSettingsHandler(gameSettingsService, settingsScheduler, gameService)
+		fairHandler = handlers.NewFairHandler(fairService, gameService)
+		payoutMethodHandler = handlers.NewPayoutMethodHandler(payoutMethodService)
+		limitHandler = handlers.NewLimitHandler(limitService)
 		
 		// Initialize default game settings if they don't exist
 		if err := gameSettingsService.InitializeDefaultSettings(context.Background()); err != nil {
@@ -99,11 +147,48 @@ func main() {
 			log.Println("[Init] ✅ Game settings initialized")
 		}
 		
+		// Replace legacy configs and disable any that fail validation
+		if err := gameSettingsService.MigrateStoredSettings(context.Background()); err != nil {
+			log.Printf("[Init] ⚠️ Failed to re-validate stored game settings: %v\n", err)
+		}
+		
+		// Give wallets funded before the ledger existed an opening entry
+		if err := ledgerService.EnsureOpeningBalances(context.Background()); err != nil {
+			log.Printf("[Init] ⚠️ Failed to post opening ledger balances: %v\n", err)
+		}
+		
+		// Apply and revert scheduled game settings changes
+		go settingsScheduler.Run(context.Background())
+		
+		// Pay out games whose win could not be credited
+		go gameService.Run(context.Background())
+		
+		// Settle game sessions that timed out
+		go gameSessionService.Run(context.Background())
+		
+		// Stop auto-bets whose runner went away
+		go autoBetService.Run(context.Background())
+		
+		// Watch realised RTP per game
+		go rtpMonitor.Run(context.Background())
+		
+		// Play shared aviation rounds while this instance holds the lease
+		go aviationService.Run(context.Background())
+		
 		log.Println("[Init] ✅ All services and handlers initialized")
 	}
 
 	// Auth middleware
 	authMiddleware := middleware.AuthMiddleware(firebaseAuth)
+	
+	// Honour Idempotency-Key on every protected mutating endpoint
+	if idempotencyService != nil {
+		verifyAuth := authMiddleware
+		idempotent := middleware.Idempotency(idempotencyService)
+		authMiddleware = func(next http.Handler) http.Handler {
+			return verifyAuth(idempotent(next))
+		}
+	}
 
 	// Health check endpoint (no auth required)
 	mux.HandleFunc("/api/health", func(w http.ResponseWriter, r *http.Request) {
@@ -225,6 +310,20 @@ func main() {
 				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			}
 		})))
+		mux.Handle("/api/wallet/withdrawal-request", authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				walletHandler.CreateWithdrawalRequest(w, r)
+			} else {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+		})))
+		mux.Handle("/api/wallet/withdrawal-request/", authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				walletHandler.CancelWithdrawalRequest(w, r)
+			} else {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+		})))
 		mux.Handle("/api/wallet/payment-requests", authMiddleware(http.HandlerFunc(walletHandler.GetPaymentRequests)))
 		mux.Handle("/api/wallet/transactions", authMiddleware(http.HandlerFunc(walletHandler.GetTransactions)))
 		mux.HandleFunc("/api/wallet/payment-details", walletHandler.GetPaymentDetails)
@@ -245,6 +344,62 @@ func main() {
 		mux.HandleFunc("/api/game/recent-bets", gameHandler.GetRecentBets)
 		log.Println("[Init] ✅ Game endpoints registered")
 	}
+	
+	// Multi-step game sessions (mines)
+	if gameSessionHandler != nil {
+		mux.Handle("/api/game/session", authMiddleware(http.HandlerFunc(gameSessionHandler.Sessions)))
+		mux.Handle("/api/game/session/", authMiddleware(http.HandlerFunc(gameSessionHandler.Session)))
+		log.Println("[Init] ✅ Game session endpoints registered")
+	}
+	
+	// Server-side auto-bet series
+	if autoBetHandler != nil {
+		mux.Handle("/api/game/autobet", authMiddleware(http.HandlerFunc(autoBetHandler.AutoBets)))
+		mux.Handle("/api/game/autobet/", authMiddleware(http.HandlerFunc(autoBetHandler.AutoBet)))
+		log.Println("[Init] ✅ Auto-bet endpoints registered")
+	}
+	
+	// Aviation rounds (live state is public, betting requires auth)
+	if aviationHandler != nil {
+		mux.Handle("/api/aviation/bet", authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				aviationHandler.Bet(w, r)
+			} else {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+		})))
+		mux.Handle("/api/aviation/cashout", authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				aviationHandler.CashOut(w, r)
+			} else {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+		})))
+		mux.HandleFunc("/api/aviation/round", aviationHandler.GetRound)
+		mux.HandleFunc("/api/aviation/rounds", aviationHandler.GetRounds)
+		mux.Handle("/api/aviation/ws", aviationHandler.Stream())
+		log.Println("[Init] ✅ Aviation endpoints registered")
+	}
+
+	// Provably-fair endpoints (verification is public)
+	if fairHandler != nil {
+		mux.Handle("/api/fair/seeds", authMiddleware(http.HandlerFunc(fairHandler.GetSeeds)))
+		mux.Handle("/api/fair/rotate", authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				fairHandler.RotateSeed(w, r)
+			} else {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+		})))
+		mux.HandleFunc("/api/fair/verify", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				fairHandler.Verify(w, r)
+			} else {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+		})
+		log.Println("[Init] ✅ Provably-fair endpoints registered")
+	}
 
 	// Protected admin endpoints (requires admin role)
 	if adminHandler != nil {
@@ -263,8 +418,24 @@ func main() {
 				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			}
 		}))))
+		mux.Handle("/api/admin/ledger/trial-balance", authMiddleware(middleware.RequireAdmin(http.HandlerFunc(adminHandler.GetTrialBalance))))
+		mux.Handle("/api/admin/wallet/adjust", authMiddleware(middleware.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				adminHandler.AdjustBalance(w, r)
+			} else {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+		}))))
 		log.Println("[Init] ✅ Admin endpoints registered")
 	}
+	
+	// House-side RTP monitoring (admin only)
+	if rtpHandler != nil {
+		mux.Handle("/api/admin/rtp", authMiddleware(middleware.RequireAdmin(http.HandlerFunc(rtpHandler.GetReport))))
+		mux.Handle("/api/admin/rtp/alerts", authMiddleware(middleware.RequireAdmin(http.HandlerFunc(rtpHandler.GetAlerts))))
+		mux.Handle("/api/admin/rtp/alerts/", authMiddleware(middleware.RequireAdmin(http.HandlerFunc(rtpHandler.AcknowledgeAlert))))
+		log.Println("[Init] ✅ RTP monitor endpoints registered")
+	}
 
 	// Protected user endpoints
 	if userHandler != nil {
@@ -281,6 +452,21 @@ func main() {
 		log.Println("[Init] ✅ User endpoints registered")
 	}
 
+	// Saved payout methods (user CRUD, admin verification)
+	if payoutMethodHandler != nil {
+		mux.Handle("/api/user/payout-methods", authMiddleware(http.HandlerFunc(payoutMethodHandler.PayoutMethods)))
+		mux.Handle("/api/user/payout-methods/", authMiddleware(http.HandlerFunc(payoutMethodHandler.PayoutMethod)))
+		mux.Handle("/api/admin/payout-methods", authMiddleware(middleware.RequireAdmin(http.HandlerFunc(payoutMethodHandler.AdminListPayoutMethods))))
+		mux.Handle("/api/admin/payout-methods/", authMiddleware(middleware.RequireAdmin(http.HandlerFunc(payoutMethodHandler.AdminReviewPayoutMethod))))
+		log.Println("[Init] ✅ Payout method endpoints registered")
+	}
+
+	// Responsible-gambling limits players set on themselves
+	if limitHandler != nil {
+		mux.Handle("/api/user/limits", authMiddleware(http.HandlerFunc(limitHandler.Limits)))
+		log.Println("[Init] ✅ Limit endpoints registered")
+	}
+
 	// Game settings endpoints (public read, admin write)
 	if gameSettingsHandler != nil {
 		mux.HandleFunc("/api/game-settings", func(w http.ResponseWriter, r *http.Request) {
@@ -300,6 +486,9 @@ func main() {
 				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			}
 		}))
+		mux.Handle("/api/admin/game-settings/", authMiddleware(middleware.RequireAdmin(http.HandlerFunc(gameSettingsHandler.AdminGameSettings))))
+		mux.Handle("/api/admin/game-settings/schedules", authMiddleware(middleware.RequireAdmin(http.HandlerFunc(gameSettingsHandler.Schedules))))
+		mux.Handle("/api/admin/game-settings/schedules/", authMiddleware(middleware.RequireAdmin(http.HandlerFunc(gameSettingsHandler.CancelSchedule))))
 		log.Println("[Init] ✅ Game settings endpoints registered")
 	}
 
@@ -311,8 +500,9 @@ func main() {
 				allowedOrigin = "*"
 			}
 			w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key")
+			w.Header().Set("Access-Control-Expose-Headers", "Idempotent-Replayed")
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 
 			if r.Method == http.MethodOptions {
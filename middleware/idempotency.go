@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"betting-app-backend-go/services"
+)
+
+// IdempotencyHeader is the request header carrying the client's key
+const IdempotencyHeader = "Idempotency-Key"
+
+// IdempotencyKey is the context key holding the request's Idempotency-Key
+const IdempotencyKey contextKey = "idempotencyKey"
+
+// responseRecorder passes a response through while keeping a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Idempotency makes requests sent with an Idempotency-Key header safe to
+// retry. The first request with a key is processed and its response stored;
+// a retry with the same key and body gets the stored response replayed, and
+// the same key with a different body is rejected with 409. Server errors
+// release the key so the request can be retried. Requests without the
+// header and read-only requests are passed through unchanged. Must run
+// after AuthMiddleware since keys are scoped per user.
+//
+// The stored response is not written in one transaction with the work of
+// the request, as the server runs without a replica set. Games and payment
+// requests instead carry the key under their own unique index; see
+// IdempotencyService.
+func Idempotency(service *services.IdempotencyService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyHeader)
+			if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			userID, ok := GetUserID(r)
+			if !ok {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "failed to read request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			hash := sha256.New()
+			hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+			hash.Write(body)
+			requestHash := hex.EncodeToString(hash.Sum(nil))
+
+			record, replay, err := service.Begin(r.Context(), userID, key, r.Method, r.URL.Path, requestHash)
+			if err != nil {
+				log.Printf("[Idempotency] ❌ Key %s for user %s: %v\n", key, userID, err)
+				switch {
+				case errors.Is(err, services.ErrIdempotencyKeyReused), errors.Is(err, services.ErrIdempotencyKeyInFlight):
+					http.Error(w, err.Error(), http.StatusConflict)
+				case errors.Is(err, services.ErrIdempotencyKeyMalformed):
+					http.Error(w, err.Error(), http.StatusBadRequest)
+				default:
+					http.Error(w, "failed to process idempotency key", http.StatusInternalServerError)
+				}
+				return
+			}
+
+			if replay {
+				log.Printf("[Idempotency] Replaying response for key %s (user %s)\n", key, userID)
+				if record.ResponseContentType != "" {
+					w.Header().Set("Content-Type", record.ResponseContentType)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(record.ResponseStatus)
+				w.Write(record.ResponseBody)
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w}
+			ctx := context.WithValue(r.Context(), IdempotencyKey, key)
+			next.ServeHTTP(rec, r.WithContext(ctx))
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+
+			// Use a fresh context: the response must be stored even if the
+			// client has already gone away
+			if rec.status >= http.StatusInternalServerError {
+				if err := service.Release(context.Background(), record); err != nil {
+					log.Printf("[Idempotency] ❌ %v\n", err)
+				}
+				return
+			}
+			if err := service.Complete(context.Background(), record, rec.status, rec.Header().Get("Content-Type"), rec.body.Bytes()); err != nil {
+				log.Printf("[Idempotency] ❌ %v\n", err)
+			}
+		})
+	}
+}
+
+// GetIdempotencyKey returns the Idempotency-Key of the request, if any
+func GetIdempotencyKey(r *http.Request) string {
+	key, _ := r.Context().Value(IdempotencyKey).(string)
+	return key
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// AutoBet is a series of rounds of one game played by the server on the
+// player's behalf. Every round is an ordinary Game; the job only tracks
+// the stake to use next and when to stop.
+type AutoBet struct {
+	ID           string                 `bson:"_id" json:"id"`
+	UserID       string                 `bson:"user_id" json:"userId"`
+	GameType     string                 `bson:"game_type" json:"gameType"`
+	BaseBet      Money                  `bson:"base_bet" json:"baseBet"`
+	Choices      map[string]interface{} `bson:"choices,omitempty" json:"choices,omitempty"`
+	Rounds       int                    `bson:"rounds" json:"rounds"`
+	OnWin        AutoBetAdjust          `bson:"on_win" json:"onWin"`
+	OnLoss       AutoBetAdjust          `bson:"on_loss" json:"onLoss"`
+	StopOnProfit Money                  `bson:"stop_on_profit,omitempty" json:"stopOnProfit,omitempty"` // Stop once profit reaches this
+	StopOnLoss   Money                  `bson:"stop_on_loss,omitempty" json:"stopOnLoss,omitempty"`     // Stop once losses reach this
+
+	Status          string `bson:"status" json:"status"`                              // running, completed, cancelled, stopped
+	StopReason      string `bson:"stop_reason,omitempty" json:"stopReason,omitempty"` // rounds_completed, profit_target, loss_limit, cancelled, insufficient_balance, bet_limit, limit_reached, game_disabled, interrupted, error
+	Error           string `bson:"error,omitempty" json:"error,omitempty"`
+	CancelRequested bool   `bson:"cancel_requested" json:"cancelRequested"`
+
+	Played     int    `bson:"played" json:"played"`
+	Wins       int    `bson:"wins" json:"wins"`
+	Wagered    Money  `bson:"wagered" json:"wagered"`
+	Won        Money  `bson:"won" json:"won"`
+	Profit     Money  `bson:"profit" json:"profit"`          // Won - Wagered
+	CurrentBet Money  `bson:"current_bet" json:"currentBet"` // Stake of the next round
+	LastGameID string `bson:"last_game_id,omitempty" json:"lastGameId,omitempty"`
+
+	CreatedAt  time.Time  `bson:"created_at" json:"createdAt"`
+	UpdatedAt  time.Time  `bson:"updated_at" json:"updatedAt"`
+	FinishedAt *time.Time `bson:"finished_at,omitempty" json:"finishedAt,omitempty"`
+}
+
+// AutoBetAdjust says how the stake changes after a round: back to the base
+// bet, or up by Percent of the last stake
+type AutoBetAdjust struct {
+	Action  string  `bson:"action" json:"action"` // reset, increase
+	Percent float64 `bson:"percent,omitempty" json:"percent,omitempty"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+)
+
+// AviationRound is one shared round of the aviation crash game. Every player
+// bets into the same round: bets are taken while the round is "betting",
+// the multiplier climbs while it is "flying", and all bets are settled at
+// once after it has "crashed". The crash point is drawn from ServerSeed
+// before betting opens; only the seed's hash is shown until the crash.
+type AviationRound struct {
+	ID              string     `bson:"_id" json:"id"`
+	Number          int64      `bson:"number" json:"number"`
+	Status          string     `bson:"status" json:"status"` // betting, flying, crashed, settled, cancelling, cancelled
+	CrashPoint      float64    `bson:"crash_point" json:"crashPoint,omitempty"`
+	ServerSeed      string     `bson:"server_seed" json:"serverSeed,omitempty"`
+	ServerSeedHash  string     `bson:"server_seed_hash" json:"serverSeedHash"`
+	SettingsVersion int64      `bson:"settings_version" json:"settingsVersion"`
+	BettingEndsAt   time.Time  `bson:"betting_ends_at" json:"bettingEndsAt"`
+	StartedAt       *time.Time `bson:"started_at,omitempty" json:"startedAt,omitempty"`
+	CrashedAt       *time.Time `bson:"crashed_at,omitempty" json:"crashedAt,omitempty"`
+	SettledAt       *time.Time `bson:"settled_at,omitempty" json:"settledAt,omitempty"`
+	CreatedAt       time.Time  `bson:"created_at" json:"createdAt"`
+
+	// Bets lists the bets the round accepted while it was taking bets, and
+	// Cashouts the cash-outs it accepted while it was flying. Both are
+	// added with an update conditional on the round's status, so none can
+	// land after the round has taken off or crashed.
+	Bets     []string          `bson:"bets,omitempty" json:"-"`
+	Cashouts []AviationCashout `bson:"cashouts,omitempty" json:"-"`
+}
+
+// AviationCashout is a cash-out accepted by a round in flight
+type AviationCashout struct {
+	BetID      string    `bson:"bet_id" json:"betId"`
+	Multiplier float64   `bson:"multiplier" json:"multiplier"`
+	At         time.Time `bson:"at" json:"at"`
+}
+
+// Public returns a copy without the crash point and server seed while the
+// round is still open
+func (r AviationRound) Public() AviationRound {
+	if r.Status == "betting" || r.Status == "flying" {
+		r.CrashPoint = 0
+		r.ServerSeed = ""
+	}
+	return r
+}
+
+// AviationLease names the server instance that runs the aviation rounds.
+// The holder extends it before every round; another instance takes over
+// once it has expired.
+type AviationLease struct {
+	ID        string    `bson:"_id" json:"id"` // Always "aviation"
+	Holder    string    `bson:"holder" json:"holder"`
+	ExpiresAt time.Time `bson:"expires_at" json:"expiresAt"`
+}
+
+// AviationBet is one player's bet on a round
+type AviationBet struct {
+	ID                string     `bson:"_id" json:"id"`
+	RoundID           string     `bson:"round_id" json:"roundId"`
+	UserID            string     `bson:"user_id" json:"userId"`
+	Amount            Money      `bson:"amount" json:"amount"`
+	AutoCashout       float64    `bson:"auto_cashout,omitempty" json:"autoCashout,omitempty"` // Cash out automatically at this multiplier
+	Status            string     `bson:"status" json:"status"`                                // pending, active, cashed_out, won, lost, refunded
+	CashoutMultiplier float64    `bson:"cashout_multiplier,omitempty" json:"cashoutMultiplier,omitempty"`
+	WinAmount         Money      `bson:"win_amount" json:"winAmount"`
+	GameID            string     `bson:"game_id,omitempty" json:"gameId,omitempty"` // Game recorded on settlement
+	CreatedAt         time.Time  `bson:"created_at" json:"createdAt"`
+	CashedOutAt       *time.Time `bson:"cashed_out_at,omitempty" json:"cashedOutAt,omitempty"`
+}
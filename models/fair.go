@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// SeedPair is a provably-fair commitment for one user. The server seed stays
+// secret while the pair is active; only its SHA-256 hash is shown until the
+// pair is rotated out and revealed.
+type SeedPair struct {
+	ID             string     `bson:"_id" json:"id"`
+	UserID         string     `bson:"user_id" json:"userId"`
+	ServerSeed     string     `bson:"server_seed" json:"-"`
+	ServerSeedHash string     `bson:"server_seed_hash" json:"serverSeedHash"`
+	ClientSeed     string     `bson:"client_seed" json:"clientSeed"`
+	Nonce          int64      `bson:"nonce" json:"nonce"` // Next nonce to be used
+	Active         bool       `bson:"active" json:"active"`
+	CreatedAt      time.Time  `bson:"created_at" json:"createdAt"`
+	RevealedAt     *time.Time `bson:"revealed_at,omitempty" json:"revealedAt,omitempty"`
+
+	// RotatingUntil is set while a rotation checks for open sessions
+	// before revealing the pair; sessions can't start on it meanwhile
+	RotatingUntil *time.Time `bson:"rotating_until,omitempty" json:"-"`
+}
+
+// FairVerification is the result of replaying a round from its seeds
+type FairVerification struct {
+	GameID          string                 `json:"gameId,omitempty"`
+	GameType        string                 `json:"gameType"`
+	ServerSeed      string                 `json:"serverSeed"`
+	ServerSeedHash  string                 `json:"serverSeedHash"`
+	ClientSeed      string                 `json:"clientSeed"`
+	Nonce           int64                  `json:"nonce"`
+	SettingsVersion int64                  `json:"settingsVersion"`
+	Multiplier      float64                `json:"multiplier"`
+	ResultData      map[string]interface{} `json:"resultData"`
+	Verified        bool                   `json:"verified"` // Replayed outcome matches the recorded game
+}
@@ -8,20 +8,31 @@ type Game struct {
 	ID         string                 `bson:"_id" json:"id"`
 	UserID     string                 `bson:"user_id" json:"userId"`
 	GameType   string                 `bson:"game_type" json:"gameType"` // aviation, spinwheel, slot, mines, plinko, dice, limbo, hilo, blackjack
-	BetAmount  float64                `bson:"bet_amount" json:"betAmount"`
-	WinAmount  float64                `bson:"win_amount" json:"winAmount"`
+	BetAmount  Money                  `bson:"bet_amount" json:"betAmount"`
+	WinAmount  Money                  `bson:"win_amount" json:"winAmount"`
 	Multiplier float64                `bson:"multiplier,omitempty" json:"multiplier,omitempty"`
 	ResultData map[string]interface{} `bson:"result_data,omitempty" json:"resultData,omitempty"` // Game-specific data
 	Settled    bool                   `bson:"settled" json:"settled"`
 	CreatedAt  time.Time              `bson:"created_at" json:"createdAt"`
+
+	// Version of the game settings the round was played under
+	SettingsVersion int64 `bson:"settings_version" json:"settingsVersion"`
+
+	// Provably-fair inputs the outcome was derived from
+	ServerSeedHash string `bson:"server_seed_hash,omitempty" json:"serverSeedHash,omitempty"`
+	ClientSeed     string `bson:"client_seed,omitempty" json:"clientSeed,omitempty"`
+	Nonce          int64  `bson:"nonce" json:"nonce"`
+
+	// Idempotency-Key of the request that played the round
+	IdempotencyKey string `bson:"idempotency_key,omitempty" json:"-"`
 }
 
 type GameStats struct {
 	UserID          string    `bson:"user_id" json:"userId"`
 	GameType        string    `bson:"game_type" json:"gameType"`
 	TotalGames      int64     `bson:"total_games" json:"totalGames"`
-	TotalWagered    float64   `bson:"total_wagered" json:"totalWagered"`
-	TotalWon        float64   `bson:"total_won" json:"totalWon"`
-	BiggestWin      float64   `bson:"biggest_win" json:"biggestWin"`
+	TotalWagered    Money     `bson:"total_wagered" json:"totalWagered"`
+	TotalWon        Money     `bson:"total_won" json:"totalWon"`
+	BiggestWin      Money     `bson:"biggest_win" json:"biggestWin"`
 	LastPlayedAt    time.Time `bson:"last_played_at" json:"lastPlayedAt"`
 }
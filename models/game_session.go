@@ -0,0 +1,143 @@
+package models
+
+import (
+	"time"
+)
+
+// GameSession is a multi-step round (mines, ...) that is played over
+// several requests. The bet is taken when the session starts and the round
+// is settled into a Game when the player cashes out, loses, or the session
+// times out. Sessions live in MongoDB so they survive a server restart.
+type GameSession struct {
+	ID         string  `bson:"_id" json:"id"`
+	UserID     string  `bson:"user_id" json:"userId"`
+	GameType   string  `bson:"game_type" json:"gameType"`
+	BetAmount  Money   `bson:"bet_amount" json:"betAmount"`                // Total wagered, including extra wagers made during play
+	Status     string  `bson:"status" json:"status"`                       // active, settling, settled
+	Outcome    string  `bson:"outcome,omitempty" json:"outcome,omitempty"` // cashed_out, busted, won, lost, push, expired
+	Multiplier float64 `bson:"multiplier" json:"multiplier"`               // Payout multiplier if cashed out now
+	WinAmount  Money   `bson:"win_amount" json:"winAmount"`                // Paid out on settlement
+
+	// What the player did, so the round can be replayed for verification
+	Stake   Money                  `bson:"stake" json:"stake"` // Bet the session started with
+	Choices map[string]interface{} `bson:"choices,omitempty" json:"choices,omitempty"`
+	Actions []SessionAction        `bson:"actions" json:"actions"`
+
+	// Game-specific state; fields that would give away the outcome are
+	// hidden until the session is settled (see Public)
+	Mines     *MinesState     `bson:"mines,omitempty" json:"mines,omitempty"`
+	Blackjack *BlackjackState `bson:"blackjack,omitempty" json:"blackjack,omitempty"`
+	HiLo      *HiLoState      `bson:"hilo,omitempty" json:"hilo,omitempty"`
+
+	// Provably-fair inputs. The server seed is kept on the session so
+	// outcomes drawn later in the round come from the same stream.
+	SettingsVersion int64  `bson:"settings_version" json:"settingsVersion"`
+	ServerSeed      string `bson:"server_seed" json:"-"`
+	ServerSeedHash  string `bson:"server_seed_hash" json:"serverSeedHash"`
+	ClientSeed      string `bson:"client_seed" json:"clientSeed"`
+	Nonce           int64  `bson:"nonce" json:"nonce"`
+	Draws           int    `bson:"draws" json:"-"` // Random numbers used so far
+
+	GameID    string     `bson:"game_id,omitempty" json:"gameId,omitempty"` // Game recorded on settlement
+	Version   int64      `bson:"version" json:"version"`                    // Incremented on every action
+	CreatedAt time.Time  `bson:"created_at" json:"createdAt"`
+	UpdatedAt time.Time  `bson:"updated_at" json:"updatedAt"`
+	ExpiresAt time.Time  `bson:"expires_at" json:"expiresAt"` // Settled automatically after this
+	SettledAt *time.Time `bson:"settled_at,omitempty" json:"settledAt,omitempty"`
+}
+
+// SessionAction is one accepted player action, in order. An action that
+// makes an extra wager (double, split, insurance) records it and the
+// wallet reference it was debited under.
+type SessionAction struct {
+	Action    string                 `bson:"action" json:"action"`
+	Params    map[string]interface{} `bson:"params,omitempty" json:"params,omitempty"`
+	Wager     Money                  `bson:"wager,omitempty" json:"wager,omitempty"`
+	Reference string                 `bson:"reference,omitempty" json:"reference,omitempty"`
+}
+
+// MinesState is the board of a mines session. Cells are numbered row by
+// row from 0 to grid_size²-1.
+type MinesState struct {
+	GridSize      int   `bson:"grid_size" json:"gridSize"`
+	Mines         int   `bson:"mines" json:"mines"`
+	MinePositions []int `bson:"mine_positions" json:"minePositions,omitempty"`
+	Revealed      []int `bson:"revealed" json:"revealed"`
+	HitMine       int   `bson:"hit_mine" json:"hitMine"` // Cell of the mine that ended the round, -1 if none
+}
+
+// BlackjackState is the table of a blackjack session. Cards are written as
+// rank and suit, e.g. "AS", "TD", "9H".
+type BlackjackState struct {
+	Shoe       []string        `bson:"shoe" json:"-"`
+	ShoeIndex  int             `bson:"shoe_index" json:"-"` // Next card to deal
+	Dealer     []string        `bson:"dealer" json:"dealer"`
+	Hands      []BlackjackHand `bson:"hands" json:"hands"`
+	ActiveHand int             `bson:"active_hand" json:"activeHand"`
+
+	// Insurance is offered when the dealer shows an ace, before any other
+	// action; taking it costs half the original bet and pays 2:1
+	InsuranceOffered bool  `bson:"insurance_offered" json:"insuranceOffered"`
+	InsuranceBet     Money `bson:"insurance_bet" json:"insuranceBet"`
+	InsurancePayout  Money `bson:"insurance_payout" json:"insurancePayout"`
+
+	History []BlackjackEvent `bson:"history" json:"history"`
+}
+
+// BlackjackHand is one player hand; splitting adds hands
+type BlackjackHand struct {
+	Cards   []string `bson:"cards" json:"cards"`
+	Bet     Money    `bson:"bet" json:"bet"`
+	Doubled bool     `bson:"doubled" json:"doubled"`
+	Split   bool     `bson:"split" json:"split"`
+	Done    bool     `bson:"done" json:"done"`
+	Result  string   `bson:"result,omitempty" json:"result,omitempty"` // blackjack, win, push, lose, bust
+	Payout  Money    `bson:"payout" json:"payout"`
+}
+
+// BlackjackEvent is one step of a blackjack round, in order
+type BlackjackEvent struct {
+	Action string `bson:"action" json:"action"` // deal, hit, stand, double, split, insurance, decline_insurance, dealer_reveal, dealer_hit
+	Hand   int    `bson:"hand" json:"hand"`     // Player hand, -1 for the dealer
+	Card   string `bson:"card,omitempty" json:"card,omitempty"`
+}
+
+// HiLoState is the card sequence of a hi-lo session. Every card is drawn
+// from a full deck from the session's provably-fair stream, so the n-th
+// card of the round is the n-th float of the stream. The last card is the
+// one to guess against.
+type HiLoState struct {
+	Cards   []string    `bson:"cards" json:"cards"`
+	Guesses []HiLoGuess `bson:"guesses" json:"guesses"`
+	Streak  int         `bson:"streak" json:"streak"` // Correct guesses so far
+}
+
+// HiLoGuess is one guess (or skip) and the card it was played against
+type HiLoGuess struct {
+	Guess      string  `bson:"guess" json:"guess"` // higher, lower, skip
+	From       string  `bson:"from" json:"from"`
+	Card       string  `bson:"card" json:"card"`
+	Won        bool    `bson:"won" json:"won"`
+	Multiplier float64 `bson:"multiplier" json:"multiplier"` // Session multiplier after the guess
+}
+
+// Public returns a copy that is safe to send to the player: while the
+// session is still in play, nothing that reveals the outcome is included
+func (s GameSession) Public() GameSession {
+	if s.Status == "settled" {
+		return s
+	}
+	if s.Mines != nil {
+		mines := *s.Mines
+		mines.MinePositions = nil
+		s.Mines = &mines
+	}
+	if s.Blackjack != nil {
+		table := *s.Blackjack
+		if len(table.Dealer) > 1 {
+			table.Dealer = table.Dealer[:1] // Hole card stays face down
+		}
+		s.Blackjack = &table
+	}
+	return s
+}
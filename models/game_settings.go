@@ -9,37 +9,78 @@ type GameSettings struct {
 	GameType    string                 `bson:"game_type" json:"game_type"`
 	DisplayName string                 `bson:"display_name" json:"display_name"`
 	Enabled     bool                   `bson:"enabled" json:"enabled"`
-	MinBet      float64                `bson:"min_bet" json:"min_bet"`
-	MaxBet      float64                `bson:"max_bet" json:"max_bet"`
+	MinBet      Money                  `bson:"min_bet" json:"min_bet"`
+	MaxBet      Money                  `bson:"max_bet" json:"max_bet"`
 	HouseEdge   float64                `bson:"house_edge" json:"house_edge"` // Percentage (e.g., 2.5 = 2.5%)
 	Config      map[string]interface{} `bson:"config" json:"config"`         // Game-specific configuration
 	UpdatedAt   time.Time              `bson:"updated_at" json:"updated_at"`
 	UpdatedBy   string                 `bson:"updated_by" json:"updated_by"` // Admin UID
+	Version     int64                  `bson:"version" json:"version"`       // Incremented on every change
+}
+
+// GameSettingsVersion is an immutable record of one change to a game's
+// settings, holding the full settings before and after the change.
+type GameSettingsVersion struct {
+	ID           string        `bson:"_id" json:"id"`
+	GameType     string        `bson:"game_type" json:"game_type"`
+	Version      int64         `bson:"version" json:"version"`
+	Action       string        `bson:"action" json:"action"` // initial, update, rollback, migrate, disable
+	RolledBackTo int64         `bson:"rolled_back_to,omitempty" json:"rolled_back_to,omitempty"`
+	Before       *GameSettings `bson:"before,omitempty" json:"before,omitempty"`
+	After        GameSettings  `bson:"after" json:"after"`
+	ChangedBy    string        `bson:"changed_by" json:"changed_by"`
+	ChangedAt    time.Time     `bson:"changed_at" json:"changed_at"`
+}
+
+// SettingsFieldChange is one field that differs between two settings versions
+type SettingsFieldChange struct {
+	Field string      `json:"field"` // e.g. "max_bet", "config.crash_chances.low"
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
 }
 
 // Game-specific configuration structures
 
 // SpinWheelConfig holds SpinWheel game configuration
 type SpinWheelConfig struct {
-	Multipliers []float64 `json:"multipliers"` // e.g., [1.2, 1.5, 2.0, 0, 1.8, 0.5, 3.0, 0]
+	Multipliers []float64 `json:"multipliers"` // One per segment, in wheel order, e.g., [0, 1.5, 0.5, 2.0, 0, 3.0, 5.0, 10.0]
+	Weights     []int     `json:"weights,omitempty"` // Relative chance of each segment; default all equal
+	Colors      []string  `json:"colors,omitempty"`  // "#RRGGBB" per segment, for the wheel's rendering
 }
 
 // AviationConfig holds Aviation game configuration
 type AviationConfig struct {
-	MinMultiplier float64 `json:"min_multiplier"` // e.g., 1.01
+	MinMultiplier float64 `json:"min_multiplier"` // e.g., 1.00, a crash at take-off
 	MaxMultiplier float64 `json:"max_multiplier"` // e.g., 50.0
 	CrashChances  struct {
-		Low    float64 `json:"low"`    // % chance for 1.01-3x (e.g., 50)
-		Medium float64 `json:"medium"` // % chance for 3-5x (e.g., 30)
-		High   float64 `json:"high"`   // % chance for 5-10x (e.g., 15)
-		VeryHigh float64 `json:"very_high"` // % chance for 10-50x (e.g., 5)
+		Low    float64 `json:"low"`    // % chance for 1-3x before the house edge (e.g., 66.5)
+		Medium float64 `json:"medium"` // % chance for 3-5x (e.g., 13.5)
+		High   float64 `json:"high"`   // % chance for 5-10x (e.g., 10)
+		VeryHigh float64 `json:"very_high"` // % chance for 10x and up (e.g., 10)
 	} `json:"crash_chances"`
 }
 
 // SlotConfig holds Slot game configuration
 type SlotConfig struct {
 	Symbols     []string           `json:"symbols"` // e.g., ["🍒", "🍋", "🍊", "🍇", "💎", "⭐", "7️⃣"]
-	Multipliers map[string]float64 `json:"multipliers"` // Symbol -> Multiplier mapping
+	Multipliers map[string]float64 `json:"multipliers"` // Symbol -> multiplier for a full line, used when Paytable is empty
+	Reels       int                `json:"reels,omitempty"`       // e.g., 5 (default 3)
+	Rows        int                `json:"rows,omitempty"`        // Visible rows per reel, e.g., 3 (default 1)
+	ReelStrips  [][]SlotStop       `json:"reel_strips,omitempty"` // One strip per reel; default every symbol once
+	Paytable    map[string][]float64 `json:"paytable,omitempty"`  // Symbol -> multiplier of the line bet for 1..Reels in a row from the left
+	Paylines    [][]int            `json:"paylines,omitempty"`    // Row index on each reel; default the middle row
+	Wild        string             `json:"wild,omitempty"`        // Substitutes for every symbol but the scatter
+	Scatter     string             `json:"scatter,omitempty"`     // Pays anywhere in view, never on a line
+	ScatterPays []float64          `json:"scatter_pays,omitempty"` // Multiplier of the total bet for 1, 2, ... scatters in view
+	FreeSpinsTrigger int           `json:"free_spins_trigger,omitempty"` // Scatters needed to award free spins
+	FreeSpins   int                `json:"free_spins,omitempty"`  // Free spins awarded per trigger
+}
+
+// SlotStop is one position on a reel strip. Weight is how often the reel
+// stops there relative to the strip's other stops.
+type SlotStop struct {
+	Symbol string `json:"symbol"`
+	Weight int    `json:"weight"`
 }
 
 // MinesConfig holds Mines game configuration
@@ -48,7 +89,7 @@ type MinesConfig struct {
 	MinMines      int     `json:"min_mines"`       // e.g., 3
 	MaxMines      int     `json:"max_mines"`       // e.g., 10
 	DefaultMines  int     `json:"default_mines"`   // e.g., 5
-	MultiplierBase float64 `json:"multiplier_base"` // Base for calculating multiplier
+	MultiplierBase float64 `json:"multiplier_base"` // Exponent/2 applied to the fair odds; 0.5 pays the full odds less the house edge
 }
 
 // PlinkoConfig holds Plinko game configuration
@@ -88,3 +129,26 @@ type BlackjackConfig struct {
 	AllowDoubleDown   bool    `json:"allow_double_down"`
 	AllowSplit        bool    `json:"allow_split"`
 }
+
+// GameSettingsSchedule is a settings change planned for a future time
+// window. Either Settings (a full replacement) or Enabled (only toggle the
+// game) is set. When EndAt is set the previous settings are restored then.
+type GameSettingsSchedule struct {
+	ID             string        `bson:"_id" json:"id"`
+	GameType       string        `bson:"game_type" json:"game_type"`
+	Settings       *GameSettings `bson:"settings,omitempty" json:"settings,omitempty"`
+	Enabled        *bool         `bson:"enabled,omitempty" json:"enabled,omitempty"`
+	StartAt        time.Time     `bson:"start_at" json:"start_at"`
+	EndAt          *time.Time    `bson:"end_at,omitempty" json:"end_at,omitempty"`
+	Status         string        `bson:"status" json:"status"` // pending, applying, active, reverting, completed, cancelled, failed
+	ClaimedAt      *time.Time    `bson:"claimed_at,omitempty" json:"claimed_at,omitempty"` // When an instance started applying or reverting it
+	Previous       *GameSettings `bson:"previous,omitempty" json:"previous,omitempty"` // Settings replaced when applied
+	AppliedVersion int64         `bson:"applied_version,omitempty" json:"applied_version,omitempty"`
+	RevertedVersion int64        `bson:"reverted_version,omitempty" json:"reverted_version,omitempty"`
+	Error          string        `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedBy      string        `bson:"created_by" json:"created_by"`
+	CreatedAt      time.Time     `bson:"created_at" json:"created_at"`
+	AppliedAt      *time.Time    `bson:"applied_at,omitempty" json:"applied_at,omitempty"`
+	RevertedAt     *time.Time    `bson:"reverted_at,omitempty" json:"reverted_at,omitempty"`
+	CancelledBy    string        `bson:"cancelled_by,omitempty" json:"cancelled_by,omitempty"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyRecord stores the outcome of a mutating request sent with an
+// Idempotency-Key header so a retry gets the same response instead of being
+// processed again.
+type IdempotencyRecord struct {
+	ID          string `bson:"_id" json:"id"`
+	UserID      string `bson:"user_id" json:"userId"`
+	Key         string `bson:"key" json:"key"`
+	Method      string `bson:"method" json:"method"`
+	Path        string `bson:"path" json:"path"`
+	RequestHash string `bson:"request_hash" json:"requestHash"` // SHA-256 of method, path and body
+	Status      string `bson:"status" json:"status"`            // processing, completed
+
+	// Stored response, set once completed
+	ResponseStatus      int    `bson:"response_status,omitempty" json:"responseStatus,omitempty"`
+	ResponseContentType string `bson:"response_content_type,omitempty" json:"responseContentType,omitempty"`
+	ResponseBody        []byte `bson:"response_body,omitempty" json:"-"`
+
+	LockedUntil time.Time `bson:"locked_until" json:"lockedUntil"` // A processing record older than this can be taken over
+	CreatedAt   time.Time `bson:"created_at" json:"createdAt"`
+	ExpiresAt   time.Time `bson:"expires_at" json:"expiresAt"` // Removed by a TTL index
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Ledger accounts. Every user wallet has its own account, named with
+// UserAccount.
+const (
+	// AccountHouseCash is money held in the house's bank accounts
+	AccountHouseCash = "house:cash"
+	// AccountHouseBankroll is the house's own funds; it takes the other
+	// side of every bet and win
+	AccountHouseBankroll = "house:bankroll"
+	// AccountPendingDeposits holds deposits reported by users that an
+	// admin has not accepted or declined yet
+	AccountPendingDeposits = "pending:deposits"
+	// AccountPendingWithdrawals holds funds locked for withdrawals that
+	// have not been paid out yet
+	AccountPendingWithdrawals = "pending:withdrawals"
+	// AccountBonusLiability funds bonus credits granted to users
+	AccountBonusLiability = "liability:bonus"
+)
+
+const userAccountPrefix = "user:"
+
+// UserAccount returns the ledger account of a user's wallet
+func UserAccount(userID string) string {
+	return userAccountPrefix + userID
+}
+
+// IsUserAccount reports whether account is a user wallet account
+func IsUserAccount(account string) bool {
+	return strings.HasPrefix(account, userAccountPrefix)
+}
+
+// LedgerPosting is one line of a journal entry. Exactly one of Debit and
+// Credit is set.
+type LedgerPosting struct {
+	Account string `bson:"account" json:"account"`
+	Debit   Money  `bson:"debit" json:"debit"`
+	Credit  Money  `bson:"credit" json:"credit"`
+}
+
+// LedgerEntry is a balanced journal entry: the debits of its postings equal
+// the credits. Entries are never updated; mistakes are corrected with a new
+// entry.
+type LedgerEntry struct {
+	ID          string          `bson:"_id" json:"id"`
+	UserID      string          `bson:"user_id,omitempty" json:"userId,omitempty"`
+	Category    string          `bson:"category" json:"category"` // deposit, deposit_submitted, deposit_declined, game_loss, game_win, game_refund, admin_adjustment, opening_balance
+	Description string          `bson:"description" json:"description"`
+	Reference   string          `bson:"reference,omitempty" json:"reference,omitempty"` // Payment request id, etc.
+	Postings    []LedgerPosting `bson:"postings" json:"postings"`
+
+	// Wallet state after the entry, for entries that move a user's balance
+	BalanceBefore Money `bson:"balance_before,omitempty" json:"balanceBefore,omitempty"`
+	BalanceAfter  Money `bson:"balance_after,omitempty" json:"balanceAfter,omitempty"`
+	WalletVersion int64 `bson:"wallet_version,omitempty" json:"walletVersion,omitempty"`
+
+	CreatedBy string    `bson:"created_by,omitempty" json:"createdBy,omitempty"` // Admin uid for adjustments
+	CreatedAt time.Time `bson:"created_at" json:"createdAt"`
+}
+
+// TrialBalanceLine is the total of one account (or of all user wallets)
+type TrialBalanceLine struct {
+	Account string `json:"account"`
+	Debit   Money  `json:"debit"`
+	Credit  Money  `json:"credit"`
+	Balance Money  `json:"balance"` // Debit - Credit
+}
+
+// WalletMismatch is a wallet whose balance differs from its ledger account
+type WalletMismatch struct {
+	UserID        string `json:"userId"`
+	WalletBalance Money  `json:"walletBalance"`
+	LedgerBalance Money  `json:"ledgerBalance"`
+}
+
+type TrialBalance struct {
+	Accounts          []TrialBalanceLine `json:"accounts"`
+	TotalDebit        Money              `json:"totalDebit"`
+	TotalCredit       Money              `json:"totalCredit"`
+	Balanced          bool               `json:"balanced"`
+	UnbalancedEntries int64              `json:"unbalancedEntries"`
+	UserAccounts      int                `json:"userAccounts"`
+	WalletMismatches  []WalletMismatch   `json:"walletMismatches"`
+	GeneratedAt       time.Time          `json:"generatedAt"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+)
+
+// Responsible-gambling limit kinds and the periods they apply over
+var (
+	LimitKinds   = []string{"deposit", "loss", "wager"}
+	LimitPeriods = []string{"day", "week", "month"}
+)
+
+// LimitKey names one limit, e.g. "loss_week"
+func LimitKey(kind, period string) string {
+	return kind + "_" + period
+}
+
+// PlayerLimits are the limits a player has set on themselves
+type PlayerLimits struct {
+	UserID    string                  `bson:"_id" json:"userId"`
+	Limits    map[string]*PlayerLimit `bson:"limits" json:"limits"` // By LimitKey
+	Version   int64                   `bson:"version" json:"version"`
+	UpdatedAt time.Time               `bson:"updated_at" json:"updatedAt"`
+}
+
+// PlayerLimit is one limit. A raise (or removal) waits out a cooling-off
+// delay as PendingAmount; until PendingFrom the previous Amount applies.
+type PlayerLimit struct {
+	Amount        Money      `bson:"amount" json:"amount"` // 0 = no limit
+	PendingAmount Money      `bson:"pending_amount,omitempty" json:"pendingAmount,omitempty"`
+	PendingFrom   *time.Time `bson:"pending_from,omitempty" json:"pendingFrom,omitempty"`
+	UpdatedAt     time.Time  `bson:"updated_at" json:"updatedAt"`
+}
+
+// Effective returns the limit in force at a time
+func (l *PlayerLimit) Effective(at time.Time) Money {
+	if l == nil {
+		return 0
+	}
+	if l.PendingFrom != nil && !at.Before(*l.PendingFrom) {
+		return l.PendingAmount
+	}
+	return l.Amount
+}
+
+// LimitUsage is one period's running totals for a player. Loss is stakes
+// minus winnings, so it goes down (and can go negative) as wins are paid.
+type LimitUsage struct {
+	ID        string    `bson:"_id" json:"-"` // user_id:period:start date
+	UserID    string    `bson:"user_id" json:"-"`
+	Period    string    `bson:"period" json:"period"`
+	Start     time.Time `bson:"start" json:"start"`
+	Deposit   Money     `bson:"deposit" json:"deposit"`
+	Wager     Money     `bson:"wager" json:"wager"`
+	Loss      Money     `bson:"loss" json:"loss"`
+	ExpiresAt time.Time `bson:"expires_at" json:"-"`
+}
+
+// LimitStatus is a limit with the player's usage in the current period
+type LimitStatus struct {
+	Kind          string     `json:"kind"`   // deposit, loss, wager
+	Period        string     `json:"period"` // day, week, month
+	Limit         Money      `json:"limit"`  // 0 = no limit
+	Used          Money      `json:"used"`
+	Remaining     *Money     `json:"remaining,omitempty"` // Unset without a limit
+	PendingLimit  *Money     `json:"pendingLimit,omitempty"`
+	PendingFrom   *time.Time `json:"pendingFrom,omitempty"`
+	PeriodStart   time.Time  `json:"periodStart"`
+	PeriodResetAt time.Time  `json:"periodResetAt"`
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// CurrencyINR is the only wallet currency; Money amounts are in its minor
+// unit (paise).
+const CurrencyINR = "INR"
+
+// minorUnits is the number of minor units (paise) per major unit (rupee)
+const minorUnits = 100
+
+// Money is an amount in paise. It is stored in MongoDB as an int64 and sent
+// over JSON as rupees with at most two decimals (e.g. 1234.5), so the API
+// keeps its existing shape.
+//
+// Documents written before Money existed hold amounts as float rupees.
+// Those BSON doubles are still read correctly: a double is always rupees
+// and an integer is always paise.
+type Money int64
+
+// MoneyFromMajor converts rupees to Money, rounding half away from zero to
+// the nearest paisa.
+func MoneyFromMajor(v float64) Money {
+	return Money(math.Round(v * minorUnits))
+}
+
+// Major returns the amount in rupees
+func (m Money) Major() float64 {
+	return float64(m) / minorUnits
+}
+
+// String formats the amount in rupees with two decimals
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/minorUnits, v%minorUnits)
+}
+
+// MulMultiplier returns the payout of a stake at the given multiplier. The
+// multiplier is rounded to 4 decimal places and the payout is truncated
+// towards zero to whole paise, so rounding never pays out more than the
+// exact amount.
+func (m Money) MulMultiplier(multiplier float64) Money {
+	bp := big.NewInt(int64(math.Round(multiplier * 10000)))
+	product := new(big.Int).Mul(big.NewInt(int64(m)), bp)
+	product.Quo(product, big.NewInt(10000))
+	if !product.IsInt64() {
+		return Money(math.MaxInt64)
+	}
+	return Money(product.Int64())
+}
+
+// RoundMultiplier rounds a multiplier the same way MulMultiplier does, so
+// the multiplier stored with a game matches the payout
+func RoundMultiplier(multiplier float64) float64 {
+	return math.Round(multiplier*10000) / 10000
+}
+
+// MoneyFromAny converts a raw value read from MongoDB (e.g. from a bson.M
+// or an aggregation result) to Money
+func MoneyFromAny(v interface{}) Money {
+	switch n := v.(type) {
+	case int64:
+		return Money(n)
+	case int32:
+		return Money(n)
+	case int:
+		return Money(n)
+	case float64:
+		return MoneyFromMajor(n)
+	case Money:
+		return n
+	}
+	return 0
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatFloat(m.Major(), 'f', -1, 64)), nil
+}
+
+// UnmarshalJSON accepts a number or numeric string in rupees. Amounts with
+// more than two decimals are rejected rather than silently rounded.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+
+	v, err := parseMajor(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// parseMajor parses a decimal rupee amount exactly
+func parseMajor(s string) (Money, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	r.Mul(r, big.NewRat(minorUnits, 1))
+	if !r.IsInt() {
+		return 0, fmt.Errorf("invalid amount %q: at most 2 decimal places allowed", s)
+	}
+	if !r.Num().IsInt64() {
+		return 0, fmt.Errorf("invalid amount %q: out of range", s)
+	}
+	return Money(r.Num().Int64()), nil
+}
+
+func (m Money) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return bsontype.Int64, bsoncore.AppendInt64(nil, int64(m)), nil
+}
+
+func (m *Money) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	v := bsoncore.Value{Type: t, Data: data}
+	switch t {
+	case bsontype.Int64:
+		*m = Money(v.Int64())
+	case bsontype.Int32:
+		*m = Money(v.Int32())
+	case bsontype.Double:
+		// Legacy float rupees
+		*m = MoneyFromMajor(v.Double())
+	case bsontype.Null, bsontype.Undefined:
+		*m = 0
+	default:
+		return fmt.Errorf("cannot decode %s into Money", t)
+	}
+	return nil
+}
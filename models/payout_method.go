@@ -0,0 +1,60 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// PayoutMethod is a bank account or UPI ID a user has saved to receive
+// withdrawals. A method must be verified by an admin before withdrawals can
+// be paid to it. Its destination can't be edited, only its label.
+type PayoutMethod struct {
+	ID     string `bson:"_id" json:"id"`
+	UserID string `bson:"user_id" json:"userId"`
+	Type   string `bson:"type" json:"type"` // bank, upi
+	Label  string `bson:"label,omitempty" json:"label,omitempty"`
+
+	// Bank account
+	AccountHolderName string `bson:"account_holder_name,omitempty" json:"accountHolderName,omitempty"`
+	AccountNumber     string `bson:"account_number,omitempty" json:"accountNumber,omitempty"`
+	IFSCCode          string `bson:"ifsc_code,omitempty" json:"ifscCode,omitempty"`
+
+	// UPI
+	UPIID string `bson:"upi_id,omitempty" json:"upiId,omitempty"`
+
+	// Fingerprint identifies the destination so a user can't save it twice
+	Fingerprint string `bson:"fingerprint" json:"-"`
+
+	Status          string     `bson:"status" json:"status"` // pending, verified, rejected
+	RejectionReason string     `bson:"rejection_reason,omitempty" json:"rejectionReason,omitempty"`
+	VerifiedBy      string     `bson:"verified_by,omitempty" json:"verifiedBy,omitempty"`
+	VerifiedAt      *time.Time `bson:"verified_at,omitempty" json:"verifiedAt,omitempty"`
+	CreatedAt       time.Time  `bson:"created_at" json:"createdAt"`
+	UpdatedAt       time.Time  `bson:"updated_at" json:"updatedAt"`
+}
+
+// Masked returns a copy with the account number masked down to its last
+// four digits, for responses to the user
+func (m PayoutMethod) Masked() PayoutMethod {
+	m.AccountNumber = MaskAccountNumber(m.AccountNumber)
+	return m
+}
+
+// PayoutDetails returns the destination of the method as stored on a
+// withdrawal request
+func (m PayoutMethod) PayoutDetails() *PayoutDetails {
+	return &PayoutDetails{
+		AccountHolderName: m.AccountHolderName,
+		AccountNumber:     m.AccountNumber,
+		IFSCCode:          m.IFSCCode,
+		UPIID:             m.UPIID,
+	}
+}
+
+// MaskAccountNumber replaces all but the last four characters with X
+func MaskAccountNumber(number string) string {
+	if len(number) <= 4 {
+		return number
+	}
+	return strings.Repeat("X", len(number)-4) + number[len(number)-4:]
+}
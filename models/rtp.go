@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+)
+
+// RTPWindow is the house-side result of one game over a trailing window
+type RTPWindow struct {
+	GameType    string    `json:"gameType"`
+	Window      string    `json:"window"` // 1h, 24h, 7d
+	Rounds      int64     `json:"rounds"`
+	Wagered     Money     `json:"wagered"`
+	Paid        Money     `json:"paid"`
+	GGR         Money     `json:"ggr"`         // Wagered - Paid
+	RTP         float64   `json:"rtp"`         // Paid / Wagered
+	ExpectedRTP float64   `json:"expectedRtp"` // 1 - house_edge / 100 of the current settings
+	Band        float64   `json:"band"`        // RTP is expected within ExpectedRTP ± Band
+	ZScore      float64   `json:"zScore"`      // (RTP - ExpectedRTP) in standard errors
+	HighOnly    bool      `json:"highOnly"`    // ExpectedRTP is the best play's; only RTP above the band alerts
+	Alerting    bool      `json:"alerting"`    // RTP is outside the band
+	BiggestWins []BigWin  `json:"biggestWins"`
+	Since       time.Time `json:"since"`
+}
+
+// BigWin is one of the largest payouts of a window
+type BigWin struct {
+	GameID     string    `bson:"_id" json:"gameId"`
+	UserID     string    `bson:"user_id" json:"userId"`
+	BetAmount  Money     `bson:"bet_amount" json:"betAmount"`
+	WinAmount  Money     `bson:"win_amount" json:"winAmount"`
+	Multiplier float64   `bson:"multiplier" json:"multiplier"`
+	CreatedAt  time.Time `bson:"created_at" json:"createdAt"`
+}
+
+// RTPReport is the latest result of every game over every window
+type RTPReport struct {
+	Windows     []RTPWindow `json:"windows"`
+	GeneratedAt time.Time   `json:"generatedAt"`
+}
+
+// RTPAlert is raised while a game's realised RTP over a window is outside
+// the band around its configured house edge
+type RTPAlert struct {
+	ID          string  `bson:"_id" json:"id"`
+	GameType    string  `bson:"game_type" json:"gameType"`
+	Window      string  `bson:"window" json:"window"`
+	Direction   string  `bson:"direction" json:"direction"` // high (paying more than configured), low
+	Status      string  `bson:"status" json:"status"`       // open, resolved
+	RTP         float64 `bson:"rtp" json:"rtp"`             // Latest realised RTP
+	PeakRTP     float64 `bson:"peak_rtp" json:"peakRtp"`    // Furthest from ExpectedRTP while open
+	ExpectedRTP float64 `bson:"expected_rtp" json:"expectedRtp"`
+	Band        float64 `bson:"band" json:"band"`
+	ZScore      float64 `bson:"z_score" json:"zScore"`
+	Rounds      int64   `bson:"rounds" json:"rounds"`
+	Wagered     Money   `bson:"wagered" json:"wagered"`
+	GGR         Money   `bson:"ggr" json:"ggr"`
+
+	RaisedAt       time.Time  `bson:"raised_at" json:"raisedAt"`
+	UpdatedAt      time.Time  `bson:"updated_at" json:"updatedAt"`
+	ResolvedAt     *time.Time `bson:"resolved_at,omitempty" json:"resolvedAt,omitempty"`
+	AcknowledgedBy string     `bson:"acknowledged_by,omitempty" json:"acknowledgedBy,omitempty"` // Admin UID
+	AcknowledgedAt *time.Time `bson:"acknowledged_at,omitempty" json:"acknowledgedAt,omitempty"`
+}
@@ -0,0 +1,36 @@
+package models
+
+// SimulationReport summarises many simulated rounds of one game config.
+// Payouts are multipliers of the base stake, so the report doesn't depend
+// on the bet amount.
+type SimulationReport struct {
+	GameType        string                 `json:"game_type"`
+	SettingsVersion int64                  `json:"settings_version,omitempty"` // Unset for a proposed config
+	HouseEdge       float64                `json:"house_edge"`
+	Choices         map[string]interface{} `json:"choices,omitempty"`
+	Rounds          int64                  `json:"rounds"`
+	Seed            uint64                 `json:"seed"`
+
+	RTP           float64            `json:"rtp"`            // Average payout per unit staked
+	RTPMargin     float64            `json:"rtp_margin"`     // Half-width of the 95% confidence interval of RTP
+	AvgStake      float64            `json:"avg_stake"`      // Average stake per round in base stakes; above 1 after doubles and splits
+	TargetRTP     float64            `json:"target_rtp"`     // 1 - house_edge / 100
+	HitFrequency  float64            `json:"hit_frequency"`  // Share of rounds paying anything
+	WinFrequency  float64            `json:"win_frequency"`  // Share of rounds paying more than the stake
+	Variance      float64            `json:"variance"`       // Of the payout multiplier
+	StdDev        float64            `json:"std_dev"`        // Of the payout multiplier
+	MaxMultiplier float64            `json:"max_multiplier"` // Highest payout seen
+	Histogram     []SimulationBucket `json:"histogram"`
+	DurationMs    int64              `json:"duration_ms"`
+}
+
+// SimulationBucket counts the rounds whose payout multiplier is in
+// [From, To). The first bucket holds losses (exactly 0) and the last one
+// has no upper bound.
+type SimulationBucket struct {
+	From   float64  `json:"from"`
+	To     *float64 `json:"to,omitempty"`
+	Rounds int64    `json:"rounds"`
+	Share  float64  `json:"share"`  // Of all rounds
+	Return float64  `json:"return"` // Contribution to RTP
+}
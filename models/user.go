@@ -12,8 +12,8 @@ type User struct {
 	Role             string    `bson:"role" json:"role"` // user, admin
 	ProfilePic       string    `bson:"profile_pic,omitempty" json:"profilePic,omitempty"`
 	TotalGamesPlayed int64     `bson:"total_games_played" json:"totalGamesPlayed"`
-	TotalWagered     float64   `bson:"total_wagered" json:"totalWagered"`
-	TotalWon         float64   `bson:"total_won" json:"totalWon"`
+	TotalWagered     Money     `bson:"total_wagered" json:"totalWagered"`
+	TotalWon         Money     `bson:"total_won" json:"totalWon"`
 	CreatedAt        time.Time `bson:"created_at" json:"createdAt"`
 	LastSeenAt       time.Time `bson:"last_seen_at" json:"lastSeenAt"`
 }
@@ -6,9 +6,11 @@ import (
 
 type Wallet struct {
 	UserID        string    `bson:"user_id" json:"userId"`
-	Balance       float64   `bson:"balance" json:"balance"`
+	Balance       Money     `bson:"balance" json:"balance"`
 	Currency      string    `bson:"currency" json:"currency"`
-	LockedBalance float64   `bson:"locked_balance" json:"lockedBalance"`
+	LockedBalance Money     `bson:"locked_balance" json:"lockedBalance"`
+	Version       int64     `bson:"version" json:"version"` // Incremented on every balance change
+	AppliedRefs   []string  `bson:"applied_refs,omitempty" json:"-"` // Latest at-most-once changes, as category:reference
 	LastUpdated   time.Time `bson:"last_updated" json:"lastUpdated"`
 	CreatedAt     time.Time `bson:"created_at" json:"createdAt"`
 }
@@ -17,11 +19,12 @@ type Transaction struct {
 	ID            string    `bson:"_id" json:"id"`
 	UserID        string    `bson:"user_id" json:"userId"`
 	Type          string    `bson:"type" json:"type"` // credit, debit
-	Amount        float64   `bson:"amount" json:"amount"`
+	Amount        Money     `bson:"amount" json:"amount"`
 	Description   string    `bson:"description" json:"description"`
 	Category      string    `bson:"category" json:"category"` // deposit, withdrawal, game_win, game_loss, admin_adjustment
-	BalanceBefore float64   `bson:"balance_before" json:"balanceBefore"`
-	BalanceAfter  float64   `bson:"balance_after" json:"balanceAfter"`
+	BalanceBefore Money     `bson:"balance_before" json:"balanceBefore"`
+	BalanceAfter  Money     `bson:"balance_after" json:"balanceAfter"`
+	WalletVersion int64     `bson:"wallet_version,omitempty" json:"walletVersion,omitempty"` // Wallet version after this change
 	Status        string    `bson:"status" json:"status"` // pending, completed, failed
 	CreatedAt     time.Time `bson:"created_at" json:"createdAt"`
 }
@@ -29,17 +32,40 @@ type Transaction struct {
 type PaymentRequest struct {
 	ID            string    `bson:"_id" json:"id"`
 	UserID        string    `bson:"user_id" json:"userId"`
-	Amount        float64   `bson:"amount" json:"amount"`
+	Amount        Money     `bson:"amount" json:"amount"`
+	Type          string    `bson:"type" json:"type"`                     // deposit, withdrawal
 	PaymentMethod string    `bson:"payment_method" json:"paymentMethod"` // bank, upi
+	PayoutMethodID string   `bson:"payout_method_id,omitempty" json:"payoutMethodId,omitempty"` // Verified payout method of a withdrawal
+	PayoutDetails *PayoutDetails `bson:"payout_details,omitempty" json:"payoutDetails,omitempty"` // Copy of the payout method's destination
 	TransactionID string    `bson:"transaction_id,omitempty" json:"transactionId,omitempty"`
 	ProofURL      string    `bson:"proof_url,omitempty" json:"proofUrl,omitempty"`
-	Status        string    `bson:"status" json:"status"` // pending, accepted, declined
+	Status        string    `bson:"status" json:"status"` // pending, accepted, declined, cancelled (withdrawals only)
 	Notes         string    `bson:"notes,omitempty" json:"notes,omitempty"`
 	AdminNotes    string    `bson:"admin_notes,omitempty" json:"adminNotes,omitempty"`
+	IdempotencyKey string   `bson:"idempotency_key,omitempty" json:"-"` // Idempotency-Key of the creating request
 	CreatedAt     time.Time `bson:"created_at" json:"createdAt"`
 	UpdatedAt     time.Time `bson:"updated_at" json:"updatedAt"`
 }
 
+// PayoutDetails is the user's bank account or UPI ID a withdrawal is paid to
+type PayoutDetails struct {
+	AccountHolderName string `bson:"account_holder_name,omitempty" json:"accountHolderName,omitempty"`
+	AccountNumber     string `bson:"account_number,omitempty" json:"accountNumber,omitempty"`
+	IFSCCode          string `bson:"ifsc_code,omitempty" json:"ifscCode,omitempty"`
+	UPIID             string `bson:"upi_id,omitempty" json:"upiId,omitempty"`
+}
+
+// Masked returns a copy with the account number masked, for responses to
+// the user
+func (d *PayoutDetails) Masked() *PayoutDetails {
+	if d == nil {
+		return nil
+	}
+	masked := *d
+	masked.AccountNumber = MaskAccountNumber(d.AccountNumber)
+	return &masked
+}
+
 type PaymentDetails struct {
 	BankName          string `bson:"bank_name" json:"bankName"`
 	AccountNumber     string `bson:"account_number" json:"accountNumber"`
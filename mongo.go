@@ -33,6 +33,11 @@ func ConnectMongo(ctx context.Context, uri string, dbName string) (*mongo.Client
         log.Println("[MongoDB] ✅ Indexes created successfully")
     }
     
+    // Convert legacy float amounts to integer paise
+    if err := migrateLegacyAmounts(ctx, db); err != nil {
+        log.Printf("[MongoDB] ⚠️  Failed to migrate legacy amounts: %v\n", err)
+    }
+    
     return client, db, nil
 }
 
@@ -67,27 +72,221 @@ func createIndexes(ctx context.Context, db *mongo.Database) error {
 		return fmt.Errorf("transactions indexes: %w", err)
 	}
 	
+	// Ledger entries, read per account for wallet history
+	ledgerCol := db.Collection("ledger_entries")
+	_, err = ledgerCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "postings.account", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "reference", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("ledger_entries indexes: %w", err)
+	}
+	
 	// Games collection indexes
 	gamesCol := db.Collection("games")
 	_, err = gamesCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
 		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
 		{Keys: bson.D{{Key: "game_type", Value: 1}}},
 		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "idempotency_key", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"idempotency_key": bson.M{"$type": "string"}}),
+		},
+		// Unsettled games waiting to be paid out
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"settled": false}),
+		},
 	})
 	if err != nil {
 		return fmt.Errorf("games indexes: %w", err)
 	}
 	
+	// Game sessions: one active session per user, swept by expiry time
+	gameSessionsCol := db.Collection("game_sessions")
+	_, err = gameSessionsCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": "active"}),
+		},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "expires_at", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "updated_at", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("game_sessions indexes: %w", err)
+	}
+	
+	// Aviation rounds and their bets: one bet per user per round
+	aviationRoundsCol := db.Collection("aviation_rounds")
+	_, err = aviationRoundsCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "number", Value: -1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("aviation_rounds indexes: %w", err)
+	}
+	aviationBetsCol := db.Collection("aviation_bets")
+	_, err = aviationBetsCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "round_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("aviation_bets indexes: %w", err)
+	}
+	
+	// Auto-bets: one running job per user
+	autoBetsCol := db.Collection("auto_bets")
+	_, err = autoBetsCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": "running"}),
+		},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "updated_at", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("auto_bets indexes: %w", err)
+	}
+	
+	// RTP alerts: one open alert per game and window
+	rtpAlertsCol := db.Collection("rtp_alerts")
+	_, err = rtpAlertsCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "game_type", Value: 1}, {Key: "window", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": "open"}),
+		},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "raised_at", Value: -1}}},
+		{Keys: bson.D{{Key: "raised_at", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("rtp_alerts indexes: %w", err)
+	}
+	
 	// Payment requests collection indexes
 	paymentRequestsCol := db.Collection("payment_requests")
 	_, err = paymentRequestsCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
 		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
 		{Keys: bson.D{{Key: "status", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "idempotency_key", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"idempotency_key": bson.M{"$type": "string"}}),
+		},
 	})
 	if err != nil {
 		return fmt.Errorf("payment_requests indexes: %w", err)
 	}
 	
+	// Payout methods: a destination can be saved once per user
+	payoutMethodsCol := db.Collection("payout_methods")
+	_, err = payoutMethodsCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "fingerprint", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("payout_methods indexes: %w", err)
+	}
+	
+	// Limit usage: one document per user and period, expired a while after
+	// the period ends
+	limitUsageCol := db.Collection("limit_usage")
+	_, err = limitUsageCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "start", Value: -1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("limit_usage indexes: %w", err)
+	}
+	
+	// Idempotency keys: one per user and key, expired after a day
+	idempotencyCol := db.Collection("idempotency_keys")
+	_, err = idempotencyCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("idempotency_keys indexes: %w", err)
+	}
+	
+	// Provably-fair seed pairs: one active pair per user
+	fairSeedsCol := db.Collection("fair_seeds")
+	_, err = fairSeedsCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"active": true}),
+		},
+		{Keys: bson.D{{Key: "server_seed_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "revealed_at", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("fair_seeds indexes: %w", err)
+	}
+	
+	// Game settings history
+	settingsVersionsCol := db.Collection("game_settings_versions")
+	_, err = settingsVersionsCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "game_type", Value: 1}, {Key: "version", Value: -1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		return fmt.Errorf("game_settings_versions indexes: %w", err)
+	}
+	
+	// One settings document per game; guards concurrent version bumps
+	gameSettingsCol := db.Collection("game_settings")
+	_, err = gameSettingsCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "game_type", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		return fmt.Errorf("game_settings indexes: %w", err)
+	}
+	
+	// Scheduled settings changes, polled by status and due time, and by
+	// claim time for the ones left applying or reverting
+	schedulesCol := db.Collection("game_settings_schedules")
+	_, err = schedulesCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "start_at", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "end_at", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "claimed_at", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("game_settings_schedules indexes: %w", err)
+	}
+	
+	return nil
+}
+
+// legacyAmountFields lists top-level amount fields that used to be stored as
+// float rupees and are now int64 paise (see models.Money)
+var legacyAmountFields = map[string][]string{
+	"wallets":          {"balance", "locked_balance"},
+	"transactions":     {"amount", "balance_before", "balance_after"},
+	"games":            {"bet_amount", "win_amount"},
+	"payment_requests": {"amount"},
+	"users":            {"total_wagered", "total_won"},
+	"game_settings":    {"min_bet", "max_bet"},
+}
+
+// migrateLegacyAmounts rewrites float rupee amounts as int64 paise so that
+// aggregations and $inc never mix both representations. Only BSON doubles
+// are touched, so running it again is a no-op.
+func migrateLegacyAmounts(ctx context.Context, db *mongo.Database) error {
+	for collection, fields := range legacyAmountFields {
+		col := db.Collection(collection)
+		for _, field := range fields {
+			filter := bson.M{field: bson.M{"$type": "double"}}
+			update := mongo.Pipeline{
+				{{Key: "$set", Value: bson.M{
+					field: bson.M{"$toLong": bson.M{"$round": bson.A{bson.M{"$multiply": bson.A{"$" + field, 100}}, 0}}},
+				}}},
+			}
+			res, err := col.UpdateMany(ctx, filter, update)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", collection, field, err)
+			}
+			if res.ModifiedCount > 0 {
+				log.Printf("[MongoDB] ✅ Migrated %d %s.%s amounts to paise\n", res.ModifiedCount, collection, field)
+			}
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"betting-app-backend-go/models"
+)
+
+// AlertNotifier tells someone about an RTP alert being raised or resolved
+type AlertNotifier interface {
+	Notify(ctx context.Context, alert *models.RTPAlert) error
+}
+
+// alertSummary is a one-line description of an alert
+func alertSummary(alert *models.RTPAlert) string {
+	if alert.Status == "resolved" {
+		return fmt.Sprintf("RTP of %s over %s is back within %.2f%% ± %.2f%% (now %.2f%%)",
+			alert.GameType, alert.Window, alert.ExpectedRTP*100, alert.Band*100, alert.RTP*100)
+	}
+	return fmt.Sprintf("RTP of %s over %s is %s: %.2f%% against %.2f%% ± %.2f%% over %d rounds (GGR %s)",
+		alert.GameType, alert.Window, alert.Direction, alert.RTP*100, alert.ExpectedRTP*100, alert.Band*100, alert.Rounds, alert.GGR)
+}
+
+// LogNotifier writes alerts to the server log
+type LogNotifier struct{}
+
+func (n *LogNotifier) Notify(ctx context.Context, alert *models.RTPAlert) error {
+	log.Printf("[RTP] ⚠️ %s\n", alertSummary(alert))
+	return nil
+}
+
+// WebhookNotifier posts alerts as JSON to a URL, e.g. a chat incoming
+// webhook. The body carries a "text" summary and the full "alert".
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, alert *models.RTPAlert) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"text":  alertSummary(alert),
+		"alert": alert,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
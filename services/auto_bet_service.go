@@ -0,0 +1,412 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"betting-app-backend-go/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Errors returned by AutoBetService
+var (
+	ErrAutoBetActive   = errors.New("an auto-bet is already running")
+	ErrAutoBetNotFound = errors.New("auto-bet not found")
+	ErrAutoBetFinished = errors.New("auto-bet has already finished")
+)
+
+const (
+	// maxAutoBetRounds bounds one auto-bet
+	maxAutoBetRounds = 1000
+	// maxAutoBetIncrease bounds the per-round stake increase, in percent
+	maxAutoBetIncrease = 1000
+	// autoBetStaleAfter is how long a running auto-bet may go without
+	// progress before it is assumed to have lost its runner
+	autoBetStaleAfter = time.Minute
+)
+
+// AutoBetEvent reports auto-bet progress to a streaming client
+type AutoBetEvent struct {
+	Type string          `json:"type"` // state, round, finished
+	Job  *models.AutoBet `json:"job"`
+	Game *models.Game    `json:"game,omitempty"`
+}
+
+// AutoBetService plays series of rounds for players. Each round goes
+// through GameService.RecordGame with an idempotency key derived from the
+// job and round number, so it has exactly the wallet guarantees of a
+// manual bet and is never played twice.
+type AutoBetService struct {
+	collection *mongo.Collection
+	games      *GameService
+	delay      time.Duration // between rounds
+	interval   time.Duration // between sweeps for stale jobs
+
+	mu          sync.Mutex
+	cancels     map[string]context.CancelFunc
+	subscribers map[string]map[chan AutoBetEvent]struct{}
+}
+
+func NewAutoBetService(db *mongo.Database, games *GameService) *AutoBetService {
+	return &AutoBetService{
+		collection:  db.Collection("auto_bets"),
+		games:       games,
+		delay:       250 * time.Millisecond,
+		interval:    30 * time.Second,
+		cancels:     make(map[string]context.CancelFunc),
+		subscribers: make(map[string]map[chan AutoBetEvent]struct{}),
+	}
+}
+
+// Start validates and launches an auto-bet. A user can run one at a time.
+func (s *AutoBetService) Start(ctx context.Context, job *models.AutoBet) error {
+	if _, ok := s.games.engines[job.GameType]; !ok {
+		return fmt.Errorf("%w: %s", ErrNoEngine, job.GameType)
+	}
+	if job.BaseBet <= 0 {
+		return fmt.Errorf("bet amount must be positive")
+	}
+	if job.Rounds < 1 || job.Rounds > maxAutoBetRounds {
+		return fmt.Errorf("%w: rounds must be between 1 and %d", ErrInvalidChoice, maxAutoBetRounds)
+	}
+	for name, adjust := range map[string]*models.AutoBetAdjust{"onWin": &job.OnWin, "onLoss": &job.OnLoss} {
+		switch adjust.Action {
+		case "", "reset":
+			*adjust = models.AutoBetAdjust{Action: "reset"}
+		case "increase":
+			if adjust.Percent <= 0 || adjust.Percent > maxAutoBetIncrease {
+				return fmt.Errorf("%w: %s.percent must be above 0 and at most %d", ErrInvalidChoice, name, maxAutoBetIncrease)
+			}
+		default:
+			return fmt.Errorf("%w: %s.action must be reset or increase", ErrInvalidChoice, name)
+		}
+	}
+	if job.StopOnProfit < 0 || job.StopOnLoss < 0 {
+		return fmt.Errorf("%w: stop limits must not be negative", ErrInvalidChoice)
+	}
+
+	// Fail fast on the first stake; later stakes are checked per round
+	settings, err := s.games.settingsService.GetCachedGameSettings(ctx, job.GameType)
+	if err != nil {
+		return fmt.Errorf("failed to load game settings: %w", err)
+	}
+	if err := checkBetAgainstSettings(job.BaseBet, settings); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	job.ID = fmt.Sprintf("autobet_%d", now.UnixNano())
+	job.Status = "running"
+	job.StopReason = ""
+	job.Error = ""
+	job.CancelRequested = false
+	job.Played, job.Wins = 0, 0
+	job.Wagered, job.Won, job.Profit = 0, 0, 0
+	job.CurrentBet = job.BaseBet
+	job.LastGameID = ""
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	job.FinishedAt = nil
+
+	// The unique index on running jobs makes the insert the reservation
+	if _, err := s.collection.InsertOne(ctx, job); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrAutoBetActive
+		}
+		return fmt.Errorf("failed to create auto-bet: %w", err)
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.cancels[job.ID] = cancel
+	s.mu.Unlock()
+
+	running := *job
+	go s.run(runCtx, &running)
+	return nil
+}
+
+// GetActive returns the user's running auto-bet, or nil
+func (s *AutoBetService) GetActive(ctx context.Context, userID string) (*models.AutoBet, error) {
+	var job models.AutoBet
+	err := s.collection.FindOne(ctx, bson.M{"user_id": userID, "status": "running"}).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auto-bet: %w", err)
+	}
+	return &job, nil
+}
+
+// Get returns one of a user's auto-bets
+func (s *AutoBetService) Get(ctx context.Context, userID, id string) (*models.AutoBet, error) {
+	var job models.AutoBet
+	err := s.collection.FindOne(ctx, bson.M{"_id": id, "user_id": userID}).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrAutoBetNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auto-bet: %w", err)
+	}
+	return &job, nil
+}
+
+// Cancel asks a running auto-bet to stop. The round in progress, if any,
+// completes; the job then finishes as "cancelled".
+func (s *AutoBetService) Cancel(ctx context.Context, userID, id string) (*models.AutoBet, error) {
+	var job models.AutoBet
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := s.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id, "user_id": userID, "status": "running"},
+		bson.M{"$set": bson.M{"cancel_requested": true}},
+		opts,
+	).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		if _, getErr := s.Get(ctx, userID, id); getErr != nil {
+			return nil, getErr
+		}
+		return nil, ErrAutoBetFinished
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel auto-bet: %w", err)
+	}
+
+	// Wake the runner if it is on this instance; others see the flag on
+	// their next round
+	s.mu.Lock()
+	if cancel, ok := s.cancels[id]; ok {
+		cancel()
+	}
+	s.mu.Unlock()
+	return &job, nil
+}
+
+// Subscribe registers for a job's events. Events are dropped for a
+// subscriber that falls behind; call the returned func to unsubscribe.
+func (s *AutoBetService) Subscribe(id string) (<-chan AutoBetEvent, func()) {
+	ch := make(chan AutoBetEvent, 64)
+	s.mu.Lock()
+	if s.subscribers[id] == nil {
+		s.subscribers[id] = make(map[chan AutoBetEvent]struct{})
+	}
+	s.subscribers[id][ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		if _, ok := s.subscribers[id][ch]; ok {
+			delete(s.subscribers[id], ch)
+			if len(s.subscribers[id]) == 0 {
+				delete(s.subscribers, id)
+			}
+			close(ch)
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *AutoBetService) broadcast(event AutoBetEvent) {
+	// The runner keeps updating its job, so subscribers get a copy
+	job := *event.Job
+	event.Job = &job
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subscribers[event.Job.ID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// run plays the job's rounds until one of its stop conditions is met
+func (s *AutoBetService) run(ctx context.Context, job *models.AutoBet) {
+	defer func() {
+		s.mu.Lock()
+		if cancel, ok := s.cancels[job.ID]; ok {
+			cancel()
+			delete(s.cancels, job.ID)
+		}
+		s.mu.Unlock()
+	}()
+
+	for job.Played < job.Rounds {
+		if ctx.Err() != nil {
+			s.finish(job, "cancelled", "cancelled", "")
+			return
+		}
+
+		// Cancelling doesn't interrupt a round: the wallet and the game
+		// record must always end up consistent
+		game, err := s.games.RecordGame(context.Background(), &PlayRequest{
+			UserID:         job.UserID,
+			GameType:       job.GameType,
+			BetAmount:      job.CurrentBet,
+			Choices:        job.Choices,
+			IdempotencyKey: fmt.Sprintf("autobet:%s:%d", job.ID, job.Played),
+		})
+		if err != nil {
+			reason := "error"
+			switch {
+			case errors.Is(err, ErrInsufficientBalance):
+				reason = "insufficient_balance"
+			case errors.Is(err, ErrBetBelowMinimum), errors.Is(err, ErrBetAboveMaximum):
+				reason = "bet_limit"
+			case errors.Is(err, ErrLimitExceeded):
+				reason = "limit_reached"
+			case errors.Is(err, ErrGameDisabled):
+				reason = "game_disabled"
+			default:
+				log.Printf("[AutoBet] ❌ Round %d of %s failed: %v\n", job.Played+1, job.ID, err)
+			}
+			s.finish(job, "stopped", reason, err.Error())
+			return
+		}
+
+		job.Played++
+		job.Wagered += game.BetAmount
+		job.Won += game.WinAmount
+		job.Profit = job.Won - job.Wagered
+		job.LastGameID = game.ID
+		adjust := job.OnLoss
+		if game.WinAmount > game.BetAmount {
+			job.Wins++
+			adjust = job.OnWin
+		}
+		if adjust.Action == "increase" {
+			job.CurrentBet = job.CurrentBet.MulMultiplier(1 + adjust.Percent/100)
+		} else {
+			job.CurrentBet = job.BaseBet
+		}
+
+		latest, err := s.saveProgress(job)
+		if err != nil {
+			log.Printf("[AutoBet] ❌ Failed to save progress of %s: %v\n", job.ID, err)
+			s.finish(job, "stopped", "error", err.Error())
+			return
+		}
+		s.broadcast(AutoBetEvent{Type: "round", Job: job, Game: game})
+
+		switch {
+		case latest.Status != "running":
+			// Swept as stale while a round was slow; someone else finished it
+			return
+		case latest.CancelRequested:
+			s.finish(job, "cancelled", "cancelled", "")
+			return
+		case job.StopOnProfit > 0 && job.Profit >= job.StopOnProfit:
+			s.finish(job, "completed", "profit_target", "")
+			return
+		case job.StopOnLoss > 0 && -job.Profit >= job.StopOnLoss:
+			s.finish(job, "completed", "loss_limit", "")
+			return
+		}
+
+		if job.Played < job.Rounds {
+			select {
+			case <-ctx.Done():
+			case <-time.After(s.delay):
+			}
+		}
+	}
+	s.finish(job, "completed", "rounds_completed", "")
+}
+
+// saveProgress stores the job's counters and returns the stored job, which
+// carries any cancellation requested meanwhile
+func (s *AutoBetService) saveProgress(job *models.AutoBet) (*models.AutoBet, error) {
+	job.UpdatedAt = time.Now()
+	var latest models.AutoBet
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := s.collection.FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": job.ID},
+		bson.M{"$set": bson.M{
+			"played":       job.Played,
+			"wins":         job.Wins,
+			"wagered":      job.Wagered,
+			"won":          job.Won,
+			"profit":       job.Profit,
+			"current_bet":  job.CurrentBet,
+			"last_game_id": job.LastGameID,
+			"updated_at":   job.UpdatedAt,
+		}},
+		opts,
+	).Decode(&latest)
+	if err != nil {
+		return nil, err
+	}
+	return &latest, nil
+}
+
+// finish records how a running job ended and tells its subscribers
+func (s *AutoBetService) finish(job *models.AutoBet, status, reason, message string) {
+	now := time.Now()
+	job.Status = status
+	job.StopReason = reason
+	job.Error = message
+	job.UpdatedAt = now
+	job.FinishedAt = &now
+
+	_, err := s.collection.UpdateOne(context.Background(), bson.M{"_id": job.ID, "status": "running"}, bson.M{"$set": bson.M{
+		"status":      job.Status,
+		"stop_reason": job.StopReason,
+		"error":       job.Error,
+		"updated_at":  now,
+		"finished_at": now,
+	}})
+	if err != nil {
+		log.Printf("[AutoBet] ❌ Failed to finish %s: %v\n", job.ID, err)
+	}
+
+	s.broadcast(AutoBetEvent{Type: "finished", Job: job})
+	log.Printf("[AutoBet] ✅ %s %s after %d rounds (%s), profit %s\n", job.ID, status, job.Played, reason, job.Profit)
+}
+
+// Run stops stale auto-bets until ctx is cancelled
+func (s *AutoBetService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.stopStale(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// stopStale stops running jobs that made no progress for a while, e.g.
+// because the server running them restarted. Their rounds so far stand.
+func (s *AutoBetService) stopStale(ctx context.Context) {
+	now := time.Now()
+	res, err := s.collection.UpdateMany(
+		ctx,
+		bson.M{"status": "running", "updated_at": bson.M{"$lt": now.Add(-autoBetStaleAfter)}},
+		bson.M{"$set": bson.M{
+			"status":      "stopped",
+			"stop_reason": "interrupted",
+			"updated_at":  now,
+			"finished_at": now,
+		}},
+	)
+	if err != nil {
+		log.Printf("[AutoBet] ❌ Failed to stop stale auto-bets: %v\n", err)
+		return
+	}
+	if res.ModifiedCount > 0 {
+		log.Printf("[AutoBet] Stopped %d interrupted auto-bets\n", res.ModifiedCount)
+	}
+}
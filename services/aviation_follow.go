@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"betting-app-backend-go/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// aviationPollInterval is how often the stored round and its bets are read
+// while no change stream is open
+const aviationPollInterval = 500 * time.Millisecond
+
+// aviationStreamRetry is how long to poll before trying to open a change
+// stream again
+const aviationStreamRetry = time.Minute
+
+// aviationStatusOrder orders round statuses, so a round seen out of date is
+// never taken back
+var aviationStatusOrder = map[string]int{
+	"betting": 0, "flying": 1, "crashed": 2, "cancelling": 2, "settled": 3, "cancelled": 3,
+}
+
+// follow keeps this instance's copy of the stored round and its bets up to
+// date until ctx is cancelled, and passes what changed on to subscribers.
+// Changes come from a change stream where the deployment has them (replica
+// sets); a standalone server is polled instead. Off the lease holder it
+// also sends the ticks of a round in flight.
+func (s *AviationService) follow(ctx context.Context) {
+	ticker := time.NewTicker(s.tickInterval)
+	defer ticker.Stop()
+
+	var stream *mongo.ChangeStream
+	defer func() {
+		if stream != nil {
+			stream.Close(context.Background())
+		}
+	}()
+	var polled, tried time.Time
+	warned := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if stream == nil && time.Since(tried) >= aviationStreamRetry {
+			tried = time.Now()
+			var err error
+			stream, err = s.watch(ctx)
+			if err != nil && !warned {
+				log.Printf("[Aviation] Following rounds by polling, no change stream: %v\n", err)
+				warned = true
+			}
+			// Catch up on what happened before the stream opened
+			polled = time.Time{}
+		}
+		if stream != nil {
+			for stream.TryNext(ctx) {
+				s.observeChange(stream.Current)
+			}
+			if err := stream.Err(); err != nil && ctx.Err() == nil {
+				log.Printf("[Aviation] ❌ Change stream failed, polling: %v\n", err)
+				stream.Close(ctx)
+				stream = nil
+			}
+		}
+		if polled.IsZero() || (stream == nil && time.Since(polled) >= aviationPollInterval) {
+			s.poll(ctx)
+			polled = time.Now()
+		}
+		s.tick()
+	}
+}
+
+// watch opens a change stream on the stored rounds and bets
+func (s *AviationService) watch(ctx context.Context) (*mongo.ChangeStream, error) {
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{
+		"ns.coll":       bson.M{"$in": bson.A{s.rounds.Name(), s.bets.Name()}},
+		"operationType": bson.M{"$in": bson.A{"insert", "update", "replace"}},
+	}}}}
+	opts := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetMaxAwaitTime(10 * time.Millisecond)
+	return s.rounds.Database().Watch(ctx, pipeline, opts)
+}
+
+// observeChange applies one change stream event
+func (s *AviationService) observeChange(raw bson.Raw) {
+	var change struct {
+		NS struct {
+			Coll string `bson:"coll"`
+		} `bson:"ns"`
+		FullDocument bson.Raw `bson:"fullDocument"`
+	}
+	if err := bson.Unmarshal(raw, &change); err != nil || change.FullDocument == nil {
+		return
+	}
+
+	switch change.NS.Coll {
+	case s.rounds.Name():
+		var round models.AviationRound
+		if err := bson.Unmarshal(change.FullDocument, &round); err == nil {
+			s.observeRound(&round)
+		}
+	case s.bets.Name():
+		var bet models.AviationBet
+		if err := bson.Unmarshal(change.FullDocument, &bet); err == nil {
+			s.observeBet(&bet)
+		}
+	}
+}
+
+// poll reads the latest stored round and its open bets
+func (s *AviationService) poll(ctx context.Context) {
+	var round models.AviationRound
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: "number", Value: -1}}).
+		SetProjection(bson.M{"bets": 0, "cashouts": 0})
+	err := s.rounds.FindOne(ctx, bson.M{}, opts).Decode(&round)
+	if err == mongo.ErrNoDocuments {
+		return
+	}
+	if err != nil {
+		log.Printf("[Aviation] ❌ Failed to get the current round: %v\n", err)
+		return
+	}
+	s.observeRound(&round)
+
+	cursor, err := s.bets.Find(ctx, bson.M{"round_id": round.ID, "status": bson.M{"$in": bson.A{"active", "cashed_out"}}})
+	if err != nil {
+		log.Printf("[Aviation] ❌ Failed to get bets of round %d: %v\n", round.Number, err)
+		return
+	}
+	var bets []models.AviationBet
+	if err := cursor.All(ctx, &bets); err != nil {
+		log.Printf("[Aviation] ❌ Failed to decode bets of round %d: %v\n", round.Number, err)
+		return
+	}
+	for i := range bets {
+		s.observeBet(&bets[i])
+	}
+}
+
+// observeRound takes a stored round as the current one and announces its
+// progress. The lease holder plays its own round and ignores it.
+func (s *AviationService) observeRound(round *models.AviationRound) {
+	s.phase.Lock()
+	prev := s.round
+	if s.leading || (prev != nil && (round.Number < prev.Number ||
+		(round.ID == prev.ID && aviationStatusOrder[round.Status] <= aviationStatusOrder[prev.Status]))) {
+		s.phase.Unlock()
+		return
+	}
+	current := *round
+	current.Bets, current.Cashouts = nil, nil
+	s.round = &current
+	s.phase.Unlock()
+
+	if prev == nil || prev.ID != round.ID {
+		s.liveMu.Lock()
+		s.live = make(map[string]*models.AviationBet)
+		s.liveMu.Unlock()
+	}
+
+	event := ""
+	switch round.Status {
+	case "betting", "flying", "crashed":
+		event = round.Status
+	case "settled":
+		// A round seen only once settled still has to be announced crashed
+		if prev == nil || prev.ID != round.ID || prev.Status != "crashed" {
+			event = "crashed"
+		}
+	}
+	if event != "" {
+		public := current.Public()
+		s.broadcast(AviationEvent{Type: event, Round: &public})
+	}
+}
+
+// observeBet announces a stored bet of the current round the first time it
+// is seen placed or cashed out. On the lease holder this also brings bets
+// taken by other instances into its auto cash-outs.
+func (s *AviationService) observeBet(bet *models.AviationBet) {
+	event := ""
+	switch bet.Status {
+	case "active":
+		event = "bet"
+	case "cashed_out":
+		event = "cashout"
+	default:
+		return
+	}
+
+	s.phase.RLock()
+	current := s.round != nil && s.round.ID == bet.RoundID
+	s.phase.RUnlock()
+	if current && s.track(bet) {
+		s.broadcast(AviationEvent{Type: event, Bet: bet})
+	}
+}
+
+// tick announces the multiplier of a followed round in flight. The lease
+// holder sends its own ticks.
+func (s *AviationService) tick() {
+	s.phase.RLock()
+	flying := !s.leading && s.round != nil && s.round.Status == "flying" && s.round.StartedAt != nil
+	var multiplier, crashPoint float64
+	if flying {
+		multiplier = aviationMultiplierAt(time.Since(*s.round.StartedAt))
+		crashPoint = s.round.CrashPoint
+	}
+	s.phase.RUnlock()
+
+	// The crash is announced once it is stored
+	if flying && multiplier < crashPoint {
+		s.broadcast(AviationEvent{Type: "tick", Multiplier: multiplier})
+	}
+}
@@ -0,0 +1,986 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"betting-app-backend-go/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Errors returned by AviationService
+var (
+	ErrAviationBettingClosed = errors.New("betting is closed for this round")
+	ErrAviationNotFlying     = errors.New("the round is not in flight")
+	ErrAviationBetPlaced     = errors.New("you already have a bet on this round")
+	ErrAviationNoBet         = errors.New("no active bet on this round")
+)
+
+// aviationGrowthRate is how fast the multiplier climbs: e^(rate * seconds),
+// i.e. 2x after about 8.7s and 10x after about 29s
+const aviationGrowthRate = 0.08
+
+// aviationLeaseMargin is how long the lease outlasts the longest possible
+// round, to cover settling it
+const aviationLeaseMargin = time.Minute
+
+// aviationBuckets are the ranges of the edge-free curve 1 / (1 - u) that
+// CrashChances weighs
+var aviationBuckets = []struct{ lo, hi float64 }{
+	{1, 3},            // low
+	{3, 5},            // medium
+	{5, 10},           // high
+	{10, math.Inf(1)}, // very_high
+}
+
+// AviationEvent is pushed to every client watching the game
+type AviationEvent struct {
+	Type       string                `json:"type"` // state, betting, bet, flying, tick, cashout, crashed
+	Round      *models.AviationRound `json:"round,omitempty"`
+	Multiplier float64               `json:"multiplier,omitempty"`
+	Bet        *models.AviationBet   `json:"bet,omitempty"`
+	Bets       []models.AviationBet  `json:"bets,omitempty"`
+}
+
+// AviationService runs the shared aviation rounds: a betting window, a
+// flight during which players cash out, and a crash after which every bet
+// of the round is settled in one pass. Rounds are driven by Run on the
+// instance holding the aviation lease. Every instance takes bets and
+// cash-outs against the stored round and follows it to serve the live
+// state; see follow.
+type AviationService struct {
+	rounds *mongo.Collection
+	bets   *mongo.Collection
+	leases *mongo.Collection
+	games  *GameService
+
+	// instanceID identifies this instance as the lease holder
+	instanceID string
+
+	bettingWindow time.Duration
+	tickInterval  time.Duration
+	pause         time.Duration
+
+	// phase guards the current round in memory: the round being played on
+	// the lease holder, the stored round as last seen elsewhere. settings
+	// are the settings of the last round bet on.
+	phase    sync.RWMutex
+	leading  bool
+	round    *models.AviationRound
+	settings *models.GameSettings
+
+	liveMu sync.Mutex
+	live   map[string]*models.AviationBet // current round's bets by user
+
+	subMu       sync.Mutex
+	subscribers map[chan AviationEvent]struct{}
+}
+
+func NewAviationService(db *mongo.Database, games *GameService) *AviationService {
+	s := &AviationService{
+		rounds:        db.Collection("aviation_rounds"),
+		bets:          db.Collection("aviation_bets"),
+		leases:        db.Collection("aviation_lease"),
+		games:         games,
+		instanceID:    primitive.NewObjectID().Hex(),
+		bettingWindow: 10 * time.Second,
+		tickInterval:  100 * time.Millisecond,
+		pause:         3 * time.Second,
+		live:          make(map[string]*models.AviationBet),
+		subscribers:   make(map[chan AviationEvent]struct{}),
+	}
+	games.registerElsewhere("aviation", "/api/aviation/bet", s)
+	return s
+}
+
+// PlaceBet takes a bet on the round in its betting window. A non-zero
+// autoCashout cashes the bet out as soon as the multiplier reaches it.
+//
+// The bet is stored pending, accepted by the round with an update
+// conditional on the round still taking bets, and only then paid for. A
+// bet left pending by a failure is settled with its round if its stake
+// was taken and dropped otherwise.
+func (s *AviationService) PlaceBet(ctx context.Context, userID string, amount models.Money, autoCashout float64) (*models.AviationBet, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("bet amount must be positive")
+	}
+
+	round, err := s.openRound(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if round == nil || round.Status != "betting" || !time.Now().Before(round.BettingEndsAt) {
+		return nil, ErrAviationBettingClosed
+	}
+	settings, err := s.roundSettings(ctx, round)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBetAgainstSettings(amount, settings); err != nil {
+		return nil, err
+	}
+	var cfg models.AviationConfig
+	if err := decodeConfig(settings.Config, &cfg); err != nil {
+		return nil, err
+	}
+	if autoCashout != 0 && (autoCashout <= 1 || autoCashout > cfg.MaxMultiplier) {
+		return nil, fmt.Errorf("%w: autoCashout must be above 1 and at most %g", ErrInvalidChoice, cfg.MaxMultiplier)
+	}
+
+	now := time.Now()
+	bet := &models.AviationBet{
+		ID:          fmt.Sprintf("avbet_%d", now.UnixNano()),
+		RoundID:     round.ID,
+		UserID:      userID,
+		Amount:      amount,
+		AutoCashout: models.RoundMultiplier(autoCashout),
+		Status:      "pending",
+		CreatedAt:   now,
+	}
+	// The unique (round, user) index makes the insert the reservation
+	if _, err := s.bets.InsertOne(ctx, bet); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrAviationBetPlaced
+		}
+		return nil, fmt.Errorf("failed to place bet: %w", err)
+	}
+
+	res, err := s.rounds.UpdateOne(ctx, bson.M{"_id": round.ID, "status": "betting"}, bson.M{"$push": bson.M{"bets": bet.ID}})
+	if err != nil {
+		// No stake was taken, so the bet is dropped with its round
+		return nil, fmt.Errorf("failed to place bet: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		if _, err := s.dropBet(ctx, bet); err != nil {
+			log.Printf("[Aviation] ❌ %v\n", err)
+		}
+		return nil, ErrAviationBettingClosed
+	}
+
+	err = s.games.walletService.DeductBalance(ctx, userID, amount, "aviation game bet", "game_loss", bet.ID, bet.CreatedAt)
+	if err != nil {
+		// Drop the bet only when it was refused; after any other error the
+		// stake may have been taken
+		if errors.Is(err, ErrInsufficientBalance) || errors.Is(err, ErrLimitExceeded) {
+			if _, dropErr := s.dropBet(ctx, bet); dropErr != nil {
+				log.Printf("[Aviation] ❌ %v\n", dropErr)
+			}
+		}
+		return nil, fmt.Errorf("failed to deduct bet: %w", err)
+	}
+
+	bet.Status = "active"
+	res, err = s.bets.UpdateOne(ctx, bson.M{"_id": bet.ID, "status": "pending"}, bson.M{"$set": bson.M{"status": bet.Status}})
+	if err != nil {
+		// The round settles the bet as it is; it just can't be cashed out
+		log.Printf("[Aviation] ❌ Failed to activate bet %s: %v\n", bet.ID, err)
+	} else if res.MatchedCount == 0 {
+		if err := s.refundDropped(ctx, bet); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.track(bet) {
+		s.broadcast(AviationEvent{Type: "bet", Bet: bet})
+	}
+	return bet, nil
+}
+
+// refundDropped gives back the stake of a bet that was still pending when
+// its round was settled or cancelled. A bet found under another status was
+// settled with its stake and keeps it.
+func (s *AviationService) refundDropped(ctx context.Context, bet *models.AviationBet) error {
+	count, err := s.bets.CountDocuments(ctx, bson.M{"_id": bet.ID})
+	if err != nil {
+		return fmt.Errorf("failed to get bet: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	if err := s.games.walletService.CreditBalance(ctx, bet.UserID, bet.Amount, "aviation bet refund", "game_refund", bet.ID, bet.CreatedAt); err != nil {
+		return fmt.Errorf("failed to refund bet: %w", err)
+	}
+	return ErrAviationBettingClosed
+}
+
+// dropBet removes a pending bet whose stake was never taken. It reports
+// false if the bet is no longer pending, as it was paid for meanwhile.
+func (s *AviationService) dropBet(ctx context.Context, bet *models.AviationBet) (bool, error) {
+	res, err := s.bets.DeleteOne(ctx, bson.M{"_id": bet.ID, "status": "pending"})
+	if err != nil {
+		return false, fmt.Errorf("failed to remove unpaid bet %s: %w", bet.ID, err)
+	}
+	return res.DeletedCount > 0, nil
+}
+
+// CashOut cashes the user's bet out at the current multiplier. The win is
+// credited when the round settles.
+func (s *AviationService) CashOut(ctx context.Context, userID string) (*models.AviationBet, error) {
+	round, err := s.openRound(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if round == nil || round.Status != "flying" || round.StartedAt == nil {
+		return nil, ErrAviationNotFlying
+	}
+	multiplier := aviationMultiplierAt(time.Since(*round.StartedAt))
+	if multiplier >= round.CrashPoint {
+		return nil, ErrAviationNotFlying
+	}
+	return s.cashOut(ctx, round, userID, multiplier)
+}
+
+// cashOut locks in a multiplier for an active bet. The round accepts the
+// cash-out with an update conditional on it still flying, and settles the
+// bet by the cash-outs it accepted.
+func (s *AviationService) cashOut(ctx context.Context, round *models.AviationRound, userID string, multiplier float64) (*models.AviationBet, error) {
+	var bet models.AviationBet
+	err := s.bets.FindOne(ctx, bson.M{"round_id": round.ID, "user_id": userID, "status": "active"}).Decode(&bet)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrAviationNoBet
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bet: %w", err)
+	}
+
+	now := time.Now()
+	res, err := s.rounds.UpdateOne(ctx,
+		bson.M{"_id": round.ID, "status": "flying", "cashouts.bet_id": bson.M{"$ne": bet.ID}},
+		bson.M{"$push": bson.M{"cashouts": models.AviationCashout{BetID: bet.ID, Multiplier: multiplier, At: now}}},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cash out: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		// The round crashed, or an auto cash-out got in first
+		flying, err := s.rounds.CountDocuments(ctx, bson.M{"_id": round.ID, "status": "flying"})
+		if err == nil && flying == 0 {
+			return nil, ErrAviationNotFlying
+		}
+		return nil, ErrAviationNoBet
+	}
+
+	bet.Status = "cashed_out"
+	bet.CashoutMultiplier = multiplier
+	bet.WinAmount = bet.Amount.MulMultiplier(multiplier)
+	bet.CashedOutAt = &now
+	_, err = s.bets.UpdateOne(ctx, bson.M{"_id": bet.ID, "status": "active"}, bson.M{"$set": bson.M{
+		"status":             bet.Status,
+		"cashout_multiplier": bet.CashoutMultiplier,
+		"win_amount":         bet.WinAmount,
+		"cashed_out_at":      now,
+	}})
+	if err != nil {
+		// The round has the cash-out and settles the bet by it
+		log.Printf("[Aviation] ❌ Failed to store cash-out of bet %s: %v\n", bet.ID, err)
+	}
+
+	if s.track(&bet) {
+		s.broadcast(AviationEvent{Type: "cashout", Bet: &bet})
+	}
+	return &bet, nil
+}
+
+// openRound returns the stored round if it is taking bets or flying, or
+// nil. The round's lists of accepted bets and cash-outs are left out.
+func (s *AviationService) openRound(ctx context.Context) (*models.AviationRound, error) {
+	var round models.AviationRound
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: "number", Value: -1}}).
+		SetProjection(bson.M{"bets": 0, "cashouts": 0})
+	err := s.rounds.FindOne(ctx, bson.M{"status": bson.M{"$in": bson.A{"betting", "flying"}}}, opts).Decode(&round)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get round: %w", err)
+	}
+	return &round, nil
+}
+
+// roundSettings returns the settings a round was opened with
+func (s *AviationService) roundSettings(ctx context.Context, round *models.AviationRound) (*models.GameSettings, error) {
+	s.phase.RLock()
+	settings := s.settings
+	s.phase.RUnlock()
+	if settings != nil && settings.Version == round.SettingsVersion {
+		return settings, nil
+	}
+
+	settings, err := s.games.settingsService.GetSettingsForVersion(ctx, "aviation", round.SettingsVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load game settings: %w", err)
+	}
+	s.phase.Lock()
+	s.settings = settings
+	s.phase.Unlock()
+	return settings, nil
+}
+
+// Snapshot describes the current round for a client that just connected
+func (s *AviationService) Snapshot() AviationEvent {
+	s.phase.RLock()
+	defer s.phase.RUnlock()
+
+	event := AviationEvent{Type: "state"}
+	if s.round == nil {
+		return event
+	}
+	round := s.round.Public()
+	event.Round = &round
+	if s.round.Status == "flying" && s.round.StartedAt != nil {
+		event.Multiplier = aviationMultiplierAt(time.Since(*s.round.StartedAt))
+	}
+
+	s.liveMu.Lock()
+	for _, bet := range s.live {
+		event.Bets = append(event.Bets, *bet)
+	}
+	s.liveMu.Unlock()
+	return event
+}
+
+// GetRounds lists the most recent finished rounds with their revealed
+// server seeds
+func (s *AviationService) GetRounds(ctx context.Context, limit int64) ([]models.AviationRound, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "number", Value: -1}}).SetLimit(limit)
+	cursor, err := s.rounds.Find(ctx, bson.M{"status": bson.M{"$in": bson.A{"crashed", "settled"}}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rounds: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rounds []models.AviationRound
+	if err := cursor.All(ctx, &rounds); err != nil {
+		return nil, fmt.Errorf("failed to decode rounds: %w", err)
+	}
+	return rounds, nil
+}
+
+// Subscribe registers for round events. Events are dropped for a
+// subscriber that falls behind; call the returned func to unsubscribe.
+func (s *AviationService) Subscribe() (<-chan AviationEvent, func()) {
+	ch := make(chan AviationEvent, 64)
+	s.subMu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.subMu.Unlock()
+
+	return ch, func() {
+		s.subMu.Lock()
+		if _, ok := s.subscribers[ch]; ok {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+		s.subMu.Unlock()
+	}
+}
+
+func (s *AviationService) broadcast(event AviationEvent) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	for ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// track updates the in-memory copy of a bet on the current round. It
+// reports whether the bet is new or has moved on, so each bet and cash-out
+// is announced once however many times it is seen.
+func (s *AviationService) track(bet *models.AviationBet) bool {
+	s.liveMu.Lock()
+	defer s.liveMu.Unlock()
+	if known, ok := s.live[bet.UserID]; ok && known.ID == bet.ID &&
+		(known.Status == bet.Status || (known.Status == "cashed_out" && bet.Status == "active")) {
+		return false
+	}
+	copied := *bet
+	s.live[bet.UserID] = &copied
+	return true
+}
+
+// Run plays rounds back to back until ctx is cancelled. It runs on every
+// instance; only the lease holder plays, the others follow the stored
+// rounds and wait to take over.
+func (s *AviationService) Run(ctx context.Context) {
+	go s.follow(ctx)
+	for {
+		if err := s.playRound(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("[Aviation] ❌ Round failed: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.pause):
+		}
+	}
+}
+
+// playRound runs one round from its betting window to settlement. Nothing
+// is played while the game is disabled.
+func (s *AviationService) playRound(ctx context.Context) error {
+	settings, err := s.games.settingsService.GetCachedGameSettings(ctx, "aviation")
+	if err != nil {
+		return fmt.Errorf("failed to load game settings: %w", err)
+	}
+	if !settings.Enabled {
+		return nil
+	}
+	var cfg models.AviationConfig
+	if err := decodeConfig(settings.Config, &cfg); err != nil {
+		return err
+	}
+
+	// Hold the lease until the longest possible round has been settled
+	flight := time.Duration(math.Log(cfg.MaxMultiplier) / aviationGrowthRate * float64(time.Second))
+	held, err := s.holdLease(ctx, s.bettingWindow+flight+aviationLeaseMargin)
+	if err != nil {
+		return err
+	}
+	s.phase.Lock()
+	took := held && !s.leading
+	s.leading = held
+	if !held {
+		s.round = nil
+	}
+	s.phase.Unlock()
+	if !held {
+		return nil
+	}
+	if took {
+		log.Printf("[Aviation] ✅ Instance %s now runs the rounds\n", s.instanceID)
+	}
+	s.recoverRounds(ctx)
+
+	round, err := s.newRound(ctx, settings, &cfg)
+	if err != nil {
+		return err
+	}
+
+	s.phase.Lock()
+	s.round = round
+	s.settings = settings
+	s.phase.Unlock()
+	s.liveMu.Lock()
+	s.live = make(map[string]*models.AviationBet)
+	s.liveMu.Unlock()
+	s.broadcastRound("betting", round)
+
+	select {
+	case <-ctx.Done():
+		// The round is cancelled and its bets refunded on the next start
+		return ctx.Err()
+	case <-time.After(time.Until(round.BettingEndsAt)):
+	}
+
+	// Take off. From here the stored round accepts no more bets; a round
+	// whose take-off can't be stored is cancelled on the next start.
+	started := time.Now()
+	stored, err := s.advance(ctx, round, "betting", bson.M{"status": "flying", "started_at": started})
+	if err != nil {
+		s.phase.Lock()
+		s.round = nil
+		s.phase.Unlock()
+		return err
+	}
+	s.phase.Lock()
+	round.Status = "flying"
+	round.StartedAt = &started
+	s.phase.Unlock()
+	s.broadcastRound("flying", round)
+	log.Printf("[Aviation] Round %d took off with %d bets\n", round.Number, len(stored.Bets))
+
+	ticker := time.NewTicker(s.tickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		multiplier := aviationMultiplierAt(time.Since(started))
+		if multiplier >= round.CrashPoint {
+			break
+		}
+		s.autoCashout(ctx, round, multiplier)
+		s.broadcast(AviationEvent{Type: "tick", Multiplier: multiplier})
+	}
+
+	// Crash. From here the stored round accepts no more cash-outs and
+	// holds every bet and cash-out to settle; a round whose crash can't
+	// be stored is settled on the next start.
+	s.phase.Lock()
+	crashed := time.Now()
+	round.Status = "crashed"
+	round.CrashedAt = &crashed
+	s.phase.Unlock()
+	stored, err = s.advance(ctx, round, "flying", bson.M{"status": "crashed", "crashed_at": crashed})
+	if err != nil {
+		return err
+	}
+	s.broadcastRound("crashed", round)
+
+	log.Printf("[Aviation] Round %d crashed at %gx\n", round.Number, round.CrashPoint)
+	return s.settleRound(ctx, stored)
+}
+
+// advance moves the stored round on from status from with an update
+// conditional on that status, and returns the round as stored afterwards
+func (s *AviationService) advance(ctx context.Context, round *models.AviationRound, from string, set bson.M) (*models.AviationRound, error) {
+	var stored models.AviationRound
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := s.rounds.FindOneAndUpdate(ctx, bson.M{"_id": round.ID, "status": from}, bson.M{"$set": set}, opts).Decode(&stored)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("round %d is no longer %s", round.Number, from)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update round %d: %w", round.Number, err)
+	}
+	return &stored, nil
+}
+
+// holdLease takes or extends the aviation lease for d. It reports false
+// while another instance holds an unexpired lease.
+func (s *AviationService) holdLease(ctx context.Context, d time.Duration) (bool, error) {
+	now := time.Now()
+	filter := bson.M{"_id": "aviation", "$or": bson.A{
+		bson.M{"holder": s.instanceID},
+		bson.M{"expires_at": bson.M{"$lte": now}},
+	}}
+	update := bson.M{"$set": bson.M{"holder": s.instanceID, "expires_at": now.Add(d)}}
+	_, err := s.leases.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// The upsert lost to a lease someone else holds
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to hold aviation lease: %w", err)
+	}
+	return true, nil
+}
+
+// newRound commits to a fresh server seed and draws the round's crash point
+// from it before any bet is taken
+func (s *AviationService) newRound(ctx context.Context, settings *models.GameSettings, cfg *models.AviationConfig) (*models.AviationRound, error) {
+	serverSeed, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+
+	var last models.AviationRound
+	opts := options.FindOne().SetSort(bson.D{{Key: "number", Value: -1}})
+	err = s.rounds.FindOne(ctx, bson.M{}, opts).Decode(&last)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to get last round: %w", err)
+	}
+
+	now := time.Now()
+	round := &models.AviationRound{
+		ID:              fmt.Sprintf("round_%d", now.UnixNano()),
+		Number:          last.Number + 1,
+		Status:          "betting",
+		ServerSeed:      serverSeed,
+		ServerSeedHash:  HashServerSeed(serverSeed),
+		SettingsVersion: settings.Version,
+		BettingEndsAt:   now.Add(s.bettingWindow),
+		CreatedAt:       now,
+	}
+	round.CrashPoint = aviationCrashPoint(cfg, settings.HouseEdge, newFairSource(round.ServerSeed, round.ID, round.Number))
+
+	if _, err := s.rounds.InsertOne(ctx, round); err != nil {
+		return nil, fmt.Errorf("failed to create round: %w", err)
+	}
+	return round, nil
+}
+
+// autoCashout cashes out every bet whose target the multiplier has reached.
+// Targets passed between ticks are paid at the target, not the tick.
+func (s *AviationService) autoCashout(ctx context.Context, round *models.AviationRound, multiplier float64) {
+	var due []models.AviationBet
+	s.liveMu.Lock()
+	for _, bet := range s.live {
+		if bet.Status == "active" && bet.AutoCashout > 0 && bet.AutoCashout <= multiplier {
+			due = append(due, *bet)
+		}
+	}
+	s.liveMu.Unlock()
+
+	for _, bet := range due {
+		if _, err := s.cashOut(ctx, round, bet.UserID, bet.AutoCashout); err != nil && !errors.Is(err, ErrAviationNoBet) {
+			log.Printf("[Aviation] ❌ Failed to auto cash out bet %s: %v\n", bet.ID, err)
+		}
+	}
+}
+
+// settleRound records every open bet of a crashed round as a Game and
+// credits the winners. round is the round as stored after the crash, with
+// the bets and cash-outs it accepted. A bet is paid at its cash-out, or at
+// its auto cash-out target if that was within the crash point, and loses
+// otherwise. Bets without a stake are dropped.
+func (s *AviationService) settleRound(ctx context.Context, round *models.AviationRound) error {
+	gamesCol := s.games.db.Collection("games")
+
+	bets, err := s.openBets(ctx, round)
+	if err != nil {
+		return err
+	}
+	cashouts := make(map[string]float64, len(round.Cashouts))
+	for _, c := range round.Cashouts {
+		cashouts[c.BetID] = c.Multiplier
+	}
+
+	games := make([]interface{}, 0, len(bets))
+	updates := make([]mongo.WriteModel, 0, len(bets))
+	settled := make([]*models.Game, 0, len(bets))
+	for i := range bets {
+		bet := &bets[i]
+		bet.CashoutMultiplier = cashouts[bet.ID]
+		if bet.CashoutMultiplier == 0 && bet.AutoCashout > 0 && bet.AutoCashout <= round.CrashPoint {
+			bet.CashoutMultiplier = bet.AutoCashout
+		}
+		if bet.CashoutMultiplier > 0 {
+			bet.Status = "won"
+			bet.WinAmount = bet.Amount.MulMultiplier(bet.CashoutMultiplier)
+		} else {
+			bet.Status = "lost"
+			bet.WinAmount = 0
+		}
+		// Derived from the bet so a retried settlement can't record it twice
+		bet.GameID = "game_" + bet.ID
+
+		game := &models.Game{
+			ID:         bet.GameID,
+			UserID:     bet.UserID,
+			GameType:   "aviation",
+			BetAmount:  bet.Amount,
+			WinAmount:  bet.WinAmount,
+			Multiplier: bet.CashoutMultiplier,
+			ResultData: map[string]interface{}{
+				"round_id":           round.ID,
+				"round_number":       round.Number,
+				"crash_point":        round.CrashPoint,
+				"cashout_multiplier": bet.CashoutMultiplier,
+				"auto_cashout":       bet.AutoCashout,
+			},
+			Settled:         false,
+			CreatedAt:       bet.CreatedAt,
+			SettingsVersion: round.SettingsVersion,
+			ServerSeedHash:  round.ServerSeedHash,
+			ClientSeed:      round.ID,
+			Nonce:           round.Number,
+		}
+		games = append(games, game)
+		settled = append(settled, game)
+		updates = append(updates, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": bet.ID, "status": bson.M{"$in": bson.A{"pending", "active", "cashed_out"}}}).
+			SetUpdate(bson.M{"$set": bson.M{
+				"status":             bet.Status,
+				"cashout_multiplier": bet.CashoutMultiplier,
+				"win_amount":         bet.WinAmount,
+				"game_id":            bet.GameID,
+			}}))
+	}
+
+	if len(bets) > 0 {
+		_, err := gamesCol.InsertMany(ctx, games, options.InsertMany().SetOrdered(false))
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("failed to insert games: %w", err)
+		}
+		if _, err := s.bets.BulkWrite(ctx, updates, options.BulkWrite().SetOrdered(false)); err != nil {
+			return fmt.Errorf("failed to settle bets: %w", err)
+		}
+	}
+
+	// A win that can't be credited leaves its game unsettled to be paid
+	// out later
+	paid := make([]string, 0, len(settled))
+	for _, game := range settled {
+		if game.WinAmount > 0 {
+			err := s.games.walletService.CreditBalance(ctx, game.UserID, game.WinAmount, "aviation game win", "game_win", game.ID, game.CreatedAt)
+			if err != nil {
+				log.Printf("[Aviation] ❌ Failed to credit winnings for game %s: %v\n", game.ID, err)
+				continue
+			}
+		}
+		paid = append(paid, game.ID)
+		game.Settled = true
+	}
+	if len(paid) > 0 {
+		if _, err := gamesCol.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": paid}}, bson.M{"$set": bson.M{"settled": true}}); err != nil {
+			return fmt.Errorf("failed to settle games: %w", err)
+		}
+	}
+	for _, game := range settled {
+		if game.Settled {
+			s.games.updateUserStats(ctx, game)
+		}
+	}
+
+	now := time.Now()
+	round.Status = "settled"
+	round.SettledAt = &now
+	s.updateRound(ctx, round, bson.M{"status": round.Status, "settled_at": now})
+
+	log.Printf("[Aviation] ✅ Round %d settled: %d bets, %d paid\n", round.Number, len(bets), len(paid))
+	return nil
+}
+
+// recoverRounds finishes rounds left open, by a previous run or by a
+// failed update of this one. A round still taking bets is cancelled and
+// refunded; one that took off is settled at its crash point.
+func (s *AviationService) recoverRounds(ctx context.Context) {
+	cursor, err := s.rounds.Find(ctx, bson.M{"status": bson.M{"$in": bson.A{"betting", "cancelling", "flying", "crashed"}}})
+	if err != nil {
+		log.Printf("[Aviation] ❌ Failed to find open rounds: %v\n", err)
+		return
+	}
+	var rounds []models.AviationRound
+	if err := cursor.All(ctx, &rounds); err != nil {
+		log.Printf("[Aviation] ❌ Failed to decode open rounds: %v\n", err)
+		return
+	}
+
+	for i := range rounds {
+		round := &rounds[i]
+		if round.Status == "betting" || round.Status == "cancelling" {
+			if err := s.cancelRound(ctx, round); err != nil {
+				log.Printf("[Aviation] ❌ Failed to cancel round %d: %v\n", round.Number, err)
+			}
+			continue
+		}
+		if round.Status == "flying" {
+			stored, err := s.advance(ctx, round, "flying", bson.M{"status": "crashed", "crashed_at": time.Now()})
+			if err != nil {
+				log.Printf("[Aviation] ❌ Failed to crash round %d: %v\n", round.Number, err)
+				continue
+			}
+			round = stored
+		}
+		if err := s.settleRound(ctx, round); err != nil {
+			log.Printf("[Aviation] ❌ Failed to settle round %d: %v\n", round.Number, err)
+		}
+	}
+}
+
+// cancelRound stops a round that never took off from taking bets, then
+// refunds every bet it holds a stake for. The round stays "cancelling"
+// until every refund is in, so a failed one is retried.
+func (s *AviationService) cancelRound(ctx context.Context, round *models.AviationRound) error {
+	if round.Status == "betting" {
+		stored, err := s.advance(ctx, round, "betting", bson.M{"status": "cancelling"})
+		if err != nil {
+			return err
+		}
+		round = stored
+	}
+
+	bets, err := s.openBets(ctx, round)
+	if err != nil {
+		return err
+	}
+	for _, bet := range bets {
+		if err := s.games.walletService.CreditBalance(ctx, bet.UserID, bet.Amount, "aviation round cancelled", "game_refund", bet.ID, bet.CreatedAt); err != nil {
+			return fmt.Errorf("failed to refund bet %s: %w", bet.ID, err)
+		}
+		if _, err := s.bets.UpdateOne(ctx, bson.M{"_id": bet.ID}, bson.M{"$set": bson.M{"status": "refunded"}}); err != nil {
+			return fmt.Errorf("failed to refund bet %s: %w", bet.ID, err)
+		}
+	}
+
+	s.updateRound(ctx, round, bson.M{"status": "cancelled"})
+	log.Printf("[Aviation] Round %d cancelled, %d bets refunded\n", round.Number, len(bets))
+	return nil
+}
+
+// openBets returns the bets of a round that has stopped taking bets and
+// still has to settle them. A bet counts if the round accepted it and, when
+// it is still pending, its stake was taken; any other bet is dropped.
+func (s *AviationService) openBets(ctx context.Context, round *models.AviationRound) ([]models.AviationBet, error) {
+	cursor, err := s.bets.Find(ctx, bson.M{"round_id": round.ID, "status": bson.M{"$in": bson.A{"pending", "active", "cashed_out"}}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bets: %w", err)
+	}
+	var found []models.AviationBet
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, fmt.Errorf("failed to decode bets: %w", err)
+	}
+
+	accepted := make(map[string]bool, len(round.Bets))
+	for _, id := range round.Bets {
+		accepted[id] = true
+	}
+	bets := make([]models.AviationBet, 0, len(found))
+	for i := range found {
+		bet := &found[i]
+		staked := accepted[bet.ID]
+		if staked && bet.Status == "pending" {
+			staked, err = s.games.walletService.Applied(ctx, bet.UserID, "game_loss", bet.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check the stake of bet %s: %w", bet.ID, err)
+			}
+		}
+		if !staked {
+			dropped, err := s.dropBet(ctx, bet)
+			if err != nil {
+				return nil, err
+			}
+			if dropped {
+				continue
+			}
+		}
+		bets = append(bets, *bet)
+	}
+	return bets, nil
+}
+
+// updateRound stores round progress. The round plays on from memory if
+// the write fails.
+func (s *AviationService) updateRound(ctx context.Context, round *models.AviationRound, set bson.M) {
+	if _, err := s.rounds.UpdateOne(ctx, bson.M{"_id": round.ID}, bson.M{"$set": set}); err != nil {
+		log.Printf("[Aviation] ❌ Failed to update round %d: %v\n", round.Number, err)
+	}
+}
+
+func (s *AviationService) broadcastRound(eventType string, round *models.AviationRound) {
+	s.phase.RLock()
+	public := round.Public()
+	s.phase.RUnlock()
+	s.broadcast(AviationEvent{Type: eventType, Round: &public})
+}
+
+// aviationMultiplierAt is the multiplier after flying for elapsed, rounded
+// down to two decimals
+func aviationMultiplierAt(elapsed time.Duration) float64 {
+	return math.Floor(math.Exp(aviationGrowthRate*elapsed.Seconds())*100) / 100
+}
+
+// revealedSeed returns the server seed of the round an aviation game was
+// played in, once the round has crashed
+func (s *AviationService) revealedSeed(ctx context.Context, game *models.Game) (string, error) {
+	var round models.AviationRound
+	err := s.rounds.FindOne(ctx, bson.M{"_id": game.ClientSeed, "server_seed_hash": game.ServerSeedHash}).Decode(&round)
+	if err == mongo.ErrNoDocuments {
+		return "", fmt.Errorf("aviation round not found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get round: %w", err)
+	}
+	if round.Public().ServerSeed == "" {
+		return "", ErrSeedNotRevealed
+	}
+	return round.ServerSeed, nil
+}
+
+// replay draws the round's crash point again from its seed and settles
+// the bet's cash-out against it: a manual cash-out must be below the crash
+// point, an auto cash-out at most the crash point
+func (s *AviationService) replay(game *models.Game, settings *models.GameSettings, serverSeed string) (*models.Game, error) {
+	var cfg models.AviationConfig
+	if err := decodeConfig(settings.Config, &cfg); err != nil {
+		return nil, err
+	}
+	crashPoint := aviationCrashPoint(&cfg, settings.HouseEdge, newFairSource(serverSeed, game.ClientSeed, game.Nonce))
+
+	cashout, _ := choiceFloat(game.ResultData, "cashout_multiplier")
+	autoCashout, _ := choiceFloat(game.ResultData, "auto_cashout")
+	multiplier := 0.0
+	if cashout > 0 && (cashout < crashPoint || (cashout == autoCashout && autoCashout <= crashPoint)) {
+		multiplier = cashout
+	}
+
+	return &models.Game{
+		GameType:   "aviation",
+		BetAmount:  game.BetAmount,
+		WinAmount:  game.BetAmount.MulMultiplier(multiplier),
+		Multiplier: multiplier,
+		ResultData: map[string]interface{}{
+			"round_id":           game.ClientSeed,
+			"round_number":       game.Nonce,
+			"crash_point":        crashPoint,
+			"cashout_multiplier": cashout,
+			"auto_cashout":       autoCashout,
+		},
+	}, nil
+}
+
+// aviationCrashPoint picks a bucket weighted by CrashChances, then a u
+// for which 1 / (1 - u) falls uniformly in the bucket's share of the curve.
+// The crash point is (1 - house edge) / (1 - u), rounded down to two
+// decimals and limited to the configured min and max multipliers.
+func aviationCrashPoint(cfg *models.AviationConfig, houseEdge float64, rng RandomSource) float64 {
+	chances := []float64{cfg.CrashChances.Low, cfg.CrashChances.Medium, cfg.CrashChances.High, cfg.CrashChances.VeryHigh}
+	i := len(aviationBuckets) - 1
+	r := rng.Float64() * 100
+	for j, chance := range chances {
+		if r < chance {
+			i = j
+			break
+		}
+		r -= chance
+	}
+
+	from := 1 - 1/aviationBuckets[i].lo
+	to := 1 - 1/aviationBuckets[i].hi
+	u := from + rng.Float64()*(to-from)
+	point := math.Floor(100*(1-houseEdge/100)/(1-u)) / 100
+	return math.Min(math.Max(point, cfg.MinMultiplier), cfg.MaxMultiplier)
+}
+
+// aviationBestReturn is the highest expected return of cashing out at a
+// fixed multiplier, and that multiplier. Cashing out at t returns
+// t * P(crash point >= t), which is linear in t between the bucket edges,
+// so only cash-outs next to an edge or a limit need checking.
+func aviationBestReturn(cfg *models.AviationConfig, houseEdge float64) (float64, float64) {
+	chances := []float64{cfg.CrashChances.Low, cfg.CrashChances.Medium, cfg.CrashChances.High, cfg.CrashChances.VeryHigh}
+	scale := 1 - houseEdge/100
+
+	reaches := func(t float64) float64 {
+		switch {
+		case t <= cfg.MinMultiplier:
+			return 1
+		case t > cfg.MaxMultiplier:
+			return 0
+		}
+		// The rounded-down point reaches t exactly when the unrounded one does
+		z := t / scale
+		p := 0.0
+		for i, bucket := range aviationBuckets {
+			switch {
+			case z <= bucket.lo:
+				p += chances[i] / 100
+			case z < bucket.hi:
+				p += chances[i] / 100 * (1/z - 1/bucket.hi) / (1/bucket.lo - 1/bucket.hi)
+			}
+		}
+		return p
+	}
+
+	// Cash-outs are on the 0.01 grid and above 1
+	edges := []float64{1.01, cfg.MinMultiplier, cfg.MaxMultiplier}
+	for _, bucket := range aviationBuckets {
+		edges = append(edges, bucket.lo*scale)
+	}
+	best, at := 0.0, 0.0
+	for _, edge := range edges {
+		for _, t := range []float64{math.Floor(edge*100+1e-9) / 100, math.Ceil(edge*100-1e-9) / 100} {
+			if t <= 1 || t > cfg.MaxMultiplier {
+				continue
+			}
+			if ret := t * reaches(t); ret > best {
+				best, at = ret, t
+			}
+		}
+	}
+	return best, at
+}
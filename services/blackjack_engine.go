@@ -0,0 +1,340 @@
+package services
+
+import (
+	"fmt"
+
+	"betting-app-backend-go/models"
+)
+
+// maxBlackjackHands is how many hands splitting can produce
+const maxBlackjackHands = 4
+
+// BlackjackEngine plays blackjack as a session against a shoe of NumDecks
+// decks shuffled when the session starts. The dealer peeks for blackjack
+// when showing a ten (at the deal) or an ace (after insurance is decided),
+// stands on hard 17 and hits soft 17 when DealerHitsSoft17 is set.
+type BlackjackEngine struct{}
+
+func (e *BlackjackEngine) GameType() string { return "blackjack" }
+
+func (e *BlackjackEngine) Start(session *models.GameSession, settings *models.GameSettings, choices map[string]interface{}, rng RandomSource) error {
+	var cfg models.BlackjackConfig
+	if err := decodeConfig(settings.Config, &cfg); err != nil {
+		return err
+	}
+
+	// Fisher-Yates shuffle of the whole shoe
+	shoe := make([]string, 0, cfg.NumDecks*52)
+	for d := 0; d < cfg.NumDecks; d++ {
+		for _, suit := range "SHDC" {
+			for _, rank := range "A23456789TJQK" {
+				shoe = append(shoe, string(rank)+string(suit))
+			}
+		}
+	}
+	for i := len(shoe) - 1; i > 0; i-- {
+		j := pickIndex(rng, i+1)
+		shoe[i], shoe[j] = shoe[j], shoe[i]
+	}
+
+	table := &models.BlackjackState{
+		Shoe:    shoe,
+		Hands:   []models.BlackjackHand{{Bet: session.BetAmount}},
+		History: []models.BlackjackEvent{},
+	}
+	session.Blackjack = table
+
+	// Player, dealer, player, dealer (hole card)
+	hand := &table.Hands[0]
+	hand.Cards = append(hand.Cards, e.deal(table, "deal", 0))
+	table.Dealer = append(table.Dealer, e.deal(table, "deal", -1))
+	hand.Cards = append(hand.Cards, e.deal(table, "deal", 0))
+	table.Dealer = append(table.Dealer, e.draw(table))
+
+	if cardValue(table.Dealer[0]) == 11 {
+		table.InsuranceOffered = true
+		return nil
+	}
+	if cardValue(table.Dealer[0]) == 10 && isBlackjack(table.Dealer) {
+		e.finish(session, &cfg)
+		return nil
+	}
+	if isBlackjack(hand.Cards) {
+		e.finish(session, &cfg)
+	}
+	return nil
+}
+
+func (e *BlackjackEngine) Act(session *models.GameSession, settings *models.GameSettings, action string, params map[string]interface{}, rng RandomSource) error {
+	var cfg models.BlackjackConfig
+	if err := decodeConfig(settings.Config, &cfg); err != nil {
+		return err
+	}
+	table := session.Blackjack
+
+	if table.InsuranceOffered {
+		switch action {
+		case "insurance":
+			// Half the original bet, paid 2:1 if the dealer has blackjack
+			table.InsuranceBet = table.Hands[0].Bet / 2
+			if table.InsuranceBet <= 0 {
+				return fmt.Errorf("%w: bet is too small to insure", ErrInvalidChoice)
+			}
+			session.BetAmount += table.InsuranceBet
+			table.History = append(table.History, models.BlackjackEvent{Action: "insurance", Hand: 0})
+		case "decline_insurance":
+			table.History = append(table.History, models.BlackjackEvent{Action: "decline_insurance", Hand: 0})
+		default:
+			return fmt.Errorf("%w: the dealer shows an ace, take insurance or decline_insurance first", ErrInvalidChoice)
+		}
+		table.InsuranceOffered = false
+
+		if isBlackjack(table.Dealer) || isBlackjack(table.Hands[0].Cards) {
+			e.finish(session, &cfg)
+		}
+		return nil
+	}
+
+	hand := &table.Hands[table.ActiveHand]
+	switch action {
+	case "hit":
+		hand.Cards = append(hand.Cards, e.deal(table, "hit", table.ActiveHand))
+		if total, _ := handTotal(hand.Cards); total >= 21 {
+			hand.Done = true
+		}
+
+	case "stand":
+		table.History = append(table.History, models.BlackjackEvent{Action: "stand", Hand: table.ActiveHand})
+		hand.Done = true
+
+	case "double":
+		if !cfg.AllowDoubleDown {
+			return fmt.Errorf("%w: doubling down is not allowed", ErrInvalidChoice)
+		}
+		if len(hand.Cards) != 2 {
+			return fmt.Errorf("%w: can only double on the first two cards", ErrInvalidChoice)
+		}
+		session.BetAmount += hand.Bet
+		hand.Bet *= 2
+		hand.Doubled = true
+		hand.Cards = append(hand.Cards, e.deal(table, "double", table.ActiveHand))
+		hand.Done = true
+
+	case "split":
+		if !cfg.AllowSplit {
+			return fmt.Errorf("%w: splitting is not allowed", ErrInvalidChoice)
+		}
+		if len(hand.Cards) != 2 || cardValue(hand.Cards[0]) != cardValue(hand.Cards[1]) {
+			return fmt.Errorf("%w: can only split a pair", ErrInvalidChoice)
+		}
+		if len(table.Hands) >= maxBlackjackHands {
+			return fmt.Errorf("%w: at most %d hands", ErrInvalidChoice, maxBlackjackHands)
+		}
+		session.BetAmount += hand.Bet
+		table.History = append(table.History, models.BlackjackEvent{Action: "split", Hand: table.ActiveHand})
+
+		// The new hand goes right after the one being split
+		i := table.ActiveHand
+		second := models.BlackjackHand{Cards: []string{hand.Cards[1]}, Bet: hand.Bet, Split: true}
+		table.Hands = append(table.Hands[:i+1], append([]models.BlackjackHand{second}, table.Hands[i+1:]...)...)
+		hand = &table.Hands[i]
+		hand.Cards = hand.Cards[:1]
+		hand.Split = true
+
+		aces := cardValue(hand.Cards[0]) == 11
+		for j := i; j <= i+1; j++ {
+			h := &table.Hands[j]
+			h.Cards = append(h.Cards, e.deal(table, "deal", j))
+			// Split aces get one card each
+			if total, _ := handTotal(h.Cards); aces || total == 21 {
+				h.Done = true
+			}
+		}
+
+	default:
+		return fmt.Errorf("%w: unknown action %q, expected hit, stand, double or split", ErrInvalidChoice, action)
+	}
+
+	// Move on to the next hand still in play
+	for table.ActiveHand < len(table.Hands) && table.Hands[table.ActiveHand].Done {
+		table.ActiveHand++
+	}
+	if table.ActiveHand == len(table.Hands) {
+		table.ActiveHand = len(table.Hands) - 1
+		e.finish(session, &cfg)
+	}
+	return nil
+}
+
+// Expire declines insurance, stands on every open hand and plays out the
+// dealer
+func (e *BlackjackEngine) Expire(session *models.GameSession, settings *models.GameSettings) {
+	var cfg models.BlackjackConfig
+	if err := decodeConfig(settings.Config, &cfg); err != nil {
+		// Settings of a stored version always decode; fall back to even money
+		cfg.WinPayout, cfg.BlackjackPayout = 2, 2
+	}
+	table := session.Blackjack
+	if table.InsuranceOffered {
+		table.InsuranceOffered = false
+		table.History = append(table.History, models.BlackjackEvent{Action: "decline_insurance", Hand: 0})
+	}
+	for i := range table.Hands {
+		table.Hands[i].Done = true
+	}
+	e.finish(session, &cfg)
+	session.Outcome = "expired"
+}
+
+// Payout is the total returned to the player: every hand plus insurance
+func (e *BlackjackEngine) Payout(session *models.GameSession) models.Money {
+	table := session.Blackjack
+	total := table.InsurancePayout
+	for _, hand := range table.Hands {
+		total += hand.Payout
+	}
+	return total
+}
+
+func (e *BlackjackEngine) ResultData(session *models.GameSession) map[string]interface{} {
+	table := session.Blackjack
+	dealerTotal, _ := handTotal(table.Dealer)
+	hands := make([]map[string]interface{}, len(table.Hands))
+	for i, hand := range table.Hands {
+		total, _ := handTotal(hand.Cards)
+		hands[i] = map[string]interface{}{
+			"cards":   hand.Cards,
+			"total":   total,
+			"bet":     hand.Bet,
+			"doubled": hand.Doubled,
+			"split":   hand.Split,
+			"result":  hand.Result,
+			"payout":  hand.Payout,
+		}
+	}
+	return map[string]interface{}{
+		"dealer":           table.Dealer,
+		"dealer_total":     dealerTotal,
+		"hands":            hands,
+		"insurance_bet":    table.InsuranceBet,
+		"insurance_payout": table.InsurancePayout,
+		"history":          table.History,
+		"outcome":          session.Outcome,
+	}
+}
+
+// finish plays the dealer's hand if needed and pays every hand
+func (e *BlackjackEngine) finish(session *models.GameSession, cfg *models.BlackjackConfig) {
+	table := session.Blackjack
+	table.History = append(table.History, models.BlackjackEvent{Action: "dealer_reveal", Hand: -1, Card: table.Dealer[1]})
+
+	dealerBlackjack := isBlackjack(table.Dealer)
+	if table.InsuranceBet > 0 && dealerBlackjack {
+		table.InsurancePayout = table.InsuranceBet * 3
+	}
+
+	// A natural only counts on the original, unsplit hand
+	natural := len(table.Hands) == 1 && isBlackjack(table.Hands[0].Cards)
+
+	// The dealer only draws if some hand could still beat them
+	live := false
+	for _, hand := range table.Hands {
+		if total, _ := handTotal(hand.Cards); total <= 21 {
+			live = true
+		}
+	}
+	if live && !dealerBlackjack && !natural {
+		for {
+			total, soft := handTotal(table.Dealer)
+			if total > 17 || (total == 17 && !(soft && cfg.DealerHitsSoft17)) {
+				break
+			}
+			table.Dealer = append(table.Dealer, e.deal(table, "dealer_hit", -1))
+		}
+	}
+	dealerTotal, _ := handTotal(table.Dealer)
+
+	var paid models.Money
+	for i := range table.Hands {
+		hand := &table.Hands[i]
+		hand.Done = true
+		total, _ := handTotal(hand.Cards)
+		switch {
+		case natural && dealerBlackjack:
+			hand.Result, hand.Payout = "push", hand.Bet
+		case natural:
+			hand.Result, hand.Payout = "blackjack", hand.Bet.MulMultiplier(cfg.BlackjackPayout)
+		case total > 21:
+			hand.Result, hand.Payout = "bust", 0
+		case dealerBlackjack:
+			hand.Result, hand.Payout = "lose", 0
+		case dealerTotal > 21 || total > dealerTotal:
+			hand.Result, hand.Payout = "win", hand.Bet.MulMultiplier(cfg.WinPayout)
+		case total == dealerTotal:
+			hand.Result, hand.Payout = "push", hand.Bet
+		default:
+			hand.Result, hand.Payout = "lose", 0
+		}
+		paid += hand.Payout
+	}
+	paid += table.InsurancePayout
+
+	switch {
+	case paid > session.BetAmount:
+		session.Outcome = "won"
+	case paid == session.BetAmount:
+		session.Outcome = "push"
+	default:
+		session.Outcome = "lost"
+	}
+	session.Multiplier = models.RoundMultiplier(float64(paid) / float64(session.BetAmount))
+}
+
+// deal draws a card and records it in the history
+func (e *BlackjackEngine) deal(table *models.BlackjackState, action string, hand int) string {
+	card := e.draw(table)
+	table.History = append(table.History, models.BlackjackEvent{Action: action, Hand: hand, Card: card})
+	return card
+}
+
+// draw takes the next card from the shoe. A round can never use up a shoe
+// of at least one deck.
+func (e *BlackjackEngine) draw(table *models.BlackjackState) string {
+	card := table.Shoe[table.ShoeIndex]
+	table.ShoeIndex++
+	return card
+}
+
+// cardValue is the blackjack value of a card, counting aces as 11
+func cardValue(card string) int {
+	switch card[0] {
+	case 'A':
+		return 11
+	case 'T', 'J', 'Q', 'K':
+		return 10
+	}
+	return int(card[0] - '0')
+}
+
+// handTotal returns the best total of cards and whether an ace is still
+// counted as 11
+func handTotal(cards []string) (int, bool) {
+	total, aces := 0, 0
+	for _, card := range cards {
+		v := cardValue(card)
+		if v == 11 {
+			aces++
+		}
+		total += v
+	}
+	for total > 21 && aces > 0 {
+		total -= 10
+		aces--
+	}
+	return total, aces > 0
+}
+
+func isBlackjack(cards []string) bool {
+	total, _ := handTotal(cards)
+	return len(cards) == 2 && total == 21
+}
@@ -0,0 +1,104 @@
+package services_test
+
+import (
+	"testing"
+
+	"betting-app-backend-go/models"
+	"betting-app-backend-go/services"
+)
+
+// stackedShoe returns values that make the shuffle of a one-deck shoe
+// deal top first, followed by the rest of the deck
+func stackedShoe(t *testing.T, top ...string) *sequenceSource {
+	t.Helper()
+	var shoe, order []string
+	for _, suit := range "SHDC" {
+		for _, rank := range "A23456789TJQK" {
+			shoe = append(shoe, string(rank)+string(suit))
+		}
+	}
+	used := make(map[string]bool, len(top))
+	for _, card := range top {
+		if used[card] {
+			t.Fatalf("card %s is stacked twice", card)
+		}
+		used[card] = true
+	}
+	order = append(order, top...)
+	for _, card := range shoe {
+		if !used[card] {
+			order = append(order, card)
+		}
+	}
+
+	// Fisher-Yates fills the shoe from the back: pick the card that
+	// belongs at i from the cards not placed yet
+	var values []float64
+	for i := len(shoe) - 1; i > 0; i-- {
+		j := 0
+		for shoe[j] != order[i] {
+			j++
+		}
+		shoe[i], shoe[j] = shoe[j], shoe[i]
+		values = append(values, (float64(j)+0.5)/float64(i+1))
+	}
+	return &sequenceSource{values: values}
+}
+
+// TestBlackjackPayouts deals stacked shoes (player, dealer up card,
+// player, hole card, then draws) and checks each hand's payout and the
+// dealer's peek for blackjack
+func TestBlackjackPayouts(t *testing.T) {
+	engine := &services.BlackjackEngine{}
+	settings := defaultSettings(t, "blackjack")
+	settings.Config["num_decks"] = 1
+
+	tests := []struct {
+		name       string
+		cards      []string
+		actions    []string
+		dealer     int // cards the dealer ends with
+		outcome    string
+		multiplier float64
+	}{
+		{"natural pays 3:2", []string{"AS", "9H", "KS", "7D"}, nil, 2, "won", 2.5},
+		{"dealer peeks a ten for blackjack", []string{"9S", "KH", "8C", "AD"}, nil, 2, "lost", 0},
+		{"natural against a peeked blackjack", []string{"AS", "KH", "QS", "AD"}, nil, 2, "push", 1},
+		{"insured against an ace with blackjack", []string{"TS", "AH", "9C", "KD"}, []string{"insurance"}, 2, "push", 1},
+		{"declined insurance, dealer hits soft 17", []string{"TS", "AH", "9C", "6D", "5H", "KC"}, []string{"decline_insurance", "stand"}, 4, "won", 2},
+		{"dealer stands on hard 17", []string{"TS", "TH", "9C", "7D"}, []string{"stand"}, 2, "won", 2},
+		{"double down", []string{"5S", "9H", "6C", "8D", "TS"}, []string{"double"}, 2, "won", 2},
+		{"push", []string{"TS", "KH", "QC", "QD"}, []string{"stand"}, 2, "push", 1},
+		{"bust leaves the dealer standing", []string{"TS", "7H", "6C", "TD", "KC"}, []string{"hit"}, 2, "lost", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &models.GameSession{GameType: "blackjack", BetAmount: models.MoneyFromMajor(10), Stake: models.MoneyFromMajor(10)}
+			if err := engine.Start(session, settings, nil, stackedShoe(t, tt.cards...)); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			for _, action := range tt.actions {
+				if session.Outcome != "" {
+					t.Fatalf("round ended before %s", action)
+				}
+				if err := engine.Act(session, settings, action, nil, nil); err != nil {
+					t.Fatalf("%s: %v", action, err)
+				}
+			}
+
+			table := session.Blackjack
+			if table.Hands[0].Cards[0] != tt.cards[0] || table.Dealer[0] != tt.cards[1] {
+				t.Fatalf("dealt %v against %v, want the stacked %v", table.Hands[0].Cards, table.Dealer, tt.cards)
+			}
+			if session.Outcome != tt.outcome || session.Multiplier != tt.multiplier {
+				t.Errorf("outcome %q at %v, want %q at %v", session.Outcome, session.Multiplier, tt.outcome, tt.multiplier)
+			}
+			if len(table.Dealer) != tt.dealer {
+				t.Errorf("dealer ended with %v, want %d cards", table.Dealer, tt.dealer)
+			}
+			if got, want := engine.Payout(session), session.BetAmount.MulMultiplier(tt.multiplier); got != want {
+				t.Errorf("Payout = %v on %v wagered, want %v", got, session.BetAmount, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"betting-app-backend-go/models"
+)
+
+// DiceEngine rolls a number from 0.00 to 99.99. The player picks a target
+// and whether the roll must land over or under it; the payout multiplier is
+// the house's share of 100 divided by the win chance.
+type DiceEngine struct{}
+
+func (e *DiceEngine) GameType() string { return "dice" }
+
+func (e *DiceEngine) Play(req *PlayRequest, settings *models.GameSettings, rng RandomSource) (*models.Game, error) {
+	var cfg models.DiceConfig
+	if err := decodeConfig(settings.Config, &cfg); err != nil {
+		return nil, err
+	}
+
+	target, err := choiceFloat(req.Choices, "target")
+	if err != nil {
+		return nil, err
+	}
+	direction, err := choiceString(req.Choices, "direction")
+	if err != nil {
+		return nil, err
+	}
+
+	// Targets are on the same 0.01 grid as the roll
+	if math.Abs(target*100-math.Round(target*100)) > 1e-6 {
+		return nil, fmt.Errorf("%w: target must have at most 2 decimal places", ErrInvalidChoice)
+	}
+	target = math.Round(target*100) / 100
+	if target < cfg.MinTarget || target > cfg.MaxTarget {
+		return nil, fmt.Errorf("%w: target must be between %.2f and %.2f", ErrInvalidChoice, cfg.MinTarget, cfg.MaxTarget)
+	}
+
+	// Win chance in percent: "under" wins on rolls below the target, "over"
+	// on rolls above it, out of the 10000 possible rolls
+	var chance float64
+	switch direction {
+	case "under":
+		chance = target
+	case "over":
+		chance = 99.99 - target
+	default:
+		return nil, fmt.Errorf("%w: direction must be over or under", ErrInvalidChoice)
+	}
+	chance = math.Round(chance*100) / 100
+	if chance <= 0 {
+		return nil, fmt.Errorf("%w: target leaves no winning rolls", ErrInvalidChoice)
+	}
+
+	multiplier := models.RoundMultiplier((100 - cfg.HouseEdge) / chance)
+	if multiplier <= 1 {
+		return nil, fmt.Errorf("%w: win chance is too high to pay out", ErrInvalidChoice)
+	}
+
+	// Roll 0.00-99.99 on an integer grid to avoid float comparisons
+	rollHundredths := pickIndex(rng, 10000)
+	targetHundredths := int(math.Round(target * 100))
+	roll := float64(rollHundredths) / 100
+
+	won := rollHundredths < targetHundredths
+	if direction == "over" {
+		won = rollHundredths > targetHundredths
+	}
+
+	payout := 0.0
+	if won {
+		payout = multiplier
+	}
+
+	game := settle(req, payout, map[string]interface{}{
+		"roll":       roll,
+		"target":     target,
+		"direction":  direction,
+		"win_chance": chance,
+		"multiplier": multiplier,
+		"won":        won,
+	})
+	return game, nil
+}
+
+// ExpectedReturn is 1 - the config's house edge, which sets every payout
+// whatever the target
+func (e *DiceEngine) ExpectedReturn(settings *models.GameSettings) (float64, error) {
+	var cfg models.DiceConfig
+	if err := decodeConfig(settings.Config, &cfg); err != nil {
+		return 0, err
+	}
+	return 1 - cfg.HouseEdge/100, nil
+}
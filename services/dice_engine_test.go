@@ -0,0 +1,116 @@
+package services_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"betting-app-backend-go/models"
+	"betting-app-backend-go/services"
+)
+
+// defaultSettings returns the settings a game ships with
+func defaultSettings(t *testing.T, gameType string) *models.GameSettings {
+	t.Helper()
+	for _, settings := range services.DefaultGameSettings() {
+		if settings.GameType == gameType {
+			return &settings
+		}
+	}
+	t.Fatalf("no default settings for %s", gameType)
+	return nil
+}
+
+// TestDicePayouts pins the win chance and multiplier of a few targets and
+// checks every one of the 10000 rolls together returns 1 - house edge
+func TestDicePayouts(t *testing.T) {
+	engine := &services.DiceEngine{}
+	settings := defaultSettings(t, "dice")
+
+	tests := []struct {
+		target     float64
+		direction  string
+		chance     float64
+		multiplier float64
+	}{
+		{50, "under", 50, 1.98},
+		{50, "over", 49.99, 1.9804},
+		{1, "under", 1, 99},
+		{98.99, "over", 1, 99},
+		{98.99, "under", 98.99, 1.0001},
+		{25.5, "over", 74.49, 1.329},
+	}
+	for _, tt := range tests {
+		total := 0.0
+		for roll := 0; roll < 10000; roll++ {
+			req := &services.PlayRequest{
+				GameType:  "dice",
+				BetAmount: models.MoneyFromMajor(1),
+				Choices:   map[string]interface{}{"target": tt.target, "direction": tt.direction},
+			}
+			game, err := engine.Play(req, settings, &sequenceSource{values: []float64{(float64(roll) + 0.5) / 10000}})
+			if err != nil {
+				t.Fatalf("%s %.2f: Play: %v", tt.direction, tt.target, err)
+			}
+			if roll == 0 {
+				if got := game.ResultData["win_chance"]; got != tt.chance {
+					t.Errorf("%s %.2f: win_chance = %v, want %v", tt.direction, tt.target, got, tt.chance)
+				}
+				if got := game.ResultData["multiplier"]; got != tt.multiplier {
+					t.Errorf("%s %.2f: multiplier = %v, want %v", tt.direction, tt.target, got, tt.multiplier)
+				}
+			}
+			total += game.Multiplier
+		}
+		// Rounding the multiplier to 4 places moves the return by less
+		// than 0.0001
+		if played := total / 10000; math.Abs(played-0.99) > 1e-4 {
+			t.Errorf("%s %.2f returns %.5f, want 0.99", tt.direction, tt.target, played)
+		}
+	}
+}
+
+// TestDiceRollOnTarget checks a roll equal to the target loses both ways
+func TestDiceRollOnTarget(t *testing.T) {
+	engine := &services.DiceEngine{}
+	settings := defaultSettings(t, "dice")
+
+	for _, direction := range []string{"under", "over"} {
+		req := &services.PlayRequest{
+			GameType:  "dice",
+			BetAmount: models.MoneyFromMajor(1),
+			Choices:   map[string]interface{}{"target": 50.0, "direction": direction},
+		}
+		game, err := engine.Play(req, settings, &sequenceSource{values: []float64{0.50005}})
+		if err != nil {
+			t.Fatalf("Play: %v", err)
+		}
+		if game.ResultData["roll"] != 50.0 || game.ResultData["won"] != false {
+			t.Errorf("%s 50: rolled %v, won %v, want 50 and a loss", direction, game.ResultData["roll"], game.ResultData["won"])
+		}
+	}
+}
+
+// TestDiceRejectsChoices checks bad targets and directions are refused as
+// invalid choices
+func TestDiceRejectsChoices(t *testing.T) {
+	engine := &services.DiceEngine{}
+	settings := defaultSettings(t, "dice")
+
+	tests := []struct {
+		name    string
+		choices map[string]interface{}
+	}{
+		{"below min_target", map[string]interface{}{"target": 0.99, "direction": "under"}},
+		{"three decimals", map[string]interface{}{"target": 50.005, "direction": "under"}},
+		{"unknown direction", map[string]interface{}{"target": 50.0, "direction": "sideways"}},
+		{"no target", map[string]interface{}{"direction": "under"}},
+		{"multiplier of 1 or less", map[string]interface{}{"target": 99.5, "direction": "under"}},
+	}
+	for _, tt := range tests {
+		req := &services.PlayRequest{GameType: "dice", BetAmount: models.MoneyFromMajor(1), Choices: tt.choices}
+		if _, err := engine.Play(req, settings, &sequenceSource{values: []float64{0.5}}); !errors.Is(err, services.ErrInvalidChoice) {
+			t.Errorf("%s: Play = %v, want ErrInvalidChoice", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package services
+
+import "betting-app-backend-go/models"
+
+// Unexported helpers used by the tests in services_test
+var (
+	PeriodStart = periodStart
+	PeriodEnd   = periodEnd
+
+	MinesMultiplier = minesMultiplier
+	HiLoFactor      = hiloFactor
+)
+
+// SlotLinePay evaluates one payline showing symbols on a slot config
+func SlotLinePay(cfg *models.SlotConfig, symbols []string) (string, int, float64) {
+	m, err := newSlotMachine(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return m.linePay(symbols)
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"betting-app-backend-go/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrSeedNotRevealed is returned when verification needs a server seed that
+// is still committed (active) and therefore secret.
+var ErrSeedNotRevealed = errors.New("server seed has not been revealed yet, rotate your seed pair first")
+
+// ErrSeedRotating is returned when the seed pair is being rotated by
+// another request
+var ErrSeedRotating = errors.New("seed pair is being rotated, try again")
+
+const maxClientSeedLength = 64
+
+// rotationLease is how long a rotation may hold a seed pair before it is
+// considered abandoned, e.g. by a crashed instance
+const rotationLease = 30 * time.Second
+
+// FairService manages provably-fair seed pairs (server seed, client seed,
+// nonce) per user.
+type FairService struct {
+	collection *mongo.Collection
+	sessions   *mongo.Collection
+}
+
+func NewFairService(db *mongo.Database) *FairService {
+	return &FairService{
+		collection: db.Collection("fair_seeds"),
+		sessions:   db.Collection("game_sessions"),
+	}
+}
+
+// GetActiveSeed returns the user's active seed pair, creating one if needed
+func (s *FairService) GetActiveSeed(ctx context.Context, userID string) (*models.SeedPair, error) {
+	var pair models.SeedPair
+	err := s.collection.FindOne(ctx, bson.M{"user_id": userID, "active": true}).Decode(&pair)
+	if err == nil {
+		return &pair, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to get seed pair: %w", err)
+	}
+
+	created, err := newSeedPair(userID, "")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.collection.InsertOne(ctx, created); err != nil {
+		// Another request created the pair first
+		if mongo.IsDuplicateKeyError(err) {
+			return s.GetActiveSeed(ctx, userID)
+		}
+		return nil, fmt.Errorf("failed to create seed pair: %w", err)
+	}
+	return created, nil
+}
+
+// NextRound reserves the next nonce of the user's active seed pair. The
+// returned pair carries the nonce to use for this round.
+func (s *FairService) NextRound(ctx context.Context, userID string) (*models.SeedPair, error) {
+	if _, err := s.GetActiveSeed(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	var pair models.SeedPair
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	err := s.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"user_id": userID, "active": true},
+		bson.M{"$inc": bson.M{"nonce": 1}},
+		opts,
+	).Decode(&pair)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve nonce: %w", err)
+	}
+	return &pair, nil
+}
+
+// RotateSeed reveals the active server seed and commits to a new one. An
+// empty clientSeed keeps the current client seed.
+//
+// Revealing the server seed would give away the rest of a session still in
+// play, so the pair is first marked as rotating and only then checked for
+// open sessions. A session started concurrently either exists by the time
+// of that check, or sees the mark and is cancelled (see Committed).
+func (s *FairService) RotateSeed(ctx context.Context, userID string, clientSeed string) (revealed *models.SeedPair, next *models.SeedPair, err error) {
+	if len(clientSeed) > maxClientSeedLength {
+		return nil, nil, fmt.Errorf("%w: client seed must be at most %d characters", ErrInvalidChoice, maxClientSeedLength)
+	}
+
+	current, err := s.GetActiveSeed(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if clientSeed == "" {
+		clientSeed = current.ClientSeed
+	}
+
+	now := time.Now()
+	until := now.Add(rotationLease)
+	res, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": current.ID, "active": true, "$or": bson.A{
+			bson.M{"rotating_until": bson.M{"$exists": false}},
+			bson.M{"rotating_until": bson.M{"$lt": now}},
+		}},
+		bson.M{"$set": bson.M{"rotating_until": until}},
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to mark seed pair as rotating: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return nil, nil, ErrSeedRotating
+	}
+
+	open, err := s.sessions.CountDocuments(ctx, bson.M{"user_id": userID, "status": bson.M{"$in": bson.A{"active", "settling"}}})
+	if err == nil && open > 0 {
+		err = ErrSessionActive
+	}
+	if err != nil {
+		if _, unmarkErr := s.collection.UpdateOne(ctx, bson.M{"_id": current.ID, "rotating_until": until}, bson.M{"$unset": bson.M{"rotating_until": ""}}); unmarkErr != nil {
+			log.Printf("[Fair] ❌ Failed to unmark seed pair %s: %v\n", current.ID, unmarkErr)
+		}
+		if err == ErrSessionActive {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("failed to check game sessions: %w", err)
+	}
+
+	var old models.SeedPair
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = s.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": current.ID, "active": true, "rotating_until": until},
+		bson.M{
+			"$set":   bson.M{"active": false, "revealed_at": time.Now()},
+			"$unset": bson.M{"rotating_until": ""},
+		},
+		opts,
+	).Decode(&old)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil, ErrSeedRotating
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to reveal seed pair: %w", err)
+	}
+
+	next, err = newSeedPair(userID, clientSeed)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := s.collection.InsertOne(ctx, next); err != nil {
+		return nil, nil, fmt.Errorf("failed to create seed pair: %w", err)
+	}
+
+	return &old, next, nil
+}
+
+// Committed reports whether the seed pair with serverSeedHash is still
+// active and not being rotated, i.e. its server seed stays secret. A
+// session checks this after it is stored: from then on a rotation sees the
+// session and refuses to reveal the seed.
+func (s *FairService) Committed(ctx context.Context, serverSeedHash string) (bool, error) {
+	count, err := s.collection.CountDocuments(ctx, bson.M{
+		"server_seed_hash": serverSeedHash,
+		"active":           true,
+		"rotating_until":   bson.M{"$not": bson.M{"$gte": time.Now()}},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to get seed pair: %w", err)
+	}
+	return count > 0, nil
+}
+
+// GetSeedPairByHash looks up a seed pair by its committed server seed hash
+func (s *FairService) GetSeedPairByHash(ctx context.Context, serverSeedHash string) (*models.SeedPair, error) {
+	var pair models.SeedPair
+	err := s.collection.FindOne(ctx, bson.M{"server_seed_hash": serverSeedHash}).Decode(&pair)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("seed pair not found")
+		}
+		return nil, fmt.Errorf("failed to get seed pair: %w", err)
+	}
+	return &pair, nil
+}
+
+// GetRevealedSeeds lists the user's previous (revealed) seed pairs
+func (s *FairService) GetRevealedSeeds(ctx context.Context, userID string, limit int64) ([]models.SeedPair, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "revealed_at", Value: -1}}).
+		SetLimit(limit)
+
+	cursor, err := s.collection.Find(ctx, bson.M{"user_id": userID, "active": false}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seed pairs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var pairs []models.SeedPair
+	if err = cursor.All(ctx, &pairs); err != nil {
+		return nil, fmt.Errorf("failed to decode seed pairs: %w", err)
+	}
+	return pairs, nil
+}
+
+// HashServerSeed returns the commitment published for a server seed
+func HashServerSeed(serverSeed string) string {
+	sum := sha256.Sum256([]byte(serverSeed))
+	return hex.EncodeToString(sum[:])
+}
+
+func newSeedPair(userID string, clientSeed string) (*models.SeedPair, error) {
+	serverSeed, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	if clientSeed == "" {
+		if clientSeed, err = randomHex(8); err != nil {
+			return nil, err
+		}
+	}
+	return &models.SeedPair{
+		ID:             fmt.Sprintf("seed_%d", time.Now().UnixNano()),
+		UserID:         userID,
+		ServerSeed:     serverSeed,
+		ServerSeedHash: HashServerSeed(serverSeed),
+		ClientSeed:     clientSeed,
+		Nonce:          0,
+		Active:         true,
+		CreatedAt:      time.Now(),
+	}, nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate seed: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// fairSource derives a deterministic stream of floats from a seed pair:
+// HMAC-SHA256(serverSeed, "clientSeed:nonce:cursor") yields 32 bytes, and
+// every 4 bytes become one float in [0, 1). The cursor increments whenever
+// a block is used up, so a round may draw as many floats as it needs.
+type fairSource struct {
+	serverSeed string
+	clientSeed string
+	nonce      int64
+	cursor     int
+	block      []byte
+}
+
+func newFairSource(serverSeed, clientSeed string, nonce int64) *fairSource {
+	return &fairSource{serverSeed: serverSeed, clientSeed: clientSeed, nonce: nonce}
+}
+
+func (f *fairSource) Float64() float64 {
+	if len(f.block) < 4 {
+		mac := hmac.New(sha256.New, []byte(f.serverSeed))
+		fmt.Fprintf(mac, "%s:%d:%d", f.clientSeed, f.nonce, f.cursor)
+		f.block = mac.Sum(nil)
+		f.cursor++
+	}
+	b := f.block[:4]
+	f.block = f.block[4:]
+
+	result := 0.0
+	divisor := 1.0
+	for _, v := range b {
+		divisor *= 256
+		result += float64(v) / divisor
+	}
+	return result
+}
@@ -0,0 +1,449 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"betting-app-backend-go/models"
+)
+
+// GameTypes lists every game the platform knows about
+var GameTypes = []string{
+	"aviation", "spinwheel", "slot", "mines", "plinko", "dice", "limbo", "hilo", "blackjack",
+}
+
+// IsValidGameType reports whether gameType is a known game
+func IsValidGameType(gameType string) bool {
+	for _, t := range GameTypes {
+		if t == gameType {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldError describes a single invalid field of a settings update
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects every field-level problem found in a settings
+// update so admins can fix them all at once.
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Field + ": " + f.Message
+	}
+	return "invalid game settings: " + strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) add(field, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// errOrNil returns e as an error only when it holds problems
+func (e *ValidationError) errOrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// newGameConfig returns a pointer to the typed config struct for gameType
+func newGameConfig(gameType string) (interface{}, bool) {
+	switch gameType {
+	case "spinwheel":
+		return &models.SpinWheelConfig{}, true
+	case "aviation":
+		return &models.AviationConfig{}, true
+	case "slot":
+		return &models.SlotConfig{}, true
+	case "mines":
+		return &models.MinesConfig{}, true
+	case "plinko":
+		return &models.PlinkoConfig{}, true
+	case "dice":
+		return &models.DiceConfig{}, true
+	case "limbo":
+		return &models.LimboConfig{}, true
+	case "hilo":
+		return &models.HiLoConfig{}, true
+	case "blackjack":
+		return &models.BlackjackConfig{}, true
+	}
+	return nil, false
+}
+
+// DecodeGameConfig strictly decodes a GameSettings.Config map into the typed
+// config struct of the game (e.g. *models.DiceConfig). Unknown fields and
+// wrongly typed values are reported as a *ValidationError.
+func DecodeGameConfig(gameType string, config map[string]interface{}) (interface{}, error) {
+	cfg, ok := newGameConfig(gameType)
+	if !ok {
+		return nil, fmt.Errorf("unknown game type: %s", gameType)
+	}
+
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		verr := &ValidationError{}
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &typeErr):
+			verr.add("config."+typeErr.Field, "expected %s", typeErr.Type.String())
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+			verr.add("config."+field, "unknown field")
+		default:
+			verr.add("config", "%v", err)
+		}
+		return nil, verr
+	}
+
+	return cfg, nil
+}
+
+// ValidateGameSettings checks the common limits and the game-specific config
+// of a settings update. It returns a *ValidationError listing every problem.
+func ValidateGameSettings(gameType string, settings *models.GameSettings) error {
+	verr := &ValidationError{}
+
+	if !IsValidGameType(gameType) {
+		verr.add("game_type", "unknown game type %q", gameType)
+		return verr
+	}
+
+	if settings.MinBet <= 0 {
+		verr.add("min_bet", "must be greater than 0")
+	}
+	if settings.MaxBet < settings.MinBet {
+		verr.add("max_bet", "must be greater than or equal to min_bet")
+	}
+	if settings.HouseEdge < 0 || settings.HouseEdge >= 100 {
+		verr.add("house_edge", "must be between 0 and 100")
+	}
+
+	cfg, err := DecodeGameConfig(gameType, settings.Config)
+	if err != nil {
+		var cfgErr *ValidationError
+		if errors.As(err, &cfgErr) {
+			verr.Fields = append(verr.Fields, cfgErr.Fields...)
+			return verr
+		}
+		return err
+	}
+
+	validateConfig(verr, cfg, settings.HouseEdge)
+	return verr.errOrNil()
+}
+
+// colorPattern matches a "#RRGGBB" colour
+var colorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// returnTolerance is how far the expected return of a payout table may be
+// from 1 - house_edge (0.005 = half a percentage point)
+const returnTolerance = 0.005
+
+// validateConfig applies the semantic rules of each game's config.
+// houseEdge is the settings-level edge in percent.
+func validateConfig(verr *ValidationError, cfg interface{}, houseEdge float64) {
+	switch c := cfg.(type) {
+	case *models.SpinWheelConfig:
+		if len(c.Multipliers) < 2 {
+			verr.add("config.multipliers", "must have at least 2 segments")
+		}
+		for i, m := range c.Multipliers {
+			if m < 0 {
+				verr.add(fmt.Sprintf("config.multipliers[%d]", i), "must not be negative")
+			}
+		}
+		weightsOK := true
+		if len(c.Weights) > 0 && len(c.Weights) != len(c.Multipliers) {
+			verr.add("config.weights", "must have one weight per segment (%d)", len(c.Multipliers))
+			weightsOK = false
+		}
+		for i, w := range c.Weights {
+			if w < 1 {
+				verr.add(fmt.Sprintf("config.weights[%d]", i), "must be at least 1")
+				weightsOK = false
+			}
+		}
+		if len(c.Colors) > 0 && len(c.Colors) != len(c.Multipliers) {
+			verr.add("config.colors", "must have one colour per segment (%d)", len(c.Multipliers))
+		}
+		for i, color := range c.Colors {
+			if !colorPattern.MatchString(color) {
+				verr.add(fmt.Sprintf("config.colors[%d]", i), "must be a #RRGGBB colour")
+			}
+		}
+		if weightsOK && len(c.Multipliers) > 0 {
+			ev := spinWheelExpectedReturn(c)
+			if target := 1 - houseEdge/100; ev > target+1e-9 {
+				verr.add("config.multipliers", "expected return is %.2f%%, must be at most %.2f%% (100 - house_edge)",
+					ev*100, target*100)
+			}
+		}
+
+	case *models.AviationConfig:
+		if c.MinMultiplier < 1 {
+			verr.add("config.min_multiplier", "must be at least 1")
+		}
+		if c.MaxMultiplier <= c.MinMultiplier {
+			verr.add("config.max_multiplier", "must be greater than min_multiplier")
+		}
+		chances := map[string]float64{
+			"low": c.CrashChances.Low, "medium": c.CrashChances.Medium,
+			"high": c.CrashChances.High, "very_high": c.CrashChances.VeryHigh,
+		}
+		sum := 0.0
+		for _, name := range []string{"low", "medium", "high", "very_high"} {
+			if chances[name] < 0 {
+				verr.add("config.crash_chances."+name, "must not be negative")
+			}
+			sum += chances[name]
+		}
+		if math.Abs(sum-100) > 1e-9 {
+			verr.add("config.crash_chances", "must sum to 100 (got %g)", sum)
+		} else if c.MinMultiplier >= 1 && c.MaxMultiplier > c.MinMultiplier {
+			ev, at := aviationBestReturn(c, houseEdge)
+			if target := 1 - houseEdge/100; ev > target+returnTolerance {
+				verr.add("config.crash_chances", "cashing out at %.2fx returns %.2f%%, must be at most %.2f%% (100 - house_edge) within %.1f points",
+					at, ev*100, target*100, returnTolerance*100)
+			}
+		}
+
+	case *models.SlotConfig:
+		before := len(verr.Fields)
+		if len(c.Symbols) == 0 {
+			verr.add("config.symbols", "must not be empty")
+		}
+		known := make(map[string]bool, len(c.Symbols))
+		for _, sym := range c.Symbols {
+			if known[sym] {
+				verr.add("config.symbols", "duplicate symbol %q", sym)
+			}
+			known[sym] = true
+		}
+		for sym, m := range c.Multipliers {
+			if !known[sym] {
+				verr.add("config.multipliers."+sym, "symbol is not in symbols")
+			}
+			if m < 0 {
+				verr.add("config.multipliers."+sym, "must not be negative")
+			}
+		}
+
+		reels, rows := c.Reels, c.Rows
+		if reels == 0 {
+			reels = 3
+		}
+		if rows == 0 {
+			rows = 1
+		}
+		if reels < 3 || reels > 7 {
+			verr.add("config.reels", "must be between 3 and 7")
+		}
+		if rows < 1 || rows > 5 {
+			verr.add("config.rows", "must be between 1 and 5")
+		}
+		if len(c.ReelStrips) > 0 && len(c.ReelStrips) != reels {
+			verr.add("config.reel_strips", "must have one strip per reel (%d)", reels)
+		}
+		for i, strip := range c.ReelStrips {
+			field := fmt.Sprintf("config.reel_strips[%d]", i)
+			if len(strip) == 0 {
+				verr.add(field, "must not be empty")
+			}
+			for j, stop := range strip {
+				if !known[stop.Symbol] {
+					verr.add(fmt.Sprintf("%s[%d].symbol", field, j), "symbol %q is not in symbols", stop.Symbol)
+				}
+				if stop.Weight < 1 {
+					verr.add(fmt.Sprintf("%s[%d].weight", field, j), "must be at least 1")
+				}
+			}
+		}
+		for sym, pays := range c.Paytable {
+			field := "config.paytable." + sym
+			if !known[sym] {
+				verr.add(field, "symbol is not in symbols")
+			}
+			if sym == c.Scatter {
+				verr.add(field, "the scatter pays through scatter_pays")
+			}
+			if len(pays) != reels {
+				verr.add(field, "must have one multiplier for each of 1-%d in a row", reels)
+			}
+			for _, m := range pays {
+				if m < 0 {
+					verr.add(field, "must not be negative")
+					break
+				}
+			}
+		}
+		for i, line := range c.Paylines {
+			field := fmt.Sprintf("config.paylines[%d]", i)
+			if len(line) != reels {
+				verr.add(field, "must have one row per reel (%d)", reels)
+			}
+			for _, row := range line {
+				if row < 0 || row >= rows {
+					verr.add(field, "rows must be between 0 and %d", rows-1)
+					break
+				}
+			}
+		}
+		if c.Wild != "" && !known[c.Wild] {
+			verr.add("config.wild", "symbol %q is not in symbols", c.Wild)
+		}
+		if c.Scatter != "" && !known[c.Scatter] {
+			verr.add("config.scatter", "symbol %q is not in symbols", c.Scatter)
+		}
+		if c.Wild != "" && c.Wild == c.Scatter {
+			verr.add("config.scatter", "must differ from wild")
+		}
+		if len(c.ScatterPays) > 0 {
+			if c.Scatter == "" {
+				verr.add("config.scatter_pays", "requires a scatter symbol")
+			}
+			if len(c.ScatterPays) > reels*rows {
+				verr.add("config.scatter_pays", "must have at most %d entries, one per scatter in view", reels*rows)
+			}
+			for _, m := range c.ScatterPays {
+				if m < 0 {
+					verr.add("config.scatter_pays", "must not be negative")
+					break
+				}
+			}
+		}
+		if c.FreeSpinsTrigger != 0 || c.FreeSpins != 0 {
+			if c.Scatter == "" {
+				verr.add("config.free_spins_trigger", "requires a scatter symbol")
+			}
+			if c.FreeSpinsTrigger < 1 || c.FreeSpinsTrigger > reels*rows {
+				verr.add("config.free_spins_trigger", "must be between 1 and %d", reels*rows)
+			}
+			if c.FreeSpins < 1 || c.FreeSpins > maxSlotFreeSpins {
+				verr.add("config.free_spins", "must be between 1 and %d", maxSlotFreeSpins)
+			}
+		}
+		// The return is only worked out for a machine that is otherwise valid
+		if len(verr.Fields) == before {
+			if machine, err := newSlotMachine(c); err == nil {
+				ev := machine.expectedReturn()
+				if target := 1 - houseEdge/100; ev > target+returnTolerance {
+					verr.add("config.paytable", "expected return is %.2f%%, must be at most %.2f%% (100 - house_edge) within %.1f points",
+						ev*100, target*100, returnTolerance*100)
+				}
+			}
+		}
+
+	case *models.MinesConfig:
+		if c.GridSize < 2 || c.GridSize > 10 {
+			verr.add("config.grid_size", "must be between 2 and 10")
+		}
+		cells := c.GridSize * c.GridSize
+		if c.MinMines < 1 {
+			verr.add("config.min_mines", "must be at least 1")
+		}
+		if c.MaxMines <= c.MinMines {
+			verr.add("config.max_mines", "must be greater than min_mines")
+		}
+		if c.MaxMines >= cells {
+			verr.add("config.max_mines", "must be less than the %d grid cells", cells)
+		}
+		if c.DefaultMines < c.MinMines || c.DefaultMines > c.MaxMines {
+			verr.add("config.default_mines", "must be between min_mines and max_mines")
+		}
+		if c.MultiplierBase <= 0 || c.MultiplierBase > 0.5 {
+			verr.add("config.multiplier_base", "must be greater than 0 and at most 0.5")
+		}
+
+	case *models.PlinkoConfig:
+		if c.Rows < 8 || c.Rows > 16 {
+			verr.add("config.rows", "must be between 8 and 16")
+		}
+		tables := map[string][]float64{
+			"multipliers_low": c.MultipliersLow, "multipliers_medium": c.MultipliersMedium,
+			"multipliers_high": c.MultipliersHigh,
+		}
+		for _, name := range []string{"multipliers_low", "multipliers_medium", "multipliers_high"} {
+			if len(tables[name]) != c.Rows+1 {
+				verr.add("config."+name, "must have rows+1 (%d) entries, got %d", c.Rows+1, len(tables[name]))
+			}
+			for i, m := range tables[name] {
+				if m < 0 {
+					verr.add(fmt.Sprintf("config.%s[%d]", name, i), "must not be negative")
+				}
+			}
+			if len(tables[name]) != c.Rows+1 {
+				continue
+			}
+			ev := plinkoExpectedReturn(tables[name])
+			if target := 1 - houseEdge/100; math.Abs(ev-target) > returnTolerance {
+				verr.add("config."+name, "expected return is %.2f%%, must be within %.1f points of %.2f%% (100 - house_edge)",
+					ev*100, returnTolerance*100, target*100)
+			}
+		}
+
+	case *models.DiceConfig:
+		if c.MinTarget <= 0 {
+			verr.add("config.min_target", "must be greater than 0")
+		}
+		if c.MaxTarget >= 100 {
+			verr.add("config.max_target", "must be less than 100")
+		}
+		if c.MaxTarget <= c.MinTarget {
+			verr.add("config.max_target", "must be greater than min_target")
+		}
+		if c.HouseEdge < 0 || c.HouseEdge >= 100 {
+			verr.add("config.house_edge", "must be between 0 and 100")
+		}
+
+	case *models.LimboConfig:
+		// Rolls never go below 1.00, so a 1.00 target would always win
+		if c.MinMultiplier < 1.01 {
+			verr.add("config.min_multiplier", "must be at least 1.01")
+		}
+		if c.MaxMultiplier <= c.MinMultiplier {
+			verr.add("config.max_multiplier", "must be greater than min_multiplier")
+		}
+		if c.HouseEdge < 0 || c.HouseEdge >= 100 {
+			verr.add("config.house_edge", "must be between 0 and 100")
+		}
+
+	case *models.HiLoConfig:
+		if c.MultiplierPerWin <= 1 {
+			verr.add("config.multiplier_per_win", "must be greater than 1")
+		}
+		if c.MaxStreak < 1 {
+			verr.add("config.max_streak", "must be at least 1")
+		}
+
+	case *models.BlackjackConfig:
+		if c.NumDecks < 1 || c.NumDecks > 8 {
+			verr.add("config.num_decks", "must be between 1 and 8")
+		}
+		if c.WinPayout <= 1 {
+			verr.add("config.win_payout", "must be greater than 1")
+		}
+		if c.BlackjackPayout < c.WinPayout {
+			verr.add("config.blackjack_payout", "must be at least win_payout")
+		}
+	}
+}
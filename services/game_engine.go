@@ -0,0 +1,141 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"betting-app-backend-go/models"
+)
+
+// ErrNoEngine is returned when a game type has no server-side engine and
+// therefore cannot be played through RecordGame.
+var ErrNoEngine = errors.New("game is not available for play")
+
+// ErrInvalidChoice wraps errors caused by bad player input (unknown risk
+// level, target out of range, ...). Handlers map it to 400.
+var ErrInvalidChoice = errors.New("invalid choice")
+
+// PlayRequest carries everything the player controls in a round: the stake
+// and any game-specific choices. Outcomes are never part of it.
+type PlayRequest struct {
+	UserID    string
+	GameType  string
+	BetAmount models.Money
+	Choices   map[string]interface{}
+
+	// IdempotencyKey, when set, makes a retried request return the round
+	// already played with the same key instead of playing a new one
+	IdempotencyKey string
+}
+
+// RandomSource yields uniformly distributed floats in [0, 1).
+type RandomSource interface {
+	Float64() float64
+}
+
+// GameEngine settles a single round of one game type on the server.
+// Implementations must derive every random outcome from rng and must not
+// touch the database; GameService takes care of the wallet and persistence.
+type GameEngine interface {
+	GameType() string
+	Play(req *PlayRequest, settings *models.GameSettings, rng RandomSource) (*models.Game, error)
+}
+
+// expectedReturner is implemented by engines that can work out the average
+// payout per unit bet of a config. For the others it is taken to be
+// 1 - house edge.
+type expectedReturner interface {
+	ExpectedReturn(settings *models.GameSettings) (float64, error)
+}
+
+// defaultEngines returns the engines registered on every GameService
+func defaultEngines() []GameEngine {
+	return []GameEngine{
+		&SpinWheelEngine{},
+		&SlotEngine{},
+		&DiceEngine{},
+		&PlinkoEngine{},
+		&LimboEngine{},
+	}
+}
+
+// decodeConfig decodes a GameSettings.Config map into one of the typed
+// config structs from the models package.
+func decodeConfig(config map[string]interface{}, out interface{}) error {
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("failed to decode config: %w", err)
+	}
+	return nil
+}
+
+// settle fills in the payout fields of a round from its multiplier, using
+// the rounding rules of models.Money
+func settle(req *PlayRequest, multiplier float64, resultData map[string]interface{}) *models.Game {
+	multiplier = models.RoundMultiplier(multiplier)
+	return &models.Game{
+		GameType:   req.GameType,
+		BetAmount:  req.BetAmount,
+		WinAmount:  req.BetAmount.MulMultiplier(multiplier),
+		Multiplier: multiplier,
+		ResultData: resultData,
+	}
+}
+
+// pickIndex returns a uniformly chosen index in [0, n)
+func pickIndex(rng RandomSource, n int) int {
+	i := int(rng.Float64() * float64(n))
+	if i >= n {
+		i = n - 1
+	}
+	return i
+}
+
+// choiceString reads a string choice sent by the player
+func choiceString(choices map[string]interface{}, key string) (string, error) {
+	v, ok := choices[key]
+	if !ok {
+		return "", fmt.Errorf("%w: %s is required", ErrInvalidChoice, key)
+	}
+	str, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: %s must be a string", ErrInvalidChoice, key)
+	}
+	return str, nil
+}
+
+// choiceFloat reads a numeric choice sent by the player
+func choiceFloat(choices map[string]interface{}, key string) (float64, error) {
+	v, ok := choices[key]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s is required", ErrInvalidChoice, key)
+	}
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case int:
+		return float64(n), nil
+	case int32:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	}
+	return 0, fmt.Errorf("%w: %s must be a number", ErrInvalidChoice, key)
+}
+
+// choiceInt reads a whole-number choice sent by the player
+func choiceInt(choices map[string]interface{}, key string) (int, error) {
+	f, err := choiceFloat(choices, key)
+	if err != nil {
+		return 0, err
+	}
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("%w: %s must be a whole number", ErrInvalidChoice, key)
+	}
+	return int(f), nil
+}
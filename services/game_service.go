@@ -2,7 +2,11 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"reflect"
 	"time"
 
 	"betting-app-backend-go/models"
@@ -12,102 +16,478 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// settleInterval is how often Run looks for unsettled games, and
+// settleGrace how long a game may be unsettled before it is considered
+// abandoned by the request that played it
+const (
+	settleInterval = time.Minute
+	settleGrace    = 2 * time.Minute
+)
+
+// Errors returned when a bet is rejected by the game's settings
+var (
+	ErrGameDisabled    = errors.New("game disabled")
+	ErrBetBelowMinimum = errors.New("bet below minimum")
+	ErrBetAboveMaximum = errors.New("bet above maximum")
+)
+
 type GameService struct {
-	db            *mongo.Database
-	walletService *WalletService
+	db              *mongo.Database
+	walletService   *WalletService
+	settingsService *GameSettingsService
+	fairService     *FairService
+	engines         map[string]GameEngine
+	
+	// playedElsewhere maps game types played outside RecordGame to the
+	// endpoint that plays them, and replayers to what replays them
+	playedElsewhere map[string]string
+	replayers       map[string]gameReplayer
+}
+
+// gameReplayer recomputes games played outside RecordGame for VerifyGame
+type gameReplayer interface {
+	// revealedSeed returns the server seed a game was played with, or
+	// ErrSeedNotRevealed while it is still secret
+	revealedSeed(ctx context.Context, game *models.Game) (string, error)
+	replay(game *models.Game, settings *models.GameSettings, serverSeed string) (*models.Game, error)
+}
+
+func NewGameService(db *mongo.Database, walletService *WalletService, settingsService *GameSettingsService, fairService *FairService) *GameService {
+	s := &GameService{
+		db:              db,
+		walletService:   walletService,
+		settingsService: settingsService,
+		fairService:     fairService,
+		engines:         make(map[string]GameEngine),
+		playedElsewhere: make(map[string]string),
+		replayers:       make(map[string]gameReplayer),
+	}
+	for _, engine := range defaultEngines() {
+		s.RegisterEngine(engine)
+	}
+	return s
+}
+
+// RegisterEngine makes a game type playable through RecordGame
+func (s *GameService) RegisterEngine(engine GameEngine) {
+	s.engines[engine.GameType()] = engine
+}
+
+// registerElsewhere records that a game type is played through another
+// service's endpoint rather than RecordGame, and how its games are replayed
+func (s *GameService) registerElsewhere(gameType, endpoint string, replayer gameReplayer) {
+	s.playedElsewhere[gameType] = endpoint
+	s.replayers[gameType] = replayer
 }
 
-func NewGameService(db *mongo.Database, walletService *WalletService) *GameService {
-	return &GameService{
-		db:            db,
-		walletService: walletService,
+// UnservedGameTypes lists the known game types that no engine plays. Every
+// game must be settled on the server, so main refuses to start if any
+// game is listed.
+func (s *GameService) UnservedGameTypes() []string {
+	var unserved []string
+	for _, t := range GameTypes {
+		if _, ok := s.engines[t]; ok {
+			continue
+		}
+		if _, ok := s.playedElsewhere[t]; ok {
+			continue
+		}
+		unserved = append(unserved, t)
 	}
+	return unserved
 }
 
-// RecordGame records a game play with bet deduction and win credit
-func (s *GameService) RecordGame(ctx context.Context, game *models.Game) error {
-	if game.BetAmount <= 0 {
-		return fmt.Errorf("bet amount must be positive")
+// ExpectedRTP is the average payout per unit bet of a game's current
+// settings. Engines that can work it out from the config do; for the
+// other games it is 1 - house edge.
+func (s *GameService) ExpectedRTP(ctx context.Context, gameType string) (float64, error) {
+	settings, err := s.settingsService.GetCachedGameSettings(ctx, gameType)
+	if err != nil {
+		return 0, err
 	}
-	
-	// Validate game type
-	validGameTypes := map[string]bool{
-		"aviation": true, "spinwheel": true, "slot": true, "mines": true,
-		"plinko": true, "dice": true, "limbo": true, "hilo": true, "blackjack": true,
+	if engine, ok := s.engines[gameType].(expectedReturner); ok {
+		return engine.ExpectedReturn(settings)
+	}
+	return 1 - settings.HouseEdge/100, nil
+}
+
+// ExpectedRTPIsCeiling reports whether a game's return depends on how it
+// is played, so ExpectedRTP is only what the best play returns. That holds
+// for the games played outside RecordGame: sessions, where the player hits,
+// stands or cashes out, and aviation, where they pick the cash-out.
+func (s *GameService) ExpectedRTPIsCeiling(gameType string) bool {
+	_, elsewhere := s.playedElsewhere[gameType]
+	return elsewhere
+}
+
+// RecordGame plays a round on the server and settles it against the wallet.
+// The outcome is always produced by the game's engine; nothing the client
+// reports about the result is trusted.
+func (s *GameService) RecordGame(ctx context.Context, req *PlayRequest) (*models.Game, error) {
+	if req.BetAmount <= 0 {
+		return nil, fmt.Errorf("bet amount must be positive")
 	}
 	
-	if !validGameTypes[game.GameType] {
-		return fmt.Errorf("invalid game type: %s", game.GameType)
+	engine, ok := s.engines[req.GameType]
+	if !ok {
+		if endpoint, elsewhere := s.playedElsewhere[req.GameType]; elsewhere {
+			return nil, fmt.Errorf("%w: %s is played through %s", ErrNoEngine, req.GameType, endpoint)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrNoEngine, req.GameType)
 	}
 	
 	gamesCol := s.db.Collection("games")
-	usersCol := s.db.Collection("users")
 	
-	// Start transaction
-	session, err := s.db.Client().StartSession()
+	// A retried request returns the round it already played
+	if req.IdempotencyKey != "" {
+		existing, err := s.findGameByIdempotencyKey(ctx, req.UserID, req.IdempotencyKey)
+		if err != nil || existing != nil {
+			return existing, err
+		}
+	}
+	
+	settings, err := s.settingsService.GetCachedGameSettings(ctx, req.GameType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load game settings: %w", err)
+	}
+	if err := checkBetAgainstSettings(req.BetAmount, settings); err != nil {
+		return nil, err
+	}
+	
+	// Reserve the nonce for this round; the outcome is derived from the
+	// committed seed pair so the player can verify it after rotation.
+	seed, err := s.fairService.NextRound(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	
+	game, err := engine.Play(req, settings, newFairSource(seed.ServerSeed, seed.ClientSeed, seed.Nonce))
+	if err != nil {
+		return nil, err
+	}
+	game.UserID = req.UserID
+	game.SettingsVersion = settings.Version
+	game.ServerSeedHash = seed.ServerSeedHash
+	game.ClientSeed = seed.ClientSeed
+	game.Nonce = seed.Nonce
+	if len(req.Choices) > 0 {
+		game.ResultData["choices"] = req.Choices
+	}
+	
+	game.IdempotencyKey = req.IdempotencyKey
+	game.ID = fmt.Sprintf("game_%d", time.Now().UnixNano())
+	game.Settled = false
+	game.CreatedAt = time.Now()
+	
+	// Record the round unsettled before any money moves. With an
+	// idempotency key the unique (user_id, idempotency_key) index makes this
+	// insert the reservation, so a duplicate request can never be charged.
+	_, err = gamesCol.InsertOne(ctx, game)
 	if err != nil {
-		return fmt.Errorf("failed to start session: %w", err)
+		if req.IdempotencyKey != "" && mongo.IsDuplicateKeyError(err) {
+			return s.findGameByIdempotencyKey(ctx, req.UserID, req.IdempotencyKey)
+		}
+		return nil, fmt.Errorf("failed to insert game: %w", err)
+	}
+	
+	// Each wallet change is a single atomic update, so no multi-document
+	// transaction is needed
+	err = s.walletService.DeductBalance(
+		ctx,
+		game.UserID,
+		game.BetAmount,
+		fmt.Sprintf("%s game bet", game.GameType),
+		"game_loss",
+		game.ID,
+		game.CreatedAt,
+	)
+	if err != nil {
+		// Drop the reserved round only when the bet was refused. Any other
+		// error may have come after the stake was taken; the round is then
+		// left for settleStale, which pays it out or drops it depending on
+		// whether the stake is in the wallet.
+		if errors.Is(err, ErrInsufficientBalance) || errors.Is(err, ErrLimitExceeded) {
+			if _, delErr := gamesCol.DeleteOne(ctx, bson.M{"_id": game.ID, "settled": false}); delErr != nil {
+				log.Printf("[Game] ❌ Failed to remove unplayed game %s: %v\n", game.ID, delErr)
+			}
+		}
+		return nil, fmt.Errorf("failed to deduct bet: %w", err)
+	}
+	
+	// If there's a win, credit the wallet. A game whose win could not be
+	// credited stays unsettled and is paid out by Run.
+	if err := s.payOut(ctx, game); err != nil {
+		log.Printf("[Game] ❌ Failed to credit winnings for game %s: %v\n", game.ID, err)
+		return nil, err
 	}
-	defer session.EndSession(ctx)
 	
-	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
-		// Deduct bet amount from wallet
-		err := s.walletService.DeductBalance(
-			sessCtx,
+	return game, nil
+}
+
+// payOut credits a game's win under the game's ID, so it is paid at most
+// once however often it is retried, then marks the game settled. The win
+// is released from the player's limits at the game's CreatedAt, when its
+// stake was taken.
+func (s *GameService) payOut(ctx context.Context, game *models.Game) error {
+	if game.WinAmount > 0 {
+		err := s.walletService.CreditBalance(
+			ctx,
 			game.UserID,
-			game.BetAmount,
-			fmt.Sprintf("%s game bet", game.GameType),
-			"game_loss",
+			game.WinAmount,
+			fmt.Sprintf("%s game win", game.GameType),
+			"game_win",
+			game.ID,
+			game.CreatedAt,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to deduct bet: %w", err)
+			return fmt.Errorf("failed to credit winnings: %w", err)
 		}
-		
-		// If there's a win, credit the wallet
-		if game.WinAmount > 0 {
-			err = s.walletService.CreditBalance(
-				sessCtx,
-				game.UserID,
-				game.WinAmount,
-				fmt.Sprintf("%s game win", game.GameType),
-				"game_win",
-			)
+	}
+	
+	res, err := s.db.Collection("games").UpdateOne(ctx, bson.M{"_id": game.ID, "settled": false}, bson.M{"$set": bson.M{"settled": true}})
+	if err != nil {
+		return fmt.Errorf("failed to settle game: %w", err)
+	}
+	game.Settled = true
+	
+	// Only whoever settled the game counts it in the player's stats
+	if res.ModifiedCount > 0 {
+		s.updateUserStats(ctx, game)
+	}
+	return nil
+}
+
+// Run pays out games left unsettled by a failed credit, every
+// settleInterval until ctx is cancelled. It runs on every instance; a game
+// is never paid twice.
+func (s *GameService) Run(ctx context.Context) {
+	ticker := time.NewTicker(settleInterval)
+	defer ticker.Stop()
+	
+	for {
+		s.settleStale(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// settleStale pays out every game that has been unsettled for longer than
+// settleGrace. A round played here whose stake was never taken is dropped
+// instead, just as RecordGame drops it when the bet is refused. Sessions and
+// aviation only record a game once the stake is taken.
+func (s *GameService) settleStale(ctx context.Context) {
+	gamesCol := s.db.Collection("games")
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetLimit(100)
+	cursor, err := gamesCol.Find(ctx, bson.M{"settled": false, "created_at": bson.M{"$lte": time.Now().Add(-settleGrace)}}, opts)
+	if err != nil {
+		log.Printf("[Game] ❌ Failed to find unsettled games: %v\n", err)
+		return
+	}
+	var games []models.Game
+	if err := cursor.All(ctx, &games); err != nil {
+		log.Printf("[Game] ❌ Failed to decode unsettled games: %v\n", err)
+		return
+	}
+	
+	for i := range games {
+		game := &games[i]
+		if _, ok := s.engines[game.GameType]; ok {
+			charged, err := s.walletService.Applied(ctx, game.UserID, "game_loss", game.ID)
 			if err != nil {
-				return nil, fmt.Errorf("failed to credit winnings: %w", err)
+				log.Printf("[Game] ❌ Failed to check the stake of game %s: %v\n", game.ID, err)
+				continue
+			}
+			if !charged {
+				if _, err := gamesCol.DeleteOne(ctx, bson.M{"_id": game.ID, "settled": false}); err != nil {
+					log.Printf("[Game] ❌ Failed to remove unplayed game %s: %v\n", game.ID, err)
+				}
+				continue
 			}
 		}
 		
-		// Record game in database
-		game.ID = fmt.Sprintf("game_%d", time.Now().UnixNano())
-		game.Settled = true
-		game.CreatedAt = time.Now()
-		
-		_, err = gamesCol.InsertOne(sessCtx, game)
-		if err != nil {
-			return nil, fmt.Errorf("failed to insert game: %w", err)
+		if err := s.payOut(ctx, game); err != nil {
+			log.Printf("[Game] ❌ Failed to settle game %s: %v\n", game.ID, err)
+			continue
 		}
-		
-		// Update user statistics
-		netProfit := game.WinAmount - game.BetAmount
-		_, err = usersCol.UpdateOne(
-			sessCtx,
-			bson.M{"uid": game.UserID},
-			bson.M{
-				"$inc": bson.M{
-					"total_games_played": 1,
-					"total_wagered":      game.BetAmount,
-					"total_won":          netProfit,
-				},
+		log.Printf("[Game] ✅ Settled game %s, paid %s\n", game.ID, game.WinAmount)
+	}
+}
+
+// updateUserStats adds a settled game to the player's totals
+func (s *GameService) updateUserStats(ctx context.Context, game *models.Game) {
+	netProfit := game.WinAmount - game.BetAmount
+	_, err := s.db.Collection("users").UpdateOne(
+		ctx,
+		bson.M{"uid": game.UserID},
+		bson.M{
+			"$inc": bson.M{
+				"total_games_played": 1,
+				"total_wagered":      game.BetAmount,
+				"total_won":          netProfit,
 			},
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to update user stats: %w", err)
-		}
-		
+		},
+	)
+	if err != nil {
+		// The round is settled; stats are informational only
+		log.Printf("[Game] ❌ Failed to update user stats for game %s: %v\n", game.ID, err)
+	}
+}
+
+// findGameByIdempotencyKey returns the game a user played with key, or nil
+func (s *GameService) findGameByIdempotencyKey(ctx context.Context, userID, key string) (*models.Game, error) {
+	var game models.Game
+	err := s.db.Collection("games").FindOne(ctx, bson.M{"user_id": userID, "idempotency_key": key}).Decode(&game)
+	if err == mongo.ErrNoDocuments {
 		return nil, nil
-	})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get game: %w", err)
+	}
+	return &game, nil
+}
+
+// checkBetAgainstSettings enforces the enabled flag and bet limits
+func checkBetAgainstSettings(betAmount models.Money, settings *models.GameSettings) error {
+	if !settings.Enabled {
+		return ErrGameDisabled
+	}
+	if settings.MinBet > 0 && betAmount < settings.MinBet {
+		return fmt.Errorf("%w: minimum bet is %s", ErrBetBelowMinimum, settings.MinBet)
+	}
+	if settings.MaxBet > 0 && betAmount > settings.MaxBet {
+		return fmt.Errorf("%w: maximum bet is %s", ErrBetAboveMaximum, settings.MaxBet)
+	}
+	return nil
+}
+
+// ComputeRound replays a round from explicit seeds and settings without
+// touching the wallet. It is the basis of provably-fair verification.
+func (s *GameService) ComputeRound(req *PlayRequest, settings *models.GameSettings, serverSeed, clientSeed string, nonce int64) (*models.Game, error) {
+	engine, ok := s.engines[req.GameType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoEngine, req.GameType)
+	}
+	
+	return engine.Play(req, settings, newFairSource(serverSeed, clientSeed, nonce))
+}
+
+// VerifyGame recomputes a recorded game from its revealed server seed and
+// what the player did (the choices stored in its ResultData, or the
+// actions of a session), and reports whether the outcome matches.
+func (s *GameService) VerifyGame(ctx context.Context, gameID string) (*models.FairVerification, error) {
+	var game models.Game
+	err := s.db.Collection("games").FindOne(ctx, bson.M{"_id": gameID}).Decode(&game)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("game not found")
+		}
+		return nil, fmt.Errorf("failed to get game: %w", err)
+	}
+	if game.ServerSeedHash == "" {
+		return nil, fmt.Errorf("game was not played with a provably-fair seed")
+	}
+	
+	replayer, elsewhere := s.replayers[game.GameType]
+	var serverSeed string
+	if elsewhere {
+		serverSeed, err = replayer.revealedSeed(ctx, &game)
+	} else {
+		serverSeed, err = s.revealedFairSeed(ctx, &game)
+	}
+	if err != nil {
+		return nil, err
+	}
+	
+	// Replay against the exact settings version the round was played under
+	settings, err := s.settingsService.GetSettingsForVersion(ctx, game.GameType, game.SettingsVersion)
+	if err != nil {
+		return nil, err
+	}
+	
+	var replayed *models.Game
+	if elsewhere {
+		replayed, err = replayer.replay(&game, settings, serverSeed)
+	} else {
+		choices, _ := game.ResultData["choices"].(map[string]interface{})
+		replayed, err = s.ComputeRound(&PlayRequest{
+			UserID:    game.UserID,
+			GameType:  game.GameType,
+			BetAmount: game.BetAmount,
+			Choices:   choices,
+		}, settings, serverSeed, game.ClientSeed, game.Nonce)
+		if err == nil && len(choices) > 0 {
+			replayed.ResultData["choices"] = choices
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	
+	// Compare with the stored game as MongoDB reads it back
+	stored, err := storedForm(replayed.ResultData)
+	if err != nil {
+		return nil, err
+	}
 	
-	return err
+	return &models.FairVerification{
+		GameID:          game.ID,
+		GameType:        game.GameType,
+		ServerSeed:      serverSeed,
+		ServerSeedHash:  game.ServerSeedHash,
+		ClientSeed:      game.ClientSeed,
+		Nonce:           game.Nonce,
+		SettingsVersion: game.SettingsVersion,
+		Multiplier:      replayed.Multiplier,
+		ResultData:      replayed.ResultData,
+		Verified: replayed.Multiplier == game.Multiplier &&
+			replayed.WinAmount == game.WinAmount &&
+			sameResultData(stored, game.ResultData),
+	}, nil
+}
+
+// revealedFairSeed returns the server seed of the player's seed pair a
+// game was played on, once the pair has been rotated
+func (s *GameService) revealedFairSeed(ctx context.Context, game *models.Game) (string, error) {
+	pair, err := s.fairService.GetSeedPairByHash(ctx, game.ServerSeedHash)
+	if err != nil {
+		return "", err
+	}
+	if pair.Active {
+		return "", ErrSeedNotRevealed
+	}
+	return pair.ServerSeed, nil
+}
+
+// storedForm returns data as it reads back from MongoDB, e.g. with amounts
+// as paise rather than models.Money
+func storedForm(data map[string]interface{}) (map[string]interface{}, error) {
+	raw, err := bson.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result data: %w", err)
+	}
+	var out map[string]interface{}
+	if err := bson.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("failed to decode result data: %w", err)
+	}
+	return out, nil
+}
+
+// sameResultData compares result data after normalising both sides through
+// JSON, since values read back from MongoDB use different Go types.
+func sameResultData(a, b map[string]interface{}) bool {
+	var na, nb interface{}
+	ra, errA := json.Marshal(a)
+	rb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	if json.Unmarshal(ra, &na) != nil || json.Unmarshal(rb, &nb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(na, nb)
 }
 
 // GetGameHistory retrieves game history for a user
@@ -173,76 +553,37 @@ func (s *GameService) GetGameStats(ctx context.Context, userID string, gameType
 	}
 	
 	// Calculate overall stats
-	stats := map[string]interface{}{
-		"total_games":   int64(0),
-		"total_wagered": 0.0,
-		"total_won":     0.0,
-		"biggest_win":   0.0,
-		"by_game_type":  results,
-	}
+	var totalGames int64
+	var totalWagered, totalWon, biggestWin models.Money
 	
 	for _, result := range results {
-		if totalGames, ok := result["total_games"].(int64); ok {
-			stats["total_games"] = stats["total_games"].(int64) + totalGames
+		switch n := result["total_games"].(type) {
+		case int32:
+			totalGames += int64(n)
+		case int64:
+			totalGames += n
 		}
-		if totalWagered, ok := result["total_wagered"].(float64); ok {
-			stats["total_wagered"] = stats["total_wagered"].(float64) + totalWagered
-		}
-		if totalWon, ok := result["total_won"].(float64); ok {
-			stats["total_won"] = stats["total_won"].(float64) + totalWon
+		
+		// Amounts are stored in paise; convert so they encode as rupees
+		for _, field := range []string{"total_wagered", "total_won", "biggest_win"} {
+			result[field] = models.MoneyFromAny(result[field])
 		}
-		if biggestWin, ok := result["biggest_win"].(float64); ok {
-			if biggestWin > stats["biggest_win"].(float64) {
-				stats["biggest_win"] = biggestWin
-			}
+		totalWagered += result["total_wagered"].(models.Money)
+		totalWon += result["total_won"].(models.Money)
+		if win := result["biggest_win"].(models.Money); win > biggestWin {
+			biggestWin = win
 		}
 	}
 	
-	return stats, nil
-}
-
-// ValidateGameResult performs basic validation on game results
-func (s *GameService) ValidateGameResult(game *models.Game) error {
-	// Basic validation rules
-	if game.BetAmount <= 0 {
-		return fmt.Errorf("bet amount must be positive")
-	}
-	
-	if game.WinAmount < 0 {
-		return fmt.Errorf("win amount cannot be negative")
-	}
-	
-	// Game-specific validation
-	switch game.GameType {
-	case "spinwheel":
-		// Max multiplier is typically 100x
-		if game.Multiplier > 100 {
-			return fmt.Errorf("invalid multiplier for spinwheel")
-		}
-		
-	case "slot":
-		// Max payout is typically 1000x
-		if game.WinAmount > game.BetAmount*1000 {
-			return fmt.Errorf("win amount exceeds maximum payout")
-		}
-		
-	case "dice":
-		// Win amount should match odds
-		if game.WinAmount > 0 && game.Multiplier > 0 {
-			expectedWin := game.BetAmount * game.Multiplier
-			if game.WinAmount != expectedWin {
-				return fmt.Errorf("win amount doesn't match multiplier")
-			}
-		}
-		
-	case "aviation":
-		// Multiplier should be >= 1.0
-		if game.WinAmount > 0 && game.Multiplier < 1.0 {
-			return fmt.Errorf("invalid aviation multiplier")
-		}
+	stats := map[string]interface{}{
+		"total_games":   totalGames,
+		"total_wagered": totalWagered,
+		"total_won":     totalWon,
+		"biggest_win":   biggestWin,
+		"by_game_type":  results,
 	}
 	
-	return nil
+	return stats, nil
 }
 
 // GetRecentBets retrieves recent bets across all users (for dashboard display)
@@ -253,7 +594,7 @@ func (s *GameService) GetRecentBets(ctx context.Context, limit int64) ([]models.
 		SetSort(bson.D{{Key: "created_at", Value: -1}}).
 		SetLimit(limit)
 	
-	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	cursor, err := collection.Find(ctx, bson.M{"settled": true}, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get recent bets: %w", err)
 	}
@@ -0,0 +1,670 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"betting-app-backend-go/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Errors returned by GameSessionService
+var (
+	ErrSessionActive   = errors.New("a game session is already in progress")
+	ErrSessionNotFound = errors.New("game session not found")
+	ErrSessionClosed   = errors.New("game session is no longer active")
+	ErrSessionConflict = errors.New("game session was changed by another request, try again")
+)
+
+// sessionTimeout is how long a session may sit without an action before it
+// is settled automatically
+const sessionTimeout = 10 * time.Minute
+
+// SessionEngine plays a multi-step game. Like GameEngine it derives every
+// random outcome from rng and never touches the database. An action that
+// ends the round sets session.Outcome; session.Multiplier is always what
+// the round pays if it ends now.
+type SessionEngine interface {
+	GameType() string
+	Start(session *models.GameSession, settings *models.GameSettings, choices map[string]interface{}, rng RandomSource) error
+	Act(session *models.GameSession, settings *models.GameSettings, action string, params map[string]interface{}, rng RandomSource) error
+	// Expire ends a session that timed out
+	Expire(session *models.GameSession, settings *models.GameSettings)
+	// ResultData describes the settled round for its Game record
+	ResultData(session *models.GameSession) map[string]interface{}
+}
+
+// payoutEngine is implemented by session engines whose payout isn't the
+// bet times one multiplier, e.g. blackjack with split hands and insurance.
+// Extra wagers an action makes are added to session.BetAmount.
+type payoutEngine interface {
+	Payout(session *models.GameSession) models.Money
+}
+
+// defaultSessionEngines returns the engines registered on every
+// GameSessionService
+func defaultSessionEngines() []SessionEngine {
+	return []SessionEngine{
+		&MinesEngine{},
+		&BlackjackEngine{},
+		&HiLoEngine{},
+	}
+}
+
+// GameSessionService runs multi-step games. The bet is taken when a session
+// starts; when it ends the round is recorded as a settled Game and any win
+// is credited, exactly like a round played through GameService.RecordGame.
+type GameSessionService struct {
+	collection *mongo.Collection
+	games      *GameService
+	engines    map[string]SessionEngine
+	interval   time.Duration
+}
+
+func NewGameSessionService(db *mongo.Database, games *GameService) *GameSessionService {
+	s := &GameSessionService{
+		collection: db.Collection("game_sessions"),
+		games:      games,
+		engines:    make(map[string]SessionEngine),
+		interval:   30 * time.Second,
+	}
+	for _, engine := range defaultSessionEngines() {
+		s.RegisterEngine(engine)
+	}
+	return s
+}
+
+// RegisterEngine makes a game type playable as a session
+func (s *GameSessionService) RegisterEngine(engine SessionEngine) {
+	s.engines[engine.GameType()] = engine
+	s.games.registerElsewhere(engine.GameType(), "/api/game/session", s)
+}
+
+// Start takes the bet and opens a session. A user can have only one active
+// session at a time. The session is stored before the bet is taken, so
+// every later step checks that it was; see staked.
+func (s *GameSessionService) Start(ctx context.Context, req *PlayRequest) (*models.GameSession, error) {
+	if req.BetAmount <= 0 {
+		return nil, fmt.Errorf("bet amount must be positive")
+	}
+
+	engine, ok := s.engines[req.GameType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoEngine, req.GameType)
+	}
+
+	if active, err := s.GetActive(ctx, req.UserID); err != nil {
+		return nil, err
+	} else if active != nil {
+		return nil, ErrSessionActive
+	}
+
+	settings, err := s.games.settingsService.GetCachedGameSettings(ctx, req.GameType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load game settings: %w", err)
+	}
+	if err := checkBetAgainstSettings(req.BetAmount, settings); err != nil {
+		return nil, err
+	}
+
+	seed, err := s.games.fairService.NextRound(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &models.GameSession{
+		ID:              fmt.Sprintf("session_%d", now.UnixNano()),
+		UserID:          req.UserID,
+		GameType:        req.GameType,
+		BetAmount:       req.BetAmount,
+		Stake:           req.BetAmount,
+		Choices:         req.Choices,
+		Actions:         []models.SessionAction{},
+		Status:          "active",
+		SettingsVersion: settings.Version,
+		ServerSeed:      seed.ServerSeed,
+		ServerSeedHash:  seed.ServerSeedHash,
+		ClientSeed:      seed.ClientSeed,
+		Nonce:           seed.Nonce,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		ExpiresAt:       now.Add(sessionTimeout),
+	}
+	if err := engine.Start(session, settings, req.Choices, newSessionSource(session)); err != nil {
+		return nil, err
+	}
+
+	// The unique index on active sessions makes the insert the reservation,
+	// so two concurrent starts can't both take a bet
+	if _, err := s.collection.InsertOne(ctx, session); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrSessionActive
+		}
+		return nil, fmt.Errorf("failed to create game session: %w", err)
+	}
+
+	// A rotation that began before the insert may not have seen the
+	// session; drop it rather than play on a seed about to be revealed
+	committed, err := s.games.fairService.Committed(ctx, session.ServerSeedHash)
+	if err == nil && !committed {
+		err = ErrSeedRotating
+	}
+	if err != nil {
+		if _, delErr := s.collection.DeleteOne(ctx, bson.M{"_id": session.ID}); delErr != nil {
+			log.Printf("[Session] ❌ Failed to remove session %s: %v\n", session.ID, delErr)
+		}
+		return nil, err
+	}
+
+	err = s.games.walletService.DeductBalance(
+		ctx,
+		session.UserID,
+		session.BetAmount,
+		fmt.Sprintf("%s game bet", session.GameType),
+		"game_loss",
+		session.ID,
+		session.CreatedAt,
+	)
+	if err != nil {
+		if _, delErr := s.collection.DeleteOne(ctx, bson.M{"_id": session.ID}); delErr != nil {
+			log.Printf("[Session] ❌ Failed to remove unpaid session %s: %v\n", session.ID, delErr)
+		}
+		return nil, fmt.Errorf("failed to deduct bet: %w", err)
+	}
+
+	// Some rounds are decided by the deal alone (e.g. a blackjack)
+	if session.Outcome != "" {
+		session.Status = "settling"
+		if err := s.save(ctx, session); err != nil {
+			return nil, err
+		}
+		if err := s.settle(ctx, session); err != nil {
+			return nil, err
+		}
+	}
+
+	return session, nil
+}
+
+// GetActive returns the user's session in play, or nil
+func (s *GameSessionService) GetActive(ctx context.Context, userID string) (*models.GameSession, error) {
+	var session models.GameSession
+	err := s.collection.FindOne(ctx, bson.M{"user_id": userID, "status": "active"}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get game session: %w", err)
+	}
+	return &session, nil
+}
+
+// Get returns one of a user's sessions
+func (s *GameSessionService) Get(ctx context.Context, userID, id string) (*models.GameSession, error) {
+	var session models.GameSession
+	err := s.collection.FindOne(ctx, bson.M{"_id": id, "user_id": userID}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get game session: %w", err)
+	}
+	return &session, nil
+}
+
+// Act applies a player action to an active session and settles it when
+// the action ends the round
+func (s *GameSessionService) Act(ctx context.Context, userID, id, action string, params map[string]interface{}) (*models.GameSession, error) {
+	session, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != "active" {
+		return nil, ErrSessionClosed
+	}
+	if staked, err := s.staked(ctx, session); err != nil {
+		return nil, err
+	} else if !staked {
+		return nil, ErrSessionNotFound
+	}
+
+	engine, ok := s.engines[session.GameType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoEngine, session.GameType)
+	}
+
+	// Play on under the settings the session started with
+	settings, err := s.games.settingsService.GetSettingsForVersion(ctx, session.GameType, session.SettingsVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	wagered := session.BetAmount
+	if err := engine.Act(session, settings, action, params, newSessionSource(session)); err != nil {
+		return nil, err
+	}
+	act := models.SessionAction{Action: action, Params: params}
+
+	// Take extra wagers (double, split, ...) before the action is stored.
+	// The reference is unique to this attempt, so a wager whose action is
+	// never stored can be told apart and refunded; see refundStrayWagers.
+	// Like its win, an extra wager counts against the player's limits in
+	// the periods the session started in.
+	now := time.Now()
+	extra := session.BetAmount - wagered
+	if extra > 0 {
+		act.Wager = extra
+		act.Reference = fmt.Sprintf("%s:%d:%d", session.ID, len(session.Actions), now.UnixNano())
+		err := s.games.walletService.DeductBalance(
+			ctx,
+			session.UserID,
+			extra,
+			fmt.Sprintf("%s game %s", session.GameType, action),
+			"game_loss",
+			act.Reference,
+			session.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to deduct %s wager: %w", action, err)
+		}
+	}
+	session.Actions = append(session.Actions, act)
+
+	session.UpdatedAt = now
+	session.ExpiresAt = now.Add(sessionTimeout)
+	if session.Outcome != "" {
+		session.Status = "settling"
+	}
+	if err := s.save(ctx, session); err != nil {
+		// The action was not stored; give the extra wager back
+		if extra > 0 {
+			refundErr := s.games.walletService.CreditBalance(
+				ctx,
+				session.UserID,
+				extra,
+				fmt.Sprintf("%s game %s refund", session.GameType, action),
+				"game_refund",
+				act.Reference,
+				session.CreatedAt,
+			)
+			if refundErr != nil {
+				log.Printf("[Session] ❌ Failed to refund %s wager on session %s: %v\n", action, session.ID, refundErr)
+			}
+		}
+		return nil, err
+	}
+
+	if session.Status == "settling" {
+		if err := s.settle(ctx, session); err != nil {
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
+// staked reports whether a session's stake was taken. Start stores the
+// session before taking the stake, so a start that failed or stopped in
+// between leaves a session without one; it must never be played or paid.
+func (s *GameSessionService) staked(ctx context.Context, session *models.GameSession) (bool, error) {
+	return s.games.walletService.Applied(ctx, session.UserID, "game_loss", session.ID)
+}
+
+// drop removes a session whose stake was never taken, unless it changed
+// since it was read
+func (s *GameSessionService) drop(ctx context.Context, session *models.GameSession) error {
+	_, err := s.collection.DeleteOne(ctx, bson.M{"_id": session.ID, "version": session.Version, "status": session.Status})
+	if err != nil {
+		return fmt.Errorf("failed to remove unpaid game session: %w", err)
+	}
+	log.Printf("[Session] Removed session %s, its stake was never taken\n", session.ID)
+	return nil
+}
+
+// save writes the session back if nobody else changed it since it was read
+func (s *GameSessionService) save(ctx context.Context, session *models.GameSession) error {
+	version := session.Version
+	session.Version++
+	res, err := s.collection.ReplaceOne(ctx, bson.M{"_id": session.ID, "version": version, "status": "active"}, session)
+	if err != nil {
+		session.Version = version
+		return fmt.Errorf("failed to save game session: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		session.Version = version
+		return ErrSessionConflict
+	}
+	return nil
+}
+
+// settle records a finished session as a Game and credits any win. A
+// session whose win could not be credited stays "settling" and Run settles
+// it again later. The game ID is derived from the session and the win is
+// credited under it, so settling again never records or pays twice.
+func (s *GameSessionService) settle(ctx context.Context, session *models.GameSession) error {
+	engine := s.engines[session.GameType]
+	gamesCol := s.games.db.Collection("games")
+
+	if err := s.refundStrayWagers(ctx, session); err != nil {
+		return err
+	}
+
+	session.WinAmount = session.BetAmount.MulMultiplier(session.Multiplier)
+	if p, ok := engine.(payoutEngine); ok {
+		session.WinAmount = p.Payout(session)
+	}
+
+	game := &models.Game{
+		ID:              "game_" + session.ID,
+		UserID:          session.UserID,
+		GameType:        session.GameType,
+		BetAmount:       session.BetAmount,
+		WinAmount:       session.WinAmount,
+		Multiplier:      session.Multiplier,
+		ResultData:      engine.ResultData(session),
+		Settled:         false,
+		CreatedAt:       session.CreatedAt,
+		SettingsVersion: session.SettingsVersion,
+		ServerSeedHash:  session.ServerSeedHash,
+		ClientSeed:      session.ClientSeed,
+		Nonce:           session.Nonce,
+	}
+	recordReplay(game.ResultData, session)
+
+	// An earlier attempt may have recorded the game already
+	if _, err := gamesCol.InsertOne(ctx, game); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("failed to insert game: %w", err)
+	}
+
+	if err := s.games.payOut(ctx, game); err != nil {
+		log.Printf("[Session] ❌ Failed to credit winnings for session %s: %v\n", session.ID, err)
+		return err
+	}
+
+	now := time.Now()
+	_, err := s.collection.UpdateOne(ctx, bson.M{"_id": session.ID, "status": "settling"}, bson.M{"$set": bson.M{
+		"status":     "settled",
+		"win_amount": game.WinAmount,
+		"game_id":    game.ID,
+		"settled_at": now,
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to settle game session: %w", err)
+	}
+	session.Status = "settled"
+	session.GameID = game.ID
+	session.SettledAt = &now
+	return nil
+}
+
+// refundStrayWagers gives back extra wagers debited for actions that were
+// never stored, e.g. because the instance stopped between the debit and
+// the save. Once a session is settling no action can be stored any more,
+// so every wager debited under one of the session's references that no
+// action lists is such a wager. Refunds use the wager's reference, so each
+// is paid at most once, including by Act giving back a wager itself.
+func (s *GameSessionService) refundStrayWagers(ctx context.Context, session *models.GameSession) error {
+	recorded := make(map[string]bool)
+	for _, act := range session.Actions {
+		if act.Reference != "" {
+			recorded[act.Reference] = true
+		}
+	}
+
+	wagers, err := s.games.walletService.AppliedUnder(ctx, session.UserID, "game_loss", session.ID+":")
+	if err != nil {
+		return err
+	}
+	account := models.UserAccount(session.UserID)
+	for _, wager := range wagers {
+		if recorded[wager.Reference] {
+			continue
+		}
+		var amount models.Money
+		for _, p := range wager.Postings {
+			if p.Account == account {
+				amount += p.Debit
+			}
+		}
+		if amount <= 0 {
+			continue
+		}
+		err := s.games.walletService.CreditBalance(
+			ctx,
+			session.UserID,
+			amount,
+			fmt.Sprintf("%s game wager refund", session.GameType),
+			"game_refund",
+			wager.Reference,
+			session.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to refund wager %s: %w", wager.Reference, err)
+		}
+		log.Printf("[Session] ✅ Refunded %s wagered on session %s by an action that was never stored\n", amount, session.ID)
+	}
+	return nil
+}
+
+// Run settles timed-out sessions until ctx is cancelled
+func (s *GameSessionService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.expireDue(ctx)
+		s.resumeSettling(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// expireDue settles every active session past its expiry time
+func (s *GameSessionService) expireDue(ctx context.Context) {
+	opts := options.Find().SetSort(bson.D{{Key: "expires_at", Value: 1}}).SetLimit(100)
+	cursor, err := s.collection.Find(ctx, bson.M{"status": "active", "expires_at": bson.M{"$lte": time.Now()}}, opts)
+	if err != nil {
+		log.Printf("[Session] ❌ Failed to find expired sessions: %v\n", err)
+		return
+	}
+	var sessions []models.GameSession
+	if err := cursor.All(ctx, &sessions); err != nil {
+		log.Printf("[Session] ❌ Failed to decode expired sessions: %v\n", err)
+		return
+	}
+
+	for i := range sessions {
+		if err := s.expire(ctx, &sessions[i]); err != nil {
+			log.Printf("[Session] ❌ Failed to expire session %s: %v\n", sessions[i].ID, err)
+		}
+	}
+}
+
+// resumeSettling settles again every session that has been "settling" for
+// longer than settleGrace, e.g. because its win could not be credited or
+// the instance settling it stopped
+func (s *GameSessionService) resumeSettling(ctx context.Context) {
+	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: 1}}).SetLimit(100)
+	cursor, err := s.collection.Find(ctx, bson.M{"status": "settling", "updated_at": bson.M{"$lte": time.Now().Add(-settleGrace)}}, opts)
+	if err != nil {
+		log.Printf("[Session] ❌ Failed to find settling sessions: %v\n", err)
+		return
+	}
+	var sessions []models.GameSession
+	if err := cursor.All(ctx, &sessions); err != nil {
+		log.Printf("[Session] ❌ Failed to decode settling sessions: %v\n", err)
+		return
+	}
+
+	for i := range sessions {
+		session := &sessions[i]
+		if _, ok := s.engines[session.GameType]; !ok {
+			log.Printf("[Session] ❌ Can't settle session %s: %v: %s\n", session.ID, ErrNoEngine, session.GameType)
+			continue
+		}
+		staked, err := s.staked(ctx, session)
+		if err != nil {
+			log.Printf("[Session] ❌ Failed to check the stake of session %s: %v\n", session.ID, err)
+			continue
+		}
+		if !staked {
+			if err := s.drop(ctx, session); err != nil {
+				log.Printf("[Session] ❌ %v\n", err)
+			}
+			continue
+		}
+		if err := s.settle(ctx, session); err != nil {
+			log.Printf("[Session] ❌ Failed to settle session %s: %v\n", session.ID, err)
+			continue
+		}
+		log.Printf("[Session] ✅ Settled session %s, paid %s\n", session.ID, session.WinAmount)
+	}
+}
+
+func (s *GameSessionService) expire(ctx context.Context, session *models.GameSession) error {
+	engine, ok := s.engines[session.GameType]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoEngine, session.GameType)
+	}
+	if staked, err := s.staked(ctx, session); err != nil {
+		return err
+	} else if !staked {
+		return s.drop(ctx, session)
+	}
+	settings, err := s.games.settingsService.GetSettingsForVersion(ctx, session.GameType, session.SettingsVersion)
+	if err != nil {
+		return err
+	}
+
+	engine.Expire(session, settings)
+	session.Status = "settling"
+	session.UpdatedAt = time.Now()
+
+	// A player action that got in first wins; the session is no longer due
+	if err := s.save(ctx, session); err != nil {
+		if errors.Is(err, ErrSessionConflict) {
+			return nil
+		}
+		return err
+	}
+	if err := s.settle(ctx, session); err != nil {
+		return err
+	}
+
+	log.Printf("[Session] ✅ Session %s expired and settled at %gx\n", session.ID, session.Multiplier)
+	return nil
+}
+
+// recordReplay adds what VerifyGame needs to replay a session to the
+// result data of its game: the session, the stake it started with, the
+// player's choices and every action in order
+func recordReplay(resultData map[string]interface{}, session *models.GameSession) {
+	resultData["session_id"] = session.ID
+	resultData["stake"] = session.Stake
+	resultData["actions"] = session.Actions
+	if len(session.Choices) > 0 {
+		resultData["choices"] = session.Choices
+	}
+}
+
+// revealedSeed returns the server seed of the player's seed pair the
+// session was played on, once the pair has been rotated
+func (s *GameSessionService) revealedSeed(ctx context.Context, game *models.Game) (string, error) {
+	return s.games.revealedFairSeed(ctx, game)
+}
+
+// replay plays a recorded session again from its seeds: the engine starts
+// with the same stake and choices, every recorded action is applied in
+// order, and a session that timed out is expired
+func (s *GameSessionService) replay(game *models.Game, settings *models.GameSettings, serverSeed string) (*models.Game, error) {
+	engine, ok := s.engines[game.GameType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoEngine, game.GameType)
+	}
+
+	stake := models.MoneyFromAny(game.ResultData["stake"])
+	if stake <= 0 {
+		return nil, fmt.Errorf("game has no recorded actions to replay")
+	}
+	var actions []models.SessionAction
+	raw, err := json.Marshal(game.ResultData["actions"])
+	if err == nil {
+		err = json.Unmarshal(raw, &actions)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode recorded actions: %w", err)
+	}
+	choices, _ := game.ResultData["choices"].(map[string]interface{})
+	sessionID, _ := game.ResultData["session_id"].(string)
+
+	session := &models.GameSession{
+		ID:         sessionID,
+		UserID:     game.UserID,
+		GameType:   game.GameType,
+		BetAmount:  stake,
+		Stake:      stake,
+		Choices:    choices,
+		Actions:    actions,
+		ServerSeed: serverSeed,
+		ClientSeed: game.ClientSeed,
+		Nonce:      game.Nonce,
+	}
+	if err := engine.Start(session, settings, choices, newSessionSource(session)); err != nil {
+		return nil, err
+	}
+	for i, a := range actions {
+		if session.Outcome != "" {
+			return nil, fmt.Errorf("action %d was recorded after the round ended", i)
+		}
+		if err := engine.Act(session, settings, a.Action, a.Params, newSessionSource(session)); err != nil {
+			return nil, fmt.Errorf("action %d (%s) can't be replayed: %w", i, a.Action, err)
+		}
+	}
+	if session.Outcome == "" {
+		engine.Expire(session, settings)
+	}
+
+	session.WinAmount = session.BetAmount.MulMultiplier(session.Multiplier)
+	if p, ok := engine.(payoutEngine); ok {
+		session.WinAmount = p.Payout(session)
+	}
+	resultData := engine.ResultData(session)
+	recordReplay(resultData, session)
+	return &models.Game{
+		GameType:   game.GameType,
+		BetAmount:  session.BetAmount,
+		WinAmount:  session.WinAmount,
+		Multiplier: session.Multiplier,
+		ResultData: resultData,
+	}, nil
+}
+
+// sessionSource continues a session's provably-fair stream where the
+// previous action left off and counts the numbers it hands out
+type sessionSource struct {
+	src     *fairSource
+	session *models.GameSession
+}
+
+func newSessionSource(session *models.GameSession) *sessionSource {
+	src := newFairSource(session.ServerSeed, session.ClientSeed, session.Nonce)
+	for i := 0; i < session.Draws; i++ {
+		src.Float64()
+	}
+	return &sessionSource{src: src, session: session}
+}
+
+func (s *sessionSource) Float64() float64 {
+	s.session.Draws++
+	return s.src.Float64()
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"betting-app-backend-go/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrVersionNotFound is returned for an unknown settings version
+var ErrVersionNotFound = errors.New("game settings version not found")
+
+// ListVersions returns the settings history of a game, newest first
+func (s *GameSettingsService) ListVersions(ctx context.Context, gameType string, limit int64) ([]models.GameSettingsVersion, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "version", Value: -1}}).
+		SetLimit(limit)
+
+	cursor, err := s.versions.Find(ctx, bson.M{"game_type": gameType}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings versions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var versions []models.GameSettingsVersion
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, fmt.Errorf("failed to decode settings versions: %w", err)
+	}
+	return versions, nil
+}
+
+// GetVersion returns a single settings version of a game
+func (s *GameSettingsService) GetVersion(ctx context.Context, gameType string, version int64) (*models.GameSettingsVersion, error) {
+	var v models.GameSettingsVersion
+	err := s.versions.FindOne(ctx, bson.M{"game_type": gameType, "version": version}).Decode(&v)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrVersionNotFound
+		}
+		return nil, fmt.Errorf("failed to get settings version: %w", err)
+	}
+	return &v, nil
+}
+
+// GetSettingsForVersion returns the settings a game had at a given version.
+// Version 0 (rounds played before versioning) resolves to current settings.
+func (s *GameSettingsService) GetSettingsForVersion(ctx context.Context, gameType string, version int64) (*models.GameSettings, error) {
+	if version == 0 {
+		return s.GetGameSettings(ctx, gameType)
+	}
+	v, err := s.GetVersion(ctx, gameType, version)
+	if err != nil {
+		return nil, err
+	}
+	return &v.After, nil
+}
+
+// DiffVersions lists the fields that differ between two settings versions
+func (s *GameSettingsService) DiffVersions(ctx context.Context, gameType string, from, to int64) ([]models.SettingsFieldChange, error) {
+	a, err := s.GetVersion(ctx, gameType, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.GetVersion(ctx, gameType, to)
+	if err != nil {
+		return nil, err
+	}
+	return diffSettings(&a.After, &b.After)
+}
+
+// Rollback restores the settings of a previous version. The rollback is
+// itself recorded as a new version.
+func (s *GameSettingsService) Rollback(ctx context.Context, gameType string, version int64, adminUID string) (*models.GameSettings, error) {
+	v, err := s.GetVersion(ctx, gameType, version)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := v.After
+	if err := s.applySettings(ctx, gameType, &settings, adminUID, "rollback", version); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// diffSettings compares two settings field by field. Audit fields that
+// change on every write are ignored.
+func diffSettings(a, b *models.GameSettings) ([]models.SettingsFieldChange, error) {
+	fa, err := flattenSettings(a)
+	if err != nil {
+		return nil, err
+	}
+	fb, err := flattenSettings(b)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]bool)
+	for k := range fa {
+		keys[k] = true
+	}
+	for k := range fb {
+		keys[k] = true
+	}
+
+	changes := []models.SettingsFieldChange{}
+	for k := range keys {
+		if !reflect.DeepEqual(fa[k], fb[k]) {
+			changes = append(changes, models.SettingsFieldChange{Field: k, From: fa[k], To: fb[k]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+// flattenSettings turns settings into a map of dotted field paths to values
+func flattenSettings(settings *models.GameSettings) (map[string]interface{}, error) {
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode settings: %w", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode settings: %w", err)
+	}
+	for _, audit := range []string{"updated_at", "updated_by", "version"} {
+		delete(doc, audit)
+	}
+
+	flat := make(map[string]interface{})
+	flatten("", doc, flat)
+	return flat, nil
+}
+
+func flatten(prefix string, value interface{}, out map[string]interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			path := k
+			if prefix != "" {
+				path = prefix + "." + k
+			}
+			flatten(path, child, out)
+		}
+	case []interface{}:
+		for i, child := range v {
+			flatten(fmt.Sprintf("%s[%d]", prefix, i), child, out)
+		}
+		// Record the length so removed trailing entries show up
+		out[prefix+".length"] = len(v)
+	default:
+		out[prefix] = v
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"betting-app-backend-go/models"
 	"context"
 	"errors"
+	"fmt"
+	"log"
+	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,13 +14,44 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// settingsCacheTTL bounds how long a bet may be checked against settings
+// that were changed on another server instance
+const settingsCacheTTL = 30 * time.Second
+
+type cachedSettings struct {
+	settings  models.GameSettings
+	expiresAt time.Time
+}
+
+// defaultSlotStrip is the reel strip every reel of the default slot uses
+var defaultSlotStrip = []models.SlotStop{
+	{Symbol: "🍒", Weight: 4}, {Symbol: "🍋", Weight: 4}, {Symbol: "🍊", Weight: 3},
+	{Symbol: "🍒", Weight: 4}, {Symbol: "🍇", Weight: 3}, {Symbol: "💎", Weight: 2},
+	{Symbol: "🍋", Weight: 4}, {Symbol: "7️⃣", Weight: 2}, {Symbol: "🍊", Weight: 3},
+	{Symbol: "🍒", Weight: 4}, {Symbol: "⭐", Weight: 1}, {Symbol: "🍇", Weight: 3},
+	{Symbol: "🍋", Weight: 4}, {Symbol: "🍊", Weight: 3}, {Symbol: "🍇", Weight: 3},
+}
+
+// ErrSettingsConflict is returned when settings were changed by someone
+// else between reading and writing them.
+var ErrSettingsConflict = errors.New("game settings were changed concurrently, reload and retry")
+
+// ErrSettingsNotFound is returned when a game has no settings document
+var ErrSettingsNotFound = errors.New("game settings not found")
+
 type GameSettingsService struct {
 	collection *mongo.Collection
+	versions   *mongo.Collection
+
+	cacheMu sync.RWMutex
+	cache   map[string]cachedSettings
 }
 
 func NewGameSettingsService(db *mongo.Database) *GameSettingsService {
 	return &GameSettingsService{
 		collection: db.Collection("game_settings"),
+		versions:   db.Collection("game_settings_versions"),
+		cache:      make(map[string]cachedSettings),
 	}
 }
 
@@ -27,13 +61,51 @@ func (s *GameSettingsService) GetGameSettings(ctx context.Context, gameType stri
 	err := s.collection.FindOne(ctx, bson.M{"game_type": gameType}).Decode(&settings)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("game settings not found")
+			return nil, ErrSettingsNotFound
 		}
 		return nil, err
 	}
 	return &settings, nil
 }
 
+// GetCachedGameSettings returns settings for a game from a short-lived
+// in-memory cache, loading them from the database when missing or stale.
+// Used on the bet path so every round doesn't hit the database.
+func (s *GameSettingsService) GetCachedGameSettings(ctx context.Context, gameType string) (*models.GameSettings, error) {
+	s.cacheMu.RLock()
+	entry, ok := s.cache[gameType]
+	s.cacheMu.RUnlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		settings := entry.settings
+		return &settings, nil
+	}
+
+	settings, err := s.GetGameSettings(ctx, gameType)
+	if err != nil {
+		return nil, err
+	}
+	// A stored config that breaks today's rules is never played
+	if settings.Enabled {
+		if err := ValidateGameSettings(gameType, settings); err != nil {
+			log.Printf("[Settings] ❌ Stored %s settings v%d are invalid, refusing bets: %v\n", gameType, settings.Version, err)
+			settings.Enabled = false
+		}
+	}
+
+	s.cacheMu.Lock()
+	s.cache[gameType] = cachedSettings{settings: *settings, expiresAt: time.Now().Add(settingsCacheTTL)}
+	s.cacheMu.Unlock()
+
+	return settings, nil
+}
+
+// invalidate drops a game's cached settings
+func (s *GameSettingsService) invalidate(gameType string) {
+	s.cacheMu.Lock()
+	delete(s.cache, gameType)
+	s.cacheMu.Unlock()
+}
+
 // GetAllGameSettings retrieves settings for all games
 func (s *GameSettingsService) GetAllGameSettings(ctx context.Context) ([]models.GameSettings, error) {
 	cursor, err := s.collection.Find(ctx, bson.M{})
@@ -49,32 +121,98 @@ func (s *GameSettingsService) GetAllGameSettings(ctx context.Context) ([]models.
 	return settings, nil
 }
 
-// UpdateGameSettings updates settings for a specific game
+// UpdateGameSettings validates and updates settings for a specific game.
+// Invalid settings are rejected with a *ValidationError. Every change is
+// stored as an immutable version.
 func (s *GameSettingsService) UpdateGameSettings(ctx context.Context, gameType string, settings *models.GameSettings, adminUID string) error {
+	return s.applySettings(ctx, gameType, settings, adminUID, "update", 0)
+}
+
+// applySettings writes a new settings version. The version record is
+// inserted first under a unique (game_type, version) index, which makes it
+// the lock: of two concurrent writers only one can claim the next version.
+func (s *GameSettingsService) applySettings(ctx context.Context, gameType string, settings *models.GameSettings, adminUID string, action string, rolledBackTo int64) error {
+	if err := ValidateGameSettings(gameType, settings); err != nil {
+		return err
+	}
+	return s.writeSettings(ctx, gameType, settings, adminUID, action, rolledBackTo)
+}
+
+// writeSettings records and stores a new settings version without
+// validating it. Only disabling a stored invalid config skips validation.
+func (s *GameSettingsService) writeSettings(ctx context.Context, gameType string, settings *models.GameSettings, adminUID string, action string, rolledBackTo int64) error {
+	var before *models.GameSettings
+	current, err := s.GetGameSettings(ctx, gameType)
+	if err == nil {
+		before = current
+	} else if !errors.Is(err, ErrSettingsNotFound) {
+		return err
+	}
+
+	var previousVersion int64
+	if before != nil {
+		previousVersion = before.Version
+	}
+
 	settings.GameType = gameType
 	settings.UpdatedAt = time.Now()
 	settings.UpdatedBy = adminUID
+	settings.Version = previousVersion + 1
+
+	version := models.GameSettingsVersion{
+		ID:           fmt.Sprintf("gsv_%s_%d", gameType, settings.Version),
+		GameType:     gameType,
+		Version:      settings.Version,
+		Action:       action,
+		RolledBackTo: rolledBackTo,
+		Before:       before,
+		After:        *settings,
+		ChangedBy:    adminUID,
+		ChangedAt:    settings.UpdatedAt,
+	}
+	if _, err := s.versions.InsertOne(ctx, version); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrSettingsConflict
+		}
+		return fmt.Errorf("failed to record settings version: %w", err)
+	}
 
+	// Documents written before versioning have no version field
+	filter := bson.M{"game_type": gameType, "version": previousVersion}
+	if previousVersion == 0 {
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
 	opts := options.Update().SetUpsert(true)
-	filter := bson.M{"game_type": gameType}
 	update := bson.M{"$set": settings}
 
-	_, err := s.collection.UpdateOne(ctx, filter, update, opts)
-	return err
+	_, err = s.collection.UpdateOne(ctx, filter, update, opts)
+	s.invalidate(gameType)
+	if err != nil {
+		// Release the claimed version so the history stays gap-free
+		s.versions.DeleteOne(ctx, bson.M{"_id": version.ID})
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrSettingsConflict
+		}
+		return err
+	}
+	return nil
 }
 
-// InitializeDefaultSettings creates default settings for all games if they don't exist
-func (s *GameSettingsService) InitializeDefaultSettings(ctx context.Context) error {
-	defaults := []models.GameSettings{
+// DefaultGameSettings returns the settings every game starts with
+func DefaultGameSettings() []models.GameSettings {
+	return []models.GameSettings{
 		{
 			GameType:    "spinwheel",
 			DisplayName: "Spin Wheel",
 			Enabled:     true,
-			MinBet:      10,
-			MaxBet:      10000,
+			MinBet:      models.MoneyFromMajor(10),
+			MaxBet:      models.MoneyFromMajor(10000),
 			HouseEdge:   2.5,
 			Config: map[string]interface{}{
-				"multipliers": []float64{1.2, 1.5, 2.0, 0, 1.8, 0.5, 3.0, 0},
+				// Returns 97.5%: 78 paid per 80 weight
+				"multipliers": []float64{0, 1.5, 0.5, 2.0, 0, 3.0, 5.0, 10.0},
+				"weights":     []int{20, 12, 10, 10, 20, 5, 2, 1},
+				"colors":      []string{"#9E9E9E", "#4CAF50", "#FF9800", "#2196F3", "#9E9E9E", "#9C27B0", "#F44336", "#FFD700"},
 			},
 			UpdatedAt: time.Now(),
 			UpdatedBy: "system",
@@ -83,17 +221,19 @@ func (s *GameSettingsService) InitializeDefaultSettings(ctx context.Context) err
 			GameType:    "aviation",
 			DisplayName: "Aviation",
 			Enabled:     true,
-			MinBet:      10,
-			MaxBet:      10000,
+			MinBet:      models.MoneyFromMajor(10),
+			MaxBet:      models.MoneyFromMajor(10000),
 			HouseEdge:   2.0,
 			Config: map[string]interface{}{
-				"min_multiplier": 1.01,
+				// 1.00 crashes at take-off; cashing out at any fixed
+				// multiplier returns at most 98.5%
+				"min_multiplier": 1.0,
 				"max_multiplier": 50.0,
 				"crash_chances": map[string]float64{
-					"low":       50.0, // 1.01-3x
-					"medium":    30.0, // 3-5x
-					"high":      15.0, // 5-10x
-					"very_high": 5.0,  // 10-50x
+					"low":       66.5, // 1-3x before the house edge
+					"medium":    13.5, // 3-5x
+					"high":      10.0, // 5-10x
+					"very_high": 10.0, // 10x and up
 				},
 			},
 			UpdatedAt: time.Now(),
@@ -103,20 +243,34 @@ func (s *GameSettingsService) InitializeDefaultSettings(ctx context.Context) err
 			GameType:    "slot",
 			DisplayName: "Slot Machine",
 			Enabled:     true,
-			MinBet:      10,
-			MaxBet:      5000,
+			MinBet:      models.MoneyFromMajor(10),
+			MaxBet:      models.MoneyFromMajor(5000),
 			HouseEdge:   3.0,
 			Config: map[string]interface{}{
 				"symbols": []string{"🍒", "🍋", "🍊", "🍇", "💎", "⭐", "7️⃣"},
-				"multipliers": map[string]float64{
-					"7️⃣": 100,
-					"💎": 50,
-					"⭐": 20,
-					"🍇": 10,
-					"🍊": 5,
-					"🍋": 3,
-					"🍒": 2,
+				"reels":   5,
+				"rows":    3,
+				"reel_strips": [][]models.SlotStop{
+					defaultSlotStrip, defaultSlotStrip, defaultSlotStrip, defaultSlotStrip, defaultSlotStrip,
+				},
+				// Returns about 97% including scatters and free spins
+				"paytable": map[string][]float64{
+					"7️⃣": {0, 0, 8, 30, 150},
+					"⭐":  {0, 0, 20, 80, 400},
+					"🍇":  {0, 0, 2, 8, 30},
+					"🍊":  {0, 0, 2, 8, 30},
+					"🍋":  {0, 0, 1, 3, 20},
+					"🍒":  {0, 0, 1, 4, 20},
+				},
+				"paylines": [][]int{
+					{1, 1, 1, 1, 1}, {0, 0, 0, 0, 0}, {2, 2, 2, 2, 2}, {0, 1, 2, 1, 0}, {2, 1, 0, 1, 2},
+					{0, 0, 1, 2, 2}, {2, 2, 1, 0, 0}, {1, 0, 0, 0, 1}, {1, 2, 2, 2, 1}, {1, 0, 1, 2, 1},
 				},
+				"wild":               "⭐",
+				"scatter":            "💎",
+				"scatter_pays":       []float64{0, 0, 2, 10, 50},
+				"free_spins_trigger": 3,
+				"free_spins":         10,
 			},
 			UpdatedAt: time.Now(),
 			UpdatedBy: "system",
@@ -125,8 +279,8 @@ func (s *GameSettingsService) InitializeDefaultSettings(ctx context.Context) err
 			GameType:    "mines",
 			DisplayName: "Mines",
 			Enabled:     true,
-			MinBet:      10,
-			MaxBet:      10000,
+			MinBet:      models.MoneyFromMajor(10),
+			MaxBet:      models.MoneyFromMajor(10000),
 			HouseEdge:   2.0,
 			Config: map[string]interface{}{
 				"grid_size":       5,
@@ -142,14 +296,14 @@ func (s *GameSettingsService) InitializeDefaultSettings(ctx context.Context) err
 			GameType:    "plinko",
 			DisplayName: "Plinko",
 			Enabled:     true,
-			MinBet:      10,
-			MaxBet:      10000,
+			MinBet:      models.MoneyFromMajor(10),
+			MaxBet:      models.MoneyFromMajor(10000),
 			HouseEdge:   2.5,
 			Config: map[string]interface{}{
-				"rows":                12,
-				"multipliers_low":     []float64{1.5, 1.3, 1.1, 1.0, 0.5, 1.0, 1.1, 1.3, 1.5},
-				"multipliers_medium":  []float64{3.0, 1.6, 1.4, 1.1, 1.0, 0.5, 1.0, 1.1, 1.4, 1.6, 3.0},
-				"multipliers_high":    []float64{10.0, 3.0, 1.6, 1.4, 1.1, 1.0, 0.2, 1.0, 1.1, 1.4, 1.6, 3.0, 10.0},
+				"rows":               12,
+				"multipliers_low":    []float64{10.0, 3.0, 1.6, 1.4, 1.07, 0.98, 0.5, 0.98, 1.07, 1.4, 1.6, 3.0, 10.0},
+				"multipliers_medium": []float64{33.0, 11.0, 4.0, 2.0, 1.1, 0.56, 0.3, 0.56, 1.1, 2.0, 4.0, 11.0, 33.0},
+				"multipliers_high":   []float64{170.0, 24.0, 8.1, 2.0, 0.63, 0.2, 0.2, 0.2, 0.63, 2.0, 8.1, 24.0, 170.0},
 			},
 			UpdatedAt: time.Now(),
 			UpdatedBy: "system",
@@ -158,8 +312,8 @@ func (s *GameSettingsService) InitializeDefaultSettings(ctx context.Context) err
 			GameType:    "dice",
 			DisplayName: "Dice",
 			Enabled:     true,
-			MinBet:      10,
-			MaxBet:      10000,
+			MinBet:      models.MoneyFromMajor(10),
+			MaxBet:      models.MoneyFromMajor(10000),
 			HouseEdge:   1.0,
 			Config: map[string]interface{}{
 				"min_target": 1.00,
@@ -173,8 +327,8 @@ func (s *GameSettingsService) InitializeDefaultSettings(ctx context.Context) err
 			GameType:    "limbo",
 			DisplayName: "Limbo",
 			Enabled:     true,
-			MinBet:      10,
-			MaxBet:      10000,
+			MinBet:      models.MoneyFromMajor(10),
+			MaxBet:      models.MoneyFromMajor(10000),
 			HouseEdge:   1.0,
 			Config: map[string]interface{}{
 				"min_multiplier": 1.01,
@@ -188,8 +342,8 @@ func (s *GameSettingsService) InitializeDefaultSettings(ctx context.Context) err
 			GameType:    "hilo",
 			DisplayName: "Hi-Lo",
 			Enabled:     true,
-			MinBet:      10,
-			MaxBet:      10000,
+			MinBet:      models.MoneyFromMajor(10),
+			MaxBet:      models.MoneyFromMajor(10000),
 			HouseEdge:   2.0,
 			Config: map[string]interface{}{
 				"multiplier_per_win": 1.5,
@@ -202,8 +356,8 @@ func (s *GameSettingsService) InitializeDefaultSettings(ctx context.Context) err
 			GameType:    "blackjack",
 			DisplayName: "Blackjack",
 			Enabled:     true,
-			MinBet:      10,
-			MaxBet:      10000,
+			MinBet:      models.MoneyFromMajor(10),
+			MaxBet:      models.MoneyFromMajor(10000),
 			HouseEdge:   0.5,
 			Config: map[string]interface{}{
 				"num_decks":           6,
@@ -217,8 +371,11 @@ func (s *GameSettingsService) InitializeDefaultSettings(ctx context.Context) err
 			UpdatedBy: "system",
 		},
 	}
+}
 
-	for _, defaultSettings := range defaults {
+// InitializeDefaultSettings creates default settings for all games if they don't exist
+func (s *GameSettingsService) InitializeDefaultSettings(ctx context.Context) error {
+	for _, defaultSettings := range DefaultGameSettings() {
 		// Only insert if doesn't exist
 		filter := bson.M{"game_type": defaultSettings.GameType}
 		count, err := s.collection.CountDocuments(ctx, filter)
@@ -226,12 +383,121 @@ func (s *GameSettingsService) InitializeDefaultSettings(ctx context.Context) err
 			return err
 		}
 		if count == 0 {
+			defaultSettings.Version = 1
 			_, err := s.collection.InsertOne(ctx, defaultSettings)
 			if err != nil {
 				return err
 			}
+			_, err = s.versions.InsertOne(ctx, models.GameSettingsVersion{
+				ID:        fmt.Sprintf("gsv_%s_%d", defaultSettings.GameType, defaultSettings.Version),
+				GameType:  defaultSettings.GameType,
+				Version:   defaultSettings.Version,
+				Action:    "initial",
+				After:     defaultSettings,
+				ChangedBy: defaultSettings.UpdatedBy,
+				ChangedAt: defaultSettings.UpdatedAt,
+			})
+			if err != nil && !mongo.IsDuplicateKeyError(err) {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
+
+// legacySpinWheelMultipliers is the flat wheel earlier releases stored as
+// the default. Played without weights it returns 125%.
+var legacySpinWheelMultipliers = []float64{1.2, 1.5, 2.0, 0, 1.8, 0.5, 3.0, 0}
+
+// legacyPlinkoTables are the 12-row tables earlier releases stored as the
+// default. They have 9, 11 and 13 entries where 12 rows need 13 each.
+var legacyPlinkoTables = [3][]float64{
+	{1.5, 1.3, 1.1, 1.0, 0.5, 1.0, 1.1, 1.3, 1.5},
+	{3.0, 1.6, 1.4, 1.1, 1.0, 0.5, 1.0, 1.1, 1.4, 1.6, 3.0},
+	{10.0, 3.0, 1.6, 1.4, 1.1, 1.0, 0.2, 1.0, 1.1, 1.4, 1.6, 3.0, 10.0},
+}
+
+// legacyAviationChances are the crash chances earlier releases stored as
+// the default. Cashing out at 2.94x returned 147% with them.
+var legacyAviationChances = [4]float64{50, 30, 15, 5}
+
+// isLegacyConfig reports whether a stored config is one earlier releases
+// shipped as a default, which is replaced by the current default rather
+// than kept disabled
+func isLegacyConfig(gameType string, config map[string]interface{}) bool {
+	cfg, err := DecodeGameConfig(gameType, config)
+	if err != nil {
+		return false
+	}
+	switch c := cfg.(type) {
+	case *models.SpinWheelConfig:
+		return len(c.Weights) == 0 && floatsEqual(c.Multipliers, legacySpinWheelMultipliers)
+	case *models.AviationConfig:
+		chances := [4]float64{c.CrashChances.Low, c.CrashChances.Medium, c.CrashChances.High, c.CrashChances.VeryHigh}
+		return c.MinMultiplier == 1.01 && c.MaxMultiplier == 50 && chances == legacyAviationChances
+	case *models.PlinkoConfig:
+		return c.Rows == 12 &&
+			floatsEqual(c.MultipliersLow, legacyPlinkoTables[0]) &&
+			floatsEqual(c.MultipliersMedium, legacyPlinkoTables[1]) &&
+			floatsEqual(c.MultipliersHigh, legacyPlinkoTables[2])
+	}
+	return false
+}
+
+func floatsEqual(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// MigrateStoredSettings re-validates every stored settings document. Legacy
+// default configs are replaced by the current default, keeping the bet
+// limits and enabled flag; any other invalid config is disabled so it can't
+// be played until an admin fixes it. Both are recorded as versions.
+func (s *GameSettingsService) MigrateStoredSettings(ctx context.Context) error {
+	stored, err := s.GetAllGameSettings(ctx)
+	if err != nil {
+		return err
+	}
+	defaults := make(map[string]models.GameSettings)
+	for _, settings := range DefaultGameSettings() {
+		defaults[settings.GameType] = settings
+	}
+
+	for _, settings := range stored {
+		invalid := ValidateGameSettings(settings.GameType, &settings)
+		if invalid == nil {
+			continue
+		}
+
+		if def, ok := defaults[settings.GameType]; ok && isLegacyConfig(settings.GameType, settings.Config) {
+			migrated := settings
+			migrated.HouseEdge = def.HouseEdge
+			migrated.Config = def.Config
+			err := s.applySettings(ctx, settings.GameType, &migrated, "system", "migrate", 0)
+			if err == nil {
+				log.Printf("[Settings] ✅ Replaced legacy %s config with the default (v%d)\n", settings.GameType, migrated.Version)
+				continue
+			}
+			log.Printf("[Settings] ❌ Failed to migrate legacy %s config: %v\n", settings.GameType, err)
+		}
+
+		if !settings.Enabled {
+			continue
+		}
+		disabled := settings
+		disabled.Enabled = false
+		if err := s.writeSettings(ctx, settings.GameType, &disabled, "system", "disable", 0); err != nil {
+			return fmt.Errorf("failed to disable invalid %s settings: %w", settings.GameType, err)
+		}
+		log.Printf("[Settings] ❌ Disabled %s, its stored config is invalid: %v\n", settings.GameType, invalid)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"betting-app-backend-go/models"
+)
+
+// hiloRanks orders the ranks from lowest (ace) to highest (king)
+const hiloRanks = "A23456789TJQK"
+
+// HiLoEngine plays hi-lo as a session. A starting card is drawn and the
+// player guesses whether the next card is strictly higher or lower, or
+// skips it. Each correct guess multiplies the session multiplier; a wrong
+// guess loses the round. The player may cash out after any correct guess
+// and the round is cashed out automatically after MaxStreak of them.
+type HiLoEngine struct{}
+
+func (e *HiLoEngine) GameType() string { return "hilo" }
+
+func (e *HiLoEngine) Start(session *models.GameSession, settings *models.GameSettings, choices map[string]interface{}, rng RandomSource) error {
+	session.HiLo = &models.HiLoState{
+		Cards:   []string{e.draw(rng)},
+		Guesses: []models.HiLoGuess{},
+	}
+	session.Multiplier = 1
+	return nil
+}
+
+func (e *HiLoEngine) Act(session *models.GameSession, settings *models.GameSettings, action string, params map[string]interface{}, rng RandomSource) error {
+	var cfg models.HiLoConfig
+	if err := decodeConfig(settings.Config, &cfg); err != nil {
+		return err
+	}
+	state := session.HiLo
+	current := state.Cards[len(state.Cards)-1]
+
+	switch action {
+	case "cashout":
+		if state.Streak == 0 {
+			return fmt.Errorf("%w: make at least one correct guess before cashing out", ErrInvalidChoice)
+		}
+		session.Outcome = "cashed_out"
+		return nil
+
+	case "skip":
+		card := e.draw(rng)
+		state.Cards = append(state.Cards, card)
+		state.Guesses = append(state.Guesses, models.HiLoGuess{
+			Guess: "skip", From: current, Card: card, Multiplier: session.Multiplier,
+		})
+		return nil
+
+	case "higher", "lower":
+		factor, err := hiloFactor(current, action, settings.HouseEdge, cfg.MultiplierPerWin)
+		if err != nil {
+			return err
+		}
+
+		card := e.draw(rng)
+		state.Cards = append(state.Cards, card)
+		won := hiloRank(card) > hiloRank(current)
+		if action == "lower" {
+			won = hiloRank(card) < hiloRank(current)
+		}
+
+		if !won {
+			session.Multiplier = 0
+			session.Outcome = "busted"
+		} else {
+			session.Multiplier = models.RoundMultiplier(session.Multiplier * factor)
+			state.Streak++
+			if state.Streak >= cfg.MaxStreak {
+				session.Outcome = "cashed_out"
+			}
+		}
+		state.Guesses = append(state.Guesses, models.HiLoGuess{
+			Guess: action, From: current, Card: card, Won: won, Multiplier: session.Multiplier,
+		})
+		return nil
+	}
+
+	return fmt.Errorf("%w: unknown action %q, expected higher, lower, skip or cashout", ErrInvalidChoice, action)
+}
+
+// Expire cashes out at the current multiplier
+func (e *HiLoEngine) Expire(session *models.GameSession, settings *models.GameSettings) {
+	session.Outcome = "expired"
+}
+
+func (e *HiLoEngine) ResultData(session *models.GameSession) map[string]interface{} {
+	state := session.HiLo
+	return map[string]interface{}{
+		"cards":   state.Cards,
+		"guesses": state.Guesses,
+		"streak":  state.Streak,
+		"outcome": session.Outcome,
+	}
+}
+
+// draw picks a card uniformly from a full deck: rank first, then suit
+func (e *HiLoEngine) draw(rng RandomSource) string {
+	i := pickIndex(rng, 52)
+	return string(hiloRanks[i%13]) + string("SHDC"[i/13])
+}
+
+// hiloRank is 1 for an ace up to 13 for a king
+func hiloRank(card string) int {
+	return strings.IndexByte(hiloRanks, card[0]) + 1
+}
+
+// hiloFactor is what a correct guess multiplies the session multiplier by:
+// MultiplierPerWin, lowered for likely guesses so that no guess returns
+// more than 1 - house edge on average. Ties lose, so guessing higher on a
+// king or lower on an ace can never win and is rejected.
+func hiloFactor(current, guess string, houseEdge, perWin float64) (float64, error) {
+	rank := hiloRank(current)
+	winning := 13 - rank // ranks above
+	if guess == "lower" {
+		winning = rank - 1
+	}
+	if winning == 0 {
+		return 0, fmt.Errorf("%w: no card is %s than %s", ErrInvalidChoice, guess, current)
+	}
+
+	// Round down so rounding never tips a guess over the edge
+	fair := math.Floor((1-houseEdge/100)*13/float64(winning)*10000) / 10000
+	factor := math.Min(perWin, fair)
+	if factor <= 1 {
+		return 0, fmt.Errorf("%w: guessing %s on %s can't pay out", ErrInvalidChoice, guess, current)
+	}
+	return factor, nil
+}
@@ -0,0 +1,89 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"betting-app-backend-go/models"
+	"betting-app-backend-go/services"
+)
+
+// TestHiLoFactor pins what a correct guess multiplies the session by
+func TestHiLoFactor(t *testing.T) {
+	tests := []struct {
+		card, guess       string
+		houseEdge, perWin float64
+		want              float64
+	}{
+		{"7S", "higher", 2, 1.5, 1.5},
+		{"QS", "higher", 2, 1.5, 1.5},
+		{"2S", "higher", 2, 1.5, 1.1581},
+		{"AS", "higher", 2, 1.5, 1.0616},
+		{"KS", "lower", 2, 1.5, 1.0616},
+		{"7S", "higher", 2, 100, 2.1233},
+		{"QS", "higher", 2, 100, 12.74},
+		{"8H", "lower", 2, 100, 1.82},
+	}
+	for _, tt := range tests {
+		got, err := services.HiLoFactor(tt.card, tt.guess, tt.houseEdge, tt.perWin)
+		if err != nil || got != tt.want {
+			t.Errorf("hiloFactor(%s %s, edge %g, per win %g) = %v, %v, want %v",
+				tt.card, tt.guess, tt.houseEdge, tt.perWin, got, err, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		card, guess string
+		houseEdge   float64
+	}{
+		{"KS", "higher", 2},
+		{"AS", "lower", 2},
+		{"AS", "higher", 10},
+	} {
+		if _, err := services.HiLoFactor(tt.card, tt.guess, tt.houseEdge, 100); !errors.Is(err, services.ErrInvalidChoice) {
+			t.Errorf("hiloFactor(%s %s, edge %g) = %v, want ErrInvalidChoice", tt.card, tt.guess, tt.houseEdge, err)
+		}
+	}
+}
+
+// TestHiLoReturn plays every guess from every starting rank against each
+// of the 52 next cards. Guesses paid the fair factor return 1 - house
+// edge; the multiplier_per_win cap only ever lowers that.
+func TestHiLoReturn(t *testing.T) {
+	engine := &services.HiLoEngine{}
+	capped := defaultSettings(t, "hilo")
+	uncapped := defaultSettings(t, "hilo")
+	uncapped.Config["multiplier_per_win"] = 100.0
+	target := 1 - capped.HouseEdge/100
+
+	for start := 0; start < 13; start++ {
+		for _, guess := range []string{"higher", "lower"} {
+			if (start == 12 && guess == "higher") || (start == 0 && guess == "lower") {
+				continue
+			}
+			for _, settings := range []*models.GameSettings{capped, uncapped} {
+				total := 0.0
+				for next := 0; next < 52; next++ {
+					rng := &sequenceSource{values: []float64{(float64(start) + 0.5) / 52, (float64(next) + 0.5) / 52}}
+					session := &models.GameSession{GameType: "hilo"}
+					if err := engine.Start(session, settings, nil, rng); err != nil {
+						t.Fatalf("Start: %v", err)
+					}
+					if err := engine.Act(session, settings, guess, nil, rng); err != nil {
+						t.Fatalf("%s on %s: %v", guess, session.HiLo.Cards[0], err)
+					}
+					total += session.Multiplier
+				}
+
+				played := total / 52
+				from := string("A23456789TJQK"[start])
+				if played > target+1e-9 {
+					t.Errorf("%s on %s returns %.5f, above %.2f", guess, from, played, target)
+				}
+				if settings == uncapped && played < target-1e-4 {
+					t.Errorf("%s on %s at the fair factor returns %.5f, want %.2f", guess, from, played, target)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"betting-app-backend-go/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Errors returned when an Idempotency-Key can't be used for a request
+var (
+	ErrIdempotencyKeyReused    = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyKeyInFlight  = errors.New("a request with this idempotency key is still being processed")
+	ErrIdempotencyKeyMalformed = errors.New("idempotency key must be 1 to 255 characters")
+)
+
+const (
+	// idempotencyTTL is how long a stored response can be replayed
+	idempotencyTTL = 24 * time.Hour
+	// idempotencyLock is how long a request may hold its key while being
+	// processed before a retry may take over
+	idempotencyLock = time.Minute
+)
+
+// IdempotencyService reserves Idempotency-Key values per user and stores the
+// response of the request that used them.
+//
+// The key, the response and the request's own writes are not stored in one
+// MongoDB transaction; that would need a replica set, which the server
+// deliberately does without. Unique indexes take its place. The reservation
+// here keeps two requests with a key from running at once. RecordGame and
+// CreatePaymentRequest store the key on the game or payment request under a
+// unique (user_id, idempotency_key) index, and insert it before any money
+// moves. A retry whose stored response was lost, or whose key was released,
+// finds that record and returns it instead of charging again.
+type IdempotencyService struct {
+	collection *mongo.Collection
+}
+
+func NewIdempotencyService(db *mongo.Database) *IdempotencyService {
+	return &IdempotencyService{collection: db.Collection("idempotency_keys")}
+}
+
+// ValidIdempotencyKey reports whether key can be used as an Idempotency-Key
+func ValidIdempotencyKey(key string) bool {
+	return len(key) > 0 && len(key) <= 255
+}
+
+// Begin reserves key for a request. It returns the stored record and true
+// when the request was already completed and its response should be
+// replayed. ErrIdempotencyKeyReused is returned when the key was used for a
+// different request, ErrIdempotencyKeyInFlight while the first request is
+// still running.
+func (s *IdempotencyService) Begin(ctx context.Context, userID, key, method, path, requestHash string) (*models.IdempotencyRecord, bool, error) {
+	if !ValidIdempotencyKey(key) {
+		return nil, false, ErrIdempotencyKeyMalformed
+	}
+
+	now := time.Now()
+	record := &models.IdempotencyRecord{
+		ID:          fmt.Sprintf("idem_%d", now.UnixNano()),
+		UserID:      userID,
+		Key:         key,
+		Method:      method,
+		Path:        path,
+		RequestHash: requestHash,
+		Status:      "processing",
+		LockedUntil: now.Add(idempotencyLock),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(idempotencyTTL),
+	}
+
+	// The unique (user_id, key) index makes the insert the reservation
+	_, err := s.collection.InsertOne(ctx, record)
+	if err == nil {
+		return record, false, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	var existing models.IdempotencyRecord
+	err = s.collection.FindOne(ctx, bson.M{"user_id": userID, "key": key}).Decode(&existing)
+	if err == mongo.ErrNoDocuments {
+		// Expired or released between the insert and the read
+		return s.Begin(ctx, userID, key, method, path, requestHash)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	if existing.RequestHash != requestHash {
+		return nil, false, ErrIdempotencyKeyReused
+	}
+	if existing.Status == "completed" {
+		return &existing, true, nil
+	}
+
+	// Take over a request that has held the key for too long, e.g. because
+	// the server handling it stopped
+	var takenOver models.IdempotencyRecord
+	err = s.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": existing.ID, "status": "processing", "locked_until": bson.M{"$lt": now}},
+		bson.M{"$set": bson.M{"locked_until": now.Add(idempotencyLock)}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&takenOver)
+	if err == mongo.ErrNoDocuments {
+		return nil, false, ErrIdempotencyKeyInFlight
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to take over idempotency key: %w", err)
+	}
+	return &takenOver, false, nil
+}
+
+// Complete stores the response of the request that reserved record
+func (s *IdempotencyService) Complete(ctx context.Context, record *models.IdempotencyRecord, status int, contentType string, body []byte) error {
+	_, err := s.collection.UpdateOne(ctx, bson.M{"_id": record.ID}, bson.M{"$set": bson.M{
+		"status":                "completed",
+		"response_status":       status,
+		"response_content_type": contentType,
+		"response_body":         body,
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release frees the key so the request can be retried, used when the
+// request failed with a server error
+func (s *IdempotencyService) Release(ctx context.Context, record *models.IdempotencyRecord) error {
+	_, err := s.collection.DeleteOne(ctx, bson.M{"_id": record.ID, "status": "processing"})
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"time"
+
+	"betting-app-backend-go/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxWalletMismatches caps how many mismatching wallets a trial balance lists
+const maxWalletMismatches = 100
+
+// LedgerService writes and reports on the double-entry ledger. Each entry is
+// one document holding all of its postings, so an entry is always written
+// (or not) as a whole and never leaves the ledger unbalanced.
+type LedgerService struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+func NewLedgerService(db *mongo.Database) *LedgerService {
+	return &LedgerService{db: db, collection: db.Collection("ledger_entries")}
+}
+
+// transfer builds the two postings that move amount from one account to
+// another: the source account is debited and the destination credited
+func transfer(from, to string, amount models.Money) []models.LedgerPosting {
+	return []models.LedgerPosting{
+		{Account: from, Debit: amount},
+		{Account: to, Credit: amount},
+	}
+}
+
+// Post validates and stores a journal entry
+func (s *LedgerService) Post(ctx context.Context, entry *models.LedgerEntry) error {
+	if len(entry.Postings) < 2 {
+		return fmt.Errorf("ledger entry needs at least two postings")
+	}
+	var debit, credit models.Money
+	for _, p := range entry.Postings {
+		if p.Account == "" {
+			return fmt.Errorf("ledger posting without account")
+		}
+		if p.Debit < 0 || p.Credit < 0 || (p.Debit == 0) == (p.Credit == 0) {
+			return fmt.Errorf("ledger posting on %s must have exactly one positive side", p.Account)
+		}
+		debit += p.Debit
+		credit += p.Credit
+	}
+	if debit != credit {
+		return fmt.Errorf("ledger entry is unbalanced: debit %s, credit %s", debit, credit)
+	}
+
+	if entry.ID == "" {
+		entry.ID = "led_" + primitive.NewObjectID().Hex()
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	if _, err := s.collection.InsertOne(ctx, entry); err != nil {
+		return fmt.Errorf("failed to post ledger entry: %w", err)
+	}
+	return nil
+}
+
+// Referenced reports whether the user has an entry of category for
+// reference
+func (s *LedgerService) Referenced(ctx context.Context, userID string, category string, reference string) (bool, error) {
+	count, err := s.collection.CountDocuments(ctx, bson.M{"user_id": userID, "category": category, "reference": reference})
+	if err != nil {
+		return false, fmt.Errorf("failed to get ledger entries: %w", err)
+	}
+	return count > 0, nil
+}
+
+// EntriesUnder returns the user's entries of category whose reference
+// starts with prefix
+func (s *LedgerService) EntriesUnder(ctx context.Context, userID string, category string, prefix string) ([]models.LedgerEntry, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{
+		"user_id":   userID,
+		"category":  category,
+		"reference": bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger entries: %w", err)
+	}
+	var entries []models.LedgerEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode ledger entries: %w", err)
+	}
+	return entries, nil
+}
+
+// GetUserTransactions projects the entries that moved a user's wallet into
+// the Transaction shape used by the wallet API, newest first
+func (s *LedgerService) GetUserTransactions(ctx context.Context, userID string, limit int64) ([]models.Transaction, error) {
+	account := models.UserAccount(userID)
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(limit)
+
+	cursor, err := s.collection.Find(ctx, bson.M{"postings.account": account}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger entries: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.LedgerEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode ledger entries: %w", err)
+	}
+
+	transactions := make([]models.Transaction, 0, len(entries))
+	for _, entry := range entries {
+		txn := models.Transaction{
+			ID:            entry.ID,
+			UserID:        userID,
+			Description:   entry.Description,
+			Category:      entry.Category,
+			BalanceBefore: entry.BalanceBefore,
+			BalanceAfter:  entry.BalanceAfter,
+			WalletVersion: entry.WalletVersion,
+			Status:        "completed",
+			CreatedAt:     entry.CreatedAt,
+		}
+		for _, p := range entry.Postings {
+			if p.Account != account {
+				continue
+			}
+			// A user account is a liability of the house: a credit adds to
+			// the wallet and a debit takes from it
+			if p.Credit > 0 {
+				txn.Type = "credit"
+				txn.Amount += p.Credit
+			} else {
+				txn.Type = "debit"
+				txn.Amount += p.Debit
+			}
+		}
+		transactions = append(transactions, txn)
+	}
+	return transactions, nil
+}
+
+// TrialBalance totals every account and checks that debits equal credits.
+// User wallet accounts are summed into a single "user:*" line and compared
+// against the wallets collection.
+func (s *LedgerService) TrialBalance(ctx context.Context) (*models.TrialBalance, error) {
+	cursor, err := s.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$unwind", Value: "$postings"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    "$postings.account",
+			"debit":  bson.M{"$sum": "$postings.debit"},
+			"credit": bson.M{"$sum": "$postings.credit"},
+		}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate ledger: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []bson.M
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode ledger totals: %w", err)
+	}
+
+	result := &models.TrialBalance{
+		Accounts:         []models.TrialBalanceLine{},
+		WalletMismatches: []models.WalletMismatch{},
+		GeneratedAt:      time.Now(),
+	}
+	users := models.TrialBalanceLine{Account: "user:*"}
+	userBalances := make(map[string]models.Money)
+	for _, row := range rows {
+		account, _ := row["_id"].(string)
+		line := models.TrialBalanceLine{
+			Account: account,
+			Debit:   models.MoneyFromAny(row["debit"]),
+			Credit:  models.MoneyFromAny(row["credit"]),
+		}
+		result.TotalDebit += line.Debit
+		result.TotalCredit += line.Credit
+
+		if models.IsUserAccount(account) {
+			users.Debit += line.Debit
+			users.Credit += line.Credit
+			userBalances[account] = line.Credit - line.Debit
+			continue
+		}
+		line.Balance = line.Debit - line.Credit
+		result.Accounts = append(result.Accounts, line)
+	}
+	users.Balance = users.Debit - users.Credit
+	result.Accounts = append(result.Accounts, users)
+	result.UserAccounts = len(userBalances)
+	sort.Slice(result.Accounts, func(i, j int) bool { return result.Accounts[i].Account < result.Accounts[j].Account })
+
+	result.UnbalancedEntries, err = s.countUnbalancedEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result.Balanced = result.TotalDebit == result.TotalCredit && result.UnbalancedEntries == 0
+
+	if err := s.reconcileWallets(ctx, userBalances, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *LedgerService) countUnbalancedEntries(ctx context.Context) (int64, error) {
+	count, err := s.collection.CountDocuments(ctx, bson.M{
+		"$expr": bson.M{"$ne": bson.A{
+			bson.M{"$sum": "$postings.debit"},
+			bson.M{"$sum": "$postings.credit"},
+		}},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unbalanced entries: %w", err)
+	}
+	return count, nil
+}
+
+// reconcileWallets lists wallets whose balance differs from their ledger
+// account
+func (s *LedgerService) reconcileWallets(ctx context.Context, userBalances map[string]models.Money, result *models.TrialBalance) error {
+	opts := options.Find().SetProjection(bson.M{"user_id": 1, "balance": 1})
+	cursor, err := s.db.Collection("wallets").Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return fmt.Errorf("failed to get wallets: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) && len(result.WalletMismatches) < maxWalletMismatches {
+		var wallet models.Wallet
+		if err := cursor.Decode(&wallet); err != nil {
+			return fmt.Errorf("failed to decode wallet: %w", err)
+		}
+		ledgerBalance := userBalances[models.UserAccount(wallet.UserID)]
+		if ledgerBalance != wallet.Balance {
+			result.WalletMismatches = append(result.WalletMismatches, models.WalletMismatch{
+				UserID:        wallet.UserID,
+				WalletBalance: wallet.Balance,
+				LedgerBalance: ledgerBalance,
+			})
+		}
+	}
+	return cursor.Err()
+}
+
+// EnsureOpeningBalances posts an opening entry for every wallet that has a
+// balance but no ledger account yet, i.e. wallets funded before the ledger
+// existed. The entry id is derived from the user id, so running it again or
+// on several instances never posts twice.
+func (s *LedgerService) EnsureOpeningBalances(ctx context.Context) error {
+	cursor, err := s.db.Collection("wallets").Find(ctx, bson.M{"balance": bson.M{"$gt": 0}})
+	if err != nil {
+		return fmt.Errorf("failed to get wallets: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	posted := 0
+	for cursor.Next(ctx) {
+		var wallet models.Wallet
+		if err := cursor.Decode(&wallet); err != nil {
+			return fmt.Errorf("failed to decode wallet: %w", err)
+		}
+
+		account := models.UserAccount(wallet.UserID)
+		count, err := s.collection.CountDocuments(ctx, bson.M{"postings.account": account}, options.Count().SetLimit(1))
+		if err != nil {
+			return fmt.Errorf("failed to check ledger account: %w", err)
+		}
+		if count > 0 {
+			continue
+		}
+
+		err = s.Post(ctx, &models.LedgerEntry{
+			ID:            "opening_" + wallet.UserID,
+			UserID:        wallet.UserID,
+			Category:      "opening_balance",
+			Description:   "Opening balance",
+			Postings:      transfer(models.AccountHouseBankroll, account, wallet.Balance),
+			BalanceAfter:  wallet.Balance,
+			WalletVersion: wallet.Version,
+		})
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		posted++
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to read wallets: %w", err)
+	}
+
+	if posted > 0 {
+		log.Printf("[Ledger] ✅ Posted opening balances for %d wallets\n", posted)
+	}
+	return nil
+}
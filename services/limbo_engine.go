@@ -0,0 +1,68 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"betting-app-backend-go/models"
+)
+
+// LimboEngine rolls an outcome multiplier and pays the player's target
+// multiplier if the roll reaches it. The roll is (1 - house edge) / (1 - u)
+// for a uniform u, so a target t is reached with probability
+// (1 - house edge) / t and every target returns 1 - house edge on average.
+type LimboEngine struct{}
+
+func (e *LimboEngine) GameType() string { return "limbo" }
+
+func (e *LimboEngine) Play(req *PlayRequest, settings *models.GameSettings, rng RandomSource) (*models.Game, error) {
+	var cfg models.LimboConfig
+	if err := decodeConfig(settings.Config, &cfg); err != nil {
+		return nil, err
+	}
+
+	target, err := choiceFloat(req.Choices, "target")
+	if err != nil {
+		return nil, err
+	}
+
+	// Targets are on the same 0.01 grid as the roll
+	if math.Abs(target*100-math.Round(target*100)) > 1e-6 {
+		return nil, fmt.Errorf("%w: target must have at most 2 decimal places", ErrInvalidChoice)
+	}
+	target = math.Round(target*100) / 100
+	if target < cfg.MinMultiplier || target > cfg.MaxMultiplier {
+		return nil, fmt.Errorf("%w: target must be between %.2f and %.2f", ErrInvalidChoice, cfg.MinMultiplier, cfg.MaxMultiplier)
+	}
+
+	// Work in hundredths to avoid float comparisons. Capping at the maximum
+	// never turns a win into a loss since targets are at most the maximum.
+	u := rng.Float64()
+	rollHundredths := int64(math.Floor(100 * (1 - cfg.HouseEdge/100) / (1 - u)))
+	rollHundredths = min(max(rollHundredths, 100), int64(math.Round(cfg.MaxMultiplier*100)))
+	targetHundredths := int64(math.Round(target * 100))
+	roll := float64(rollHundredths) / 100
+
+	won := rollHundredths >= targetHundredths
+	payout := 0.0
+	if won {
+		payout = target
+	}
+
+	return settle(req, payout, map[string]interface{}{
+		"roll":       roll,
+		"target":     target,
+		"win_chance": math.Round((100-cfg.HouseEdge)/target*100) / 100,
+		"won":        won,
+	}), nil
+}
+
+// ExpectedReturn is 1 - the config's house edge, which sets the odds of
+// every target
+func (e *LimboEngine) ExpectedReturn(settings *models.GameSettings) (float64, error) {
+	var cfg models.LimboConfig
+	if err := decodeConfig(settings.Config, &cfg); err != nil {
+		return 0, err
+	}
+	return 1 - cfg.HouseEdge/100, nil
+}
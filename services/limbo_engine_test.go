@@ -0,0 +1,110 @@
+package services_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"betting-app-backend-go/models"
+	"betting-app-backend-go/services"
+)
+
+func limboSettings(minMultiplier float64) *models.GameSettings {
+	return &models.GameSettings{
+		GameType:  "limbo",
+		MinBet:    models.MoneyFromMajor(1),
+		MaxBet:    models.MoneyFromMajor(100),
+		HouseEdge: 1,
+		Config: map[string]interface{}{
+			"min_multiplier": minMultiplier,
+			"max_multiplier": 1000.0,
+			"house_edge":     1.0,
+		},
+	}
+}
+
+// TestLimboRoll pins the roll drawn from u and that reaching the target
+// exactly wins
+func TestLimboRoll(t *testing.T) {
+	engine := &services.LimboEngine{}
+	settings := limboSettings(1.01)
+
+	tests := []struct {
+		u      float64
+		target float64
+		roll   float64
+		won    bool
+	}{
+		{0, 1.01, 1, false},
+		{0.5, 1.98, 1.98, true},
+		{0.5, 1.99, 1.98, false},
+		{0.75, 2, 3.96, true},
+		{0.9, 9.9, 9.9, true},
+		{0.999999, 1000, 1000, true},
+	}
+	for _, tt := range tests {
+		req := &services.PlayRequest{
+			GameType:  "limbo",
+			BetAmount: models.MoneyFromMajor(1),
+			Choices:   map[string]interface{}{"target": tt.target},
+		}
+		game, err := engine.Play(req, settings, &sequenceSource{values: []float64{tt.u}})
+		if err != nil {
+			t.Fatalf("Play: %v", err)
+		}
+		if game.ResultData["roll"] != tt.roll || game.ResultData["won"] != tt.won {
+			t.Errorf("u %v, target %v: rolled %v, won %v, want %v and %v", tt.u, tt.target,
+				game.ResultData["roll"], game.ResultData["won"], tt.roll, tt.won)
+		}
+		if want := math.Round(99/tt.target*100) / 100; game.ResultData["win_chance"] != want {
+			t.Errorf("target %v: win_chance %v, want %v", tt.target, game.ResultData["win_chance"], want)
+		}
+	}
+}
+
+// TestLimboTargetReturn plays targets from the lowest up over an even grid
+// of rolls and checks each returns 1 - house edge, not the whole stake
+func TestLimboTargetReturn(t *testing.T) {
+	engine := &services.LimboEngine{}
+	settings := limboSettings(1.01)
+
+	const steps = 100000
+	for _, target := range []float64{1.01, 1.5, 2, 10, 99.99, 1000} {
+		total := 0.0
+		for i := 0; i < steps; i++ {
+			req := &services.PlayRequest{
+				GameType:  "limbo",
+				BetAmount: models.MoneyFromMajor(1),
+				Choices:   map[string]interface{}{"target": target},
+			}
+			game, err := engine.Play(req, settings, &sequenceSource{values: []float64{(float64(i) + 0.5) / steps}})
+			if err != nil {
+				t.Fatalf("Play: %v", err)
+			}
+			total += game.Multiplier
+		}
+
+		// The grid decides each win to within one step, worth target/steps
+		played := total / steps
+		if want := 0.99; math.Abs(played-want) > 1e-4+target/steps {
+			t.Errorf("target %v returns %.5f, want %.2f", target, played, want)
+		}
+	}
+}
+
+// TestLimboRejectsTargetOfOne checks a config that would allow a 1.00
+// target, which always wins, is refused
+func TestLimboRejectsTargetOfOne(t *testing.T) {
+	err := services.ValidateGameSettings("limbo", limboSettings(1))
+	var verr *services.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("ValidateGameSettings = %v, want a *ValidationError", err)
+	}
+	if len(verr.Fields) != 1 || verr.Fields[0].Field != "config.min_multiplier" {
+		t.Errorf("ValidateGameSettings rejected %+v, want only config.min_multiplier", verr.Fields)
+	}
+
+	if err := services.ValidateGameSettings("limbo", limboSettings(1.01)); err != nil {
+		t.Errorf("ValidateGameSettings(min 1.01) = %v, want nil", err)
+	}
+}
@@ -0,0 +1,331 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"betting-app-backend-go/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Errors returned by LimitService
+var (
+	ErrLimitExceeded = errors.New("limit reached")
+	ErrInvalidLimit  = errors.New("invalid limit")
+	ErrLimitConflict = errors.New("limits were changed by another request")
+)
+
+// limitCoolingOff is how long a raised or removed limit waits before it
+// takes effect
+const limitCoolingOff = 24 * time.Hour
+
+// limitLocation is the time zone limit periods follow. India has no
+// daylight saving, so a fixed offset is exact.
+var limitLocation = time.FixedZone("IST", 5*60*60+30*60)
+
+// LimitDelta is a change to a player's running totals
+type LimitDelta struct {
+	Deposit models.Money
+	Wager   models.Money
+	Loss    models.Money
+}
+
+func (d LimitDelta) amount(kind string) models.Money {
+	switch kind {
+	case "deposit":
+		return d.Deposit
+	case "wager":
+		return d.Wager
+	case "loss":
+		return d.Loss
+	}
+	return 0
+}
+
+// LimitService keeps players' deposit, loss and wager limits and their
+// usage. Usage is one document per player and period, and every change is
+// a single conditional $inc guarded by the limits it must stay within, so
+// concurrent bets can never take a player past a limit.
+type LimitService struct {
+	limits     *mongo.Collection
+	usage      *mongo.Collection
+	coolingOff time.Duration
+}
+
+func NewLimitService(db *mongo.Database) *LimitService {
+	return &LimitService{
+		limits:     db.Collection("player_limits"),
+		usage:      db.Collection("limit_usage"),
+		coolingOff: limitCoolingOff,
+	}
+}
+
+// periodStart returns the start of the day, week (from Monday) or month
+// containing at
+func periodStart(period string, at time.Time) time.Time {
+	t := at.In(limitLocation)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, limitLocation)
+	switch period {
+	case "week":
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case "month":
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day
+}
+
+// periodEnd returns the end of the period starting at start
+func periodEnd(period string, start time.Time) time.Time {
+	switch period {
+	case "week":
+		return start.AddDate(0, 0, 7)
+	case "month":
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+func usageID(userID, period string, start time.Time) string {
+	return fmt.Sprintf("%s:%s:%s", userID, period, start.Format("2006-01-02"))
+}
+
+var periodAdjectives = map[string]string{"day": "daily", "week": "weekly", "month": "monthly"}
+
+func limitExceeded(kind, period string, limit models.Money) error {
+	return fmt.Errorf("%w: your %s %s limit is %s", ErrLimitExceeded, periodAdjectives[period], kind, limit)
+}
+
+func validLimit(kind, period string) bool {
+	return slices.Contains(models.LimitKinds, kind) && slices.Contains(models.LimitPeriods, period)
+}
+
+// GetLimits returns the limits a player has set, none if they never did
+func (s *LimitService) GetLimits(ctx context.Context, userID string) (*models.PlayerLimits, error) {
+	var limits models.PlayerLimits
+	err := s.limits.FindOne(ctx, bson.M{"_id": userID}).Decode(&limits)
+	if err == mongo.ErrNoDocuments {
+		return &models.PlayerLimits{UserID: userID, Limits: map[string]*models.PlayerLimit{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get limits: %w", err)
+	}
+	if limits.Limits == nil {
+		limits.Limits = map[string]*models.PlayerLimit{}
+	}
+	return &limits, nil
+}
+
+// SetLimit changes one of a player's limits. A lower limit applies at
+// once; a higher one, or removing the limit (amount 0), only after the
+// cooling-off delay, until which the current limit stays in force. Setting
+// the limit in force again cancels a pending change.
+func (s *LimitService) SetLimit(ctx context.Context, userID, kind, period string, amount models.Money) error {
+	if !validLimit(kind, period) {
+		return fmt.Errorf("%w: unknown limit %s per %s", ErrInvalidLimit, kind, period)
+	}
+	if amount < 0 {
+		return fmt.Errorf("%w: amount must not be negative", ErrInvalidLimit)
+	}
+	key := models.LimitKey(kind, period)
+
+	// Read, decide and write back only if nobody changed the limits since
+	for attempt := 0; attempt < 3; attempt++ {
+		current, err := s.GetLimits(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		limit := &models.PlayerLimit{}
+		if existing := current.Limits[key]; existing != nil {
+			*limit = *existing
+		}
+		// A change whose cooling-off is over is simply the limit now
+		limit.Amount = limit.Effective(now)
+		limit.PendingAmount = 0
+		limit.PendingFrom = nil
+
+		if amount != limit.Amount {
+			if amount > 0 && (limit.Amount == 0 || amount < limit.Amount) {
+				limit.Amount = amount
+			} else {
+				from := now.Add(s.coolingOff)
+				limit.PendingAmount = amount
+				limit.PendingFrom = &from
+			}
+		}
+		limit.UpdatedAt = now
+
+		if current.Version == 0 {
+			_, err = s.limits.InsertOne(ctx, models.PlayerLimits{
+				UserID:    userID,
+				Limits:    map[string]*models.PlayerLimit{key: limit},
+				Version:   1,
+				UpdatedAt: now,
+			})
+			if mongo.IsDuplicateKeyError(err) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to set limit: %w", err)
+			}
+			return nil
+		}
+
+		res, err := s.limits.UpdateOne(ctx, bson.M{"_id": userID, "version": current.Version}, bson.M{
+			"$set": bson.M{"limits." + key: limit, "updated_at": now},
+			"$inc": bson.M{"version": 1},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set limit: %w", err)
+		}
+		if res.MatchedCount == 1 {
+			return nil
+		}
+	}
+	return ErrLimitConflict
+}
+
+// Status returns every limit with the player's usage in its current period
+func (s *LimitService) Status(ctx context.Context, userID string) ([]models.LimitStatus, error) {
+	limits, err := s.GetLimits(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	statuses := []models.LimitStatus{}
+	for _, period := range models.LimitPeriods {
+		start := periodStart(period, now)
+		var usage models.LimitUsage
+		err := s.usage.FindOne(ctx, bson.M{"_id": usageID(userID, period, start)}).Decode(&usage)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("failed to get limit usage: %w", err)
+		}
+
+		totals := LimitDelta{Deposit: usage.Deposit, Wager: usage.Wager, Loss: usage.Loss}
+		for _, kind := range models.LimitKinds {
+			used := totals.amount(kind)
+			limit := limits.Limits[models.LimitKey(kind, period)]
+			status := models.LimitStatus{
+				Kind:          kind,
+				Period:        period,
+				Limit:         limit.Effective(now),
+				Used:          used,
+				PeriodStart:   start,
+				PeriodResetAt: periodEnd(period, start),
+			}
+			if status.Limit > 0 {
+				remaining := max(status.Limit-used, 0)
+				status.Remaining = &remaining
+			}
+			if limit != nil && limit.PendingFrom != nil && now.Before(*limit.PendingFrom) {
+				pending := limit.PendingAmount
+				status.PendingLimit = &pending
+				status.PendingFrom = limit.PendingFrom
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, nil
+}
+
+// Reserve adds delta to the player's usage of every period containing at,
+// failing with ErrLimitExceeded if that would take any total past its
+// limit. Either every period is updated or none is.
+func (s *LimitService) Reserve(ctx context.Context, userID string, at time.Time, delta LimitDelta) error {
+	limits, err := s.GetLimits(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for i, period := range models.LimitPeriods {
+		start := periodStart(period, at)
+		filter := bson.M{"_id": usageID(userID, period, start)}
+		for _, kind := range models.LimitKinds {
+			amount := delta.amount(kind)
+			limit := limits.Limits[models.LimitKey(kind, period)].Effective(at)
+			if amount <= 0 || limit <= 0 {
+				continue
+			}
+			if amount > limit {
+				s.release(ctx, userID, at, models.LimitPeriods[:i], delta)
+				return limitExceeded(kind, period, limit)
+			}
+			filter[kind] = bson.M{"$lte": limit - amount}
+		}
+
+		// When the guard fails on an existing document the upsert tries to
+		// insert a second one and hits the duplicate _id. The first
+		// duplicate may also be a race to create the document, so retry.
+		err := s.inc(ctx, filter, userID, period, start, delta)
+		if mongo.IsDuplicateKeyError(err) {
+			err = s.inc(ctx, filter, userID, period, start, delta)
+		}
+		if err != nil {
+			s.release(ctx, userID, at, models.LimitPeriods[:i], delta)
+			if mongo.IsDuplicateKeyError(err) {
+				return s.exceededIn(ctx, limits, userID, period, start, at, delta)
+			}
+			return fmt.Errorf("failed to update limit usage: %w", err)
+		}
+	}
+	return nil
+}
+
+// Release takes delta back off the player's usage of every period
+// containing at, e.g. when a reserved bet could not be placed or a win is
+// paid out
+func (s *LimitService) Release(ctx context.Context, userID string, at time.Time, delta LimitDelta) error {
+	return s.release(ctx, userID, at, models.LimitPeriods, delta)
+}
+
+func (s *LimitService) release(ctx context.Context, userID string, at time.Time, periods []string, delta LimitDelta) error {
+	negated := LimitDelta{Deposit: -delta.Deposit, Wager: -delta.Wager, Loss: -delta.Loss}
+	for _, period := range periods {
+		start := periodStart(period, at)
+		if err := s.inc(ctx, bson.M{"_id": usageID(userID, period, start)}, userID, period, start, negated); err != nil {
+			return fmt.Errorf("failed to release limit usage: %w", err)
+		}
+	}
+	return nil
+}
+
+// inc applies delta to the usage document matching filter, creating it if
+// it doesn't exist
+func (s *LimitService) inc(ctx context.Context, filter bson.M, userID, period string, start time.Time, delta LimitDelta) error {
+	_, err := s.usage.UpdateOne(ctx, filter, bson.M{
+		"$inc": bson.M{"deposit": delta.Deposit, "wager": delta.Wager, "loss": delta.Loss},
+		"$setOnInsert": bson.M{
+			"user_id": userID,
+			"period":  period,
+			"start":   start,
+			// Kept a while after the period for the player's history
+			"expires_at": periodEnd(period, start).AddDate(0, 0, 90),
+		},
+	}, options.Update().SetUpsert(true))
+	return err
+}
+
+// exceededIn works out which limit of a period a rejected delta would
+// have exceeded
+func (s *LimitService) exceededIn(ctx context.Context, limits *models.PlayerLimits, userID, period string, start, at time.Time, delta LimitDelta) error {
+	var usage models.LimitUsage
+	if err := s.usage.FindOne(ctx, bson.M{"_id": usageID(userID, period, start)}).Decode(&usage); err != nil {
+		return fmt.Errorf("%w: your %s limit", ErrLimitExceeded, periodAdjectives[period])
+	}
+	totals := LimitDelta{Deposit: usage.Deposit, Wager: usage.Wager, Loss: usage.Loss}
+	for _, kind := range models.LimitKinds {
+		limit := limits.Limits[models.LimitKey(kind, period)].Effective(at)
+		if amount := delta.amount(kind); amount > 0 && limit > 0 && totals.amount(kind)+amount > limit {
+			return limitExceeded(kind, period, limit)
+		}
+	}
+	return fmt.Errorf("%w: your %s limit", ErrLimitExceeded, periodAdjectives[period])
+}
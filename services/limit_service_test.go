@@ -0,0 +1,166 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"betting-app-backend-go/models"
+	"betting-app-backend-go/services"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TestPeriodBoundaries checks limit periods start at midnight IST, weeks on
+// Monday and months on the 1st, on either side of each boundary
+func TestPeriodBoundaries(t *testing.T) {
+	ist := time.FixedZone("IST", 5*60*60+30*60)
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, ist)
+	}
+
+	tests := []struct {
+		name   string
+		at     time.Time
+		period string
+		start  time.Time
+		end    time.Time
+	}{
+		// 2026-03-15 is a Sunday
+		{"last second of a day", time.Date(2026, 3, 15, 18, 29, 59, 0, time.UTC), "day", date(2026, 3, 15), date(2026, 3, 16)},
+		{"midnight IST", time.Date(2026, 3, 15, 18, 30, 0, 0, time.UTC), "day", date(2026, 3, 16), date(2026, 3, 17)},
+		{"still the UTC day before", time.Date(2026, 3, 15, 20, 0, 0, 0, time.UTC), "day", date(2026, 3, 16), date(2026, 3, 17)},
+		{"Sunday night", time.Date(2026, 3, 15, 18, 29, 59, 0, time.UTC), "week", date(2026, 3, 9), date(2026, 3, 16)},
+		{"Monday", time.Date(2026, 3, 15, 18, 30, 0, 0, time.UTC), "week", date(2026, 3, 16), date(2026, 3, 23)},
+		{"week across months", time.Date(2026, 4, 1, 12, 0, 0, 0, ist), "week", date(2026, 3, 30), date(2026, 4, 6)},
+		{"last second of February", time.Date(2026, 2, 28, 18, 29, 59, 0, time.UTC), "month", date(2026, 2, 1), date(2026, 3, 1)},
+		{"first of March", time.Date(2026, 2, 28, 18, 30, 0, 0, time.UTC), "month", date(2026, 3, 1), date(2026, 4, 1)},
+		{"month across years", time.Date(2026, 12, 31, 23, 0, 0, 0, ist), "month", date(2026, 12, 1), date(2027, 1, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := services.PeriodStart(tt.period, tt.at)
+			if !start.Equal(tt.start) {
+				t.Errorf("%s containing %s starts %s, want %s", tt.period, tt.at, start.In(ist), tt.start)
+			}
+			if end := services.PeriodEnd(tt.period, start); !end.Equal(tt.end) {
+				t.Errorf("%s from %s ends %s, want %s", tt.period, start.In(ist), end.In(ist), tt.end)
+			}
+		})
+	}
+}
+
+// TestConcurrentReserve fires parallel bets at a daily wager limit that
+// only some of them fit in. The guarded $inc must never let usage pass the
+// limit or lose a reservation.
+func TestConcurrentReserve(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	limits := services.NewLimitService(db)
+
+	userID := fmt.Sprintf("limits_%d", time.Now().UnixNano())
+	limit := models.MoneyFromMajor(100)
+	bet := models.MoneyFromMajor(3)
+	if err := limits.SetLimit(ctx, userID, "wager", "day", limit); err != nil {
+		t.Fatalf("SetLimit: %v", err)
+	}
+
+	const bets = 200
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		accepted  int
+		otherErrs []error
+	)
+	at := time.Now()
+	start := make(chan struct{})
+	for i := 0; i < bets; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			err := limits.Reserve(ctx, userID, at, services.LimitDelta{Wager: bet, Loss: bet})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				accepted++
+			case !errors.Is(err, services.ErrLimitExceeded):
+				otherErrs = append(otherErrs, err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if len(otherErrs) > 0 {
+		t.Fatalf("%d unexpected errors, first: %v", len(otherErrs), otherErrs[0])
+	}
+	if want := int(limit / bet); accepted != want {
+		t.Errorf("%d bets accepted, want the %d that fit in the limit", accepted, want)
+	}
+
+	var usage models.LimitUsage
+	if err := db.Collection("limit_usage").FindOne(ctx, bson.M{"user_id": userID, "period": "day"}).Decode(&usage); err != nil {
+		t.Fatalf("failed to read usage: %v", err)
+	}
+	if usage.Wager > limit {
+		t.Errorf("daily wager %s passed the limit %s", usage.Wager, limit)
+	}
+	if want := models.Money(accepted) * bet; usage.Wager != want {
+		t.Errorf("daily wager %s, want %s for %d accepted bets", usage.Wager, want, accepted)
+	}
+}
+
+// TestSetLimitCoolingOff checks a new or lower limit applies at once, and
+// a raise or removal only after the cooling-off period
+func TestSetLimitCoolingOff(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	limits := services.NewLimitService(db)
+	userID := fmt.Sprintf("limits_%d", time.Now().UnixNano())
+
+	later := time.Now().Add(25 * time.Hour)
+	steps := []struct {
+		name    string
+		amount  models.Money
+		now     models.Money // limit in force now
+		pending models.Money // limit in force after the cooling-off
+	}{
+		{"new limit applies at once", 1000, 1000, 1000},
+		{"lower limit applies at once", 500, 500, 500},
+		{"raise waits", 2000, 500, 2000},
+		{"setting the limit in force cancels the raise", 500, 500, 500},
+		{"removal waits", 0, 500, 0},
+	}
+	for _, step := range steps {
+		if err := limits.SetLimit(ctx, userID, "loss", "week", step.amount); err != nil {
+			t.Fatalf("%s: SetLimit(%s): %v", step.name, step.amount, err)
+		}
+		current, err := limits.GetLimits(ctx, userID)
+		if err != nil {
+			t.Fatalf("%s: GetLimits: %v", step.name, err)
+		}
+		limit := current.Limits[models.LimitKey("loss", "week")]
+		if got := limit.Effective(time.Now()); got != step.now {
+			t.Errorf("%s: limit now %s, want %s", step.name, got, step.now)
+		}
+		if got := limit.Effective(later); got != step.pending {
+			t.Errorf("%s: limit after cooling-off %s, want %s", step.name, got, step.pending)
+		}
+		if waiting := step.now != step.pending; waiting != (limit.PendingFrom != nil) {
+			t.Errorf("%s: pending from %v, want a pending change: %v", step.name, limit.PendingFrom, waiting)
+		}
+	}
+
+	if err := limits.SetLimit(ctx, userID, "loss", "fortnight", 100); !errors.Is(err, services.ErrInvalidLimit) {
+		t.Errorf("SetLimit for an unknown period = %v, want ErrInvalidLimit", err)
+	}
+	if err := limits.SetLimit(ctx, userID, "loss", "week", -1); !errors.Is(err, services.ErrInvalidLimit) {
+		t.Errorf("SetLimit with a negative amount = %v, want ErrInvalidLimit", err)
+	}
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"betting-app-backend-go/models"
+)
+
+// MinesEngine plays mines as a session: mines are placed when the session
+// starts, the player reveals cells one at a time and may cash out after any
+// safe reveal. Hitting a mine loses the bet.
+type MinesEngine struct{}
+
+func (e *MinesEngine) GameType() string { return "mines" }
+
+func (e *MinesEngine) Start(session *models.GameSession, settings *models.GameSettings, choices map[string]interface{}, rng RandomSource) error {
+	var cfg models.MinesConfig
+	if err := decodeConfig(settings.Config, &cfg); err != nil {
+		return err
+	}
+
+	mines := cfg.DefaultMines
+	if _, ok := choices["mines"]; ok {
+		n, err := choiceInt(choices, "mines")
+		if err != nil {
+			return err
+		}
+		mines = n
+	}
+	if mines < cfg.MinMines || mines > cfg.MaxMines {
+		return fmt.Errorf("%w: mines must be between %d and %d", ErrInvalidChoice, cfg.MinMines, cfg.MaxMines)
+	}
+
+	// Partial Fisher-Yates shuffle: the first mines cells become mines
+	cells := make([]int, cfg.GridSize*cfg.GridSize)
+	for i := range cells {
+		cells[i] = i
+	}
+	for i := 0; i < mines; i++ {
+		j := i + pickIndex(rng, len(cells)-i)
+		cells[i], cells[j] = cells[j], cells[i]
+	}
+
+	session.Mines = &models.MinesState{
+		GridSize:      cfg.GridSize,
+		Mines:         mines,
+		MinePositions: append([]int(nil), cells[:mines]...),
+		Revealed:      []int{},
+		HitMine:       -1,
+	}
+	session.Multiplier = 1
+	return nil
+}
+
+func (e *MinesEngine) Act(session *models.GameSession, settings *models.GameSettings, action string, params map[string]interface{}, rng RandomSource) error {
+	var cfg models.MinesConfig
+	if err := decodeConfig(settings.Config, &cfg); err != nil {
+		return err
+	}
+	board := session.Mines
+
+	switch action {
+	case "reveal":
+		cell, err := choiceInt(params, "cell")
+		if err != nil {
+			return err
+		}
+		cells := board.GridSize * board.GridSize
+		if cell < 0 || cell >= cells {
+			return fmt.Errorf("%w: cell must be between 0 and %d", ErrInvalidChoice, cells-1)
+		}
+		for _, c := range board.Revealed {
+			if c == cell {
+				return fmt.Errorf("%w: cell %d is already revealed", ErrInvalidChoice, cell)
+			}
+		}
+
+		for _, m := range board.MinePositions {
+			if m == cell {
+				board.HitMine = cell
+				session.Outcome = "busted"
+				session.Multiplier = 0
+				return nil
+			}
+		}
+
+		board.Revealed = append(board.Revealed, cell)
+		session.Multiplier = minesMultiplier(cells, board.Mines, len(board.Revealed), settings.HouseEdge, cfg.MultiplierBase)
+
+		// Nothing left to reveal; pay out automatically
+		if len(board.Revealed) == cells-board.Mines {
+			session.Outcome = "cashed_out"
+		}
+
+	case "cashout":
+		if len(board.Revealed) == 0 {
+			return fmt.Errorf("%w: reveal at least one cell before cashing out", ErrInvalidChoice)
+		}
+		session.Outcome = "cashed_out"
+
+	default:
+		return fmt.Errorf("%w: unknown action %q, expected reveal or cashout", ErrInvalidChoice, action)
+	}
+	return nil
+}
+
+// Expire cashes out at the current multiplier. With nothing revealed the
+// multiplier is 1, so the stake is returned.
+func (e *MinesEngine) Expire(session *models.GameSession, settings *models.GameSettings) {
+	session.Outcome = "expired"
+}
+
+func (e *MinesEngine) ResultData(session *models.GameSession) map[string]interface{} {
+	board := session.Mines
+	return map[string]interface{}{
+		"grid_size":      board.GridSize,
+		"mines":          board.Mines,
+		"mine_positions": board.MinePositions,
+		"revealed":       board.Revealed,
+		"hit_mine":       board.HitMine,
+		"outcome":        session.Outcome,
+	}
+}
+
+// minesMultiplier is the payout after revealed safe cells. The fair odds of
+// surviving that many reveals are C(cells, revealed) / C(cells-mines,
+// revealed); they are raised to 2*base (so the default base of 0.5 pays the
+// full odds) and reduced by the house edge. A base above 0.5 would pay more
+// than the odds, so validation caps it there.
+func minesMultiplier(cells, mines, revealed int, houseEdge, base float64) float64 {
+	if revealed == 0 {
+		return 1
+	}
+	odds := 1.0
+	for i := 0; i < revealed; i++ {
+		odds *= float64(cells-i) / float64(cells-mines-i)
+	}
+	return models.RoundMultiplier((1 - houseEdge/100) * math.Pow(odds, 2*base))
+}
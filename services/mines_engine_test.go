@@ -0,0 +1,69 @@
+package services_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"betting-app-backend-go/models"
+	"betting-app-backend-go/services"
+)
+
+// TestMinesMultiplier pins the payout after a number of safe reveals
+func TestMinesMultiplier(t *testing.T) {
+	tests := []struct {
+		cells, mines, revealed int
+		houseEdge, base        float64
+		want                   float64
+	}{
+		{25, 5, 0, 2, 0.5, 1},
+		{25, 5, 1, 2, 0.5, 1.225},
+		{25, 5, 2, 2, 0.5, 1.5474},
+		{25, 1, 1, 2, 0.5, 1.0208},
+		{25, 24, 1, 2, 0.5, 24.5},
+		{25, 3, 22, 2, 0.5, 2254},
+		{25, 5, 1, 2, 0.25, 1.0957},
+		{25, 5, 1, 0, 0.5, 1.25},
+	}
+	for _, tt := range tests {
+		got := services.MinesMultiplier(tt.cells, tt.mines, tt.revealed, tt.houseEdge, tt.base)
+		if got != tt.want {
+			t.Errorf("minesMultiplier(%d cells, %d mines, %d revealed, edge %g, base %g) = %v, want %v",
+				tt.cells, tt.mines, tt.revealed, tt.houseEdge, tt.base, got, tt.want)
+		}
+	}
+}
+
+// TestMinesReturn plays seeded sessions that reveal three cells and cash
+// out, and checks they return 1 - house edge
+func TestMinesReturn(t *testing.T) {
+	engine := &services.MinesEngine{}
+	settings := defaultSettings(t, "mines")
+
+	const rounds = 20000
+	rng := rand.New(rand.NewSource(1))
+	sum, sumSq := 0.0, 0.0
+	for i := 0; i < rounds; i++ {
+		session := &models.GameSession{GameType: "mines"}
+		if err := engine.Start(session, settings, map[string]interface{}{"mines": 5}, rng); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		for cell := 0; cell < 3 && session.Outcome == ""; cell++ {
+			if err := engine.Act(session, settings, "reveal", map[string]interface{}{"cell": cell}, rng); err != nil {
+				t.Fatalf("reveal: %v", err)
+			}
+		}
+		if session.Outcome == "" {
+			if err := engine.Act(session, settings, "cashout", nil, rng); err != nil {
+				t.Fatalf("cashout: %v", err)
+			}
+		}
+		sum += session.Multiplier
+		sumSq += session.Multiplier * session.Multiplier
+	}
+	mean := sum / rounds
+	stdErr := math.Sqrt((sumSq/rounds - mean*mean) / rounds)
+	if want := 1 - settings.HouseEdge/100; math.Abs(mean-want) > 4*stdErr {
+		t.Errorf("%d seeded rounds returned %.4f ± %.4f, want %.2f", rounds, mean, stdErr, want)
+	}
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"betting-app-backend-go/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Errors returned by PayoutMethodService
+var (
+	ErrInvalidPayoutMethod     = errors.New("invalid payout method")
+	ErrPayoutMethodNotFound    = errors.New("payout method not found")
+	ErrPayoutMethodExists      = errors.New("payout method already saved")
+	ErrPayoutMethodNotVerified = errors.New("payout method is not verified")
+)
+
+var (
+	// IFSC: 4-letter bank code, a zero, then a 6-character branch code
+	ifscPattern = regexp.MustCompile(`^[A-Z]{4}0[A-Z0-9]{6}$`)
+	// Indian bank account numbers are 9 to 18 digits
+	accountNumberPattern = regexp.MustCompile(`^[0-9]{9,18}$`)
+	// UPI VPA: handle@provider
+	upiPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{1,255}@[a-z][a-z0-9]{2,63}$`)
+)
+
+// maxPayoutMethods is how many payout methods a user may save
+const maxPayoutMethods = 10
+
+type PayoutMethodService struct {
+	collection *mongo.Collection
+}
+
+func NewPayoutMethodService(db *mongo.Database) *PayoutMethodService {
+	return &PayoutMethodService{collection: db.Collection("payout_methods")}
+}
+
+// normalizePayoutMethod cleans up and validates the destination fields of
+// method and sets its fingerprint
+func normalizePayoutMethod(method *models.PayoutMethod) error {
+	method.Label = strings.TrimSpace(method.Label)
+	if len(method.Label) > 50 {
+		return fmt.Errorf("%w: label must be at most 50 characters", ErrInvalidPayoutMethod)
+	}
+
+	switch method.Type {
+	case "bank":
+		method.AccountHolderName = strings.TrimSpace(method.AccountHolderName)
+		method.AccountNumber = strings.ReplaceAll(strings.TrimSpace(method.AccountNumber), " ", "")
+		method.IFSCCode = strings.ToUpper(strings.TrimSpace(method.IFSCCode))
+		method.UPIID = ""
+		if method.AccountHolderName == "" || len(method.AccountHolderName) > 100 {
+			return fmt.Errorf("%w: accountHolderName is required (at most 100 characters)", ErrInvalidPayoutMethod)
+		}
+		if !accountNumberPattern.MatchString(method.AccountNumber) {
+			return fmt.Errorf("%w: accountNumber must be 9 to 18 digits", ErrInvalidPayoutMethod)
+		}
+		if !ifscPattern.MatchString(method.IFSCCode) {
+			return fmt.Errorf("%w: ifscCode must be 11 characters like HDFC0001234", ErrInvalidPayoutMethod)
+		}
+		method.Fingerprint = "bank:" + method.IFSCCode + ":" + method.AccountNumber
+
+	case "upi":
+		method.UPIID = strings.ToLower(strings.TrimSpace(method.UPIID))
+		method.AccountHolderName = strings.TrimSpace(method.AccountHolderName)
+		method.AccountNumber = ""
+		method.IFSCCode = ""
+		if !upiPattern.MatchString(method.UPIID) {
+			return fmt.Errorf("%w: upiId must look like name@bank", ErrInvalidPayoutMethod)
+		}
+		method.Fingerprint = "upi:" + method.UPIID
+
+	default:
+		return fmt.Errorf("%w: type must be bank or upi", ErrInvalidPayoutMethod)
+	}
+	return nil
+}
+
+// Create validates and saves a new payout method pending verification
+func (s *PayoutMethodService) Create(ctx context.Context, method *models.PayoutMethod) error {
+	if err := normalizePayoutMethod(method); err != nil {
+		return err
+	}
+
+	count, err := s.collection.CountDocuments(ctx, bson.M{"user_id": method.UserID})
+	if err != nil {
+		return fmt.Errorf("failed to count payout methods: %w", err)
+	}
+	if count >= maxPayoutMethods {
+		return fmt.Errorf("%w: at most %d payout methods can be saved", ErrInvalidPayoutMethod, maxPayoutMethods)
+	}
+
+	now := time.Now()
+	method.ID = fmt.Sprintf("pm_%d", now.UnixNano())
+	method.Status = "pending"
+	method.RejectionReason = ""
+	method.VerifiedBy = ""
+	method.VerifiedAt = nil
+	method.CreatedAt = now
+	method.UpdatedAt = now
+
+	_, err = s.collection.InsertOne(ctx, method)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrPayoutMethodExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create payout method: %w", err)
+	}
+	return nil
+}
+
+// List returns a user's payout methods, newest first
+func (s *PayoutMethodService) List(ctx context.Context, userID string) ([]models.PayoutMethod, error) {
+	return s.find(ctx, bson.M{"user_id": userID})
+}
+
+// ListByStatus returns the payout methods of all users with the given
+// status, oldest first, for admin review
+func (s *PayoutMethodService) ListByStatus(ctx context.Context, status string) ([]models.PayoutMethod, error) {
+	filter := bson.M{}
+	if status != "" && status != "all" {
+		filter["status"] = status
+	}
+	return s.find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+}
+
+func (s *PayoutMethodService) find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]models.PayoutMethod, error) {
+	if len(opts) == 0 {
+		opts = append(opts, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	}
+	cursor, err := s.collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payout methods: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	methods := []models.PayoutMethod{}
+	if err := cursor.All(ctx, &methods); err != nil {
+		return nil, fmt.Errorf("failed to decode payout methods: %w", err)
+	}
+	return methods, nil
+}
+
+// Get returns one of a user's payout methods
+func (s *PayoutMethodService) Get(ctx context.Context, userID, id string) (*models.PayoutMethod, error) {
+	var method models.PayoutMethod
+	err := s.collection.FindOne(ctx, bson.M{"_id": id, "user_id": userID}).Decode(&method)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrPayoutMethodNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payout method: %w", err)
+	}
+	return &method, nil
+}
+
+// GetVerified returns a user's payout method only if it is verified, for
+// paying out a withdrawal
+func (s *PayoutMethodService) GetVerified(ctx context.Context, userID, id string) (*models.PayoutMethod, error) {
+	method, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if method.Status != "verified" {
+		return nil, ErrPayoutMethodNotVerified
+	}
+	return method, nil
+}
+
+// UpdateLabel renames a payout method. The destination itself can't be
+// changed: a new destination is saved as a new method, so an admin's
+// verification always applies to the details they reviewed.
+func (s *PayoutMethodService) UpdateLabel(ctx context.Context, userID, id string, label string) (*models.PayoutMethod, error) {
+	label = strings.TrimSpace(label)
+	if len(label) > 50 {
+		return nil, fmt.Errorf("%w: label must be at most 50 characters", ErrInvalidPayoutMethod)
+	}
+
+	var method models.PayoutMethod
+	err := s.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id, "user_id": userID},
+		bson.M{"$set": bson.M{"label": label, "updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&method)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrPayoutMethodNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update payout method: %w", err)
+	}
+	return &method, nil
+}
+
+// Delete removes a payout method. Withdrawals already requested keep a copy
+// of its details.
+func (s *PayoutMethodService) Delete(ctx context.Context, userID, id string) error {
+	res, err := s.collection.DeleteOne(ctx, bson.M{"_id": id, "user_id": userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete payout method: %w", err)
+	}
+	if res.DeletedCount == 0 {
+		return ErrPayoutMethodNotFound
+	}
+	return nil
+}
+
+// Review records an admin's verification decision
+func (s *PayoutMethodService) Review(ctx context.Context, id string, approve bool, reason string, adminUID string) (*models.PayoutMethod, error) {
+	now := time.Now()
+	set := bson.M{"verified_by": adminUID, "updated_at": now}
+	if approve {
+		set["status"] = "verified"
+		set["verified_at"] = now
+		set["rejection_reason"] = ""
+	} else {
+		if strings.TrimSpace(reason) == "" {
+			return nil, fmt.Errorf("%w: reason is required when rejecting", ErrInvalidPayoutMethod)
+		}
+		set["status"] = "rejected"
+		set["verified_at"] = nil
+		set["rejection_reason"] = reason
+	}
+
+	var method models.PayoutMethod
+	err := s.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&method)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrPayoutMethodNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to review payout method: %w", err)
+	}
+	return &method, nil
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"betting-app-backend-go/models"
+)
+
+// PlinkoEngine drops a ball through Rows rows of pegs. At every row it
+// bounces left or right with equal chance; the number of right bounces is
+// the bucket, which pays the multiplier at that index in the table of the
+// chosen risk level.
+type PlinkoEngine struct{}
+
+func (e *PlinkoEngine) GameType() string { return "plinko" }
+
+func (e *PlinkoEngine) Play(req *PlayRequest, settings *models.GameSettings, rng RandomSource) (*models.Game, error) {
+	var cfg models.PlinkoConfig
+	if err := decodeConfig(settings.Config, &cfg); err != nil {
+		return nil, err
+	}
+
+	risk, err := choiceString(req.Choices, "risk")
+	if err != nil {
+		return nil, err
+	}
+	table, ok := plinkoTable(&cfg, risk)
+	if !ok {
+		return nil, fmt.Errorf("%w: risk must be low, medium or high", ErrInvalidChoice)
+	}
+	if len(table) != cfg.Rows+1 {
+		return nil, fmt.Errorf("plinko %s table has %d buckets for %d rows", risk, len(table), cfg.Rows)
+	}
+
+	var path strings.Builder
+	bucket := 0
+	for row := 0; row < cfg.Rows; row++ {
+		if pickIndex(rng, 2) == 1 {
+			path.WriteByte('R')
+			bucket++
+		} else {
+			path.WriteByte('L')
+		}
+	}
+	multiplier := table[bucket]
+
+	return settle(req, multiplier, map[string]interface{}{
+		"risk":       risk,
+		"rows":       cfg.Rows,
+		"path":       path.String(),
+		"bucket":     bucket,
+		"multiplier": multiplier,
+	}), nil
+}
+
+// plinkoTable returns the multiplier table of a risk level
+func plinkoTable(cfg *models.PlinkoConfig, risk string) ([]float64, bool) {
+	switch risk {
+	case "low":
+		return cfg.MultipliersLow, true
+	case "medium":
+		return cfg.MultipliersMedium, true
+	case "high":
+		return cfg.MultipliersHigh, true
+	}
+	return nil, false
+}
+
+// ExpectedReturn is the average of the three risk tables, as if players
+// picked each risk equally often
+func (e *PlinkoEngine) ExpectedReturn(settings *models.GameSettings) (float64, error) {
+	var cfg models.PlinkoConfig
+	if err := decodeConfig(settings.Config, &cfg); err != nil {
+		return 0, err
+	}
+	ev := 0.0
+	for _, table := range [][]float64{cfg.MultipliersLow, cfg.MultipliersMedium, cfg.MultipliersHigh} {
+		ev += plinkoExpectedReturn(table) / 3
+	}
+	return ev, nil
+}
+
+// plinkoExpectedReturn is the average payout of a table per unit bet: the
+// ball lands in bucket k with probability C(rows, k) / 2^rows
+func plinkoExpectedReturn(table []float64) float64 {
+	rows := len(table) - 1
+	ev := 0.0
+	ways := 1.0 // C(rows, k)
+	for k, m := range table {
+		ev += m * ways / math.Pow(2, float64(rows))
+		ways = ways * float64(rows-k) / float64(k+1)
+	}
+	return ev
+}
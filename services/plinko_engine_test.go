@@ -0,0 +1,94 @@
+package services_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"betting-app-backend-go/models"
+	"betting-app-backend-go/services"
+)
+
+// TestPlinkoPath checks each bounce to the right moves the ball one bucket
+// over and the bucket's multiplier is paid
+func TestPlinkoPath(t *testing.T) {
+	engine := &services.PlinkoEngine{}
+	settings := defaultSettings(t, "plinko")
+
+	tests := []struct {
+		risk       string
+		values     []float64
+		path       string
+		bucket     int
+		multiplier float64
+	}{
+		{"low", []float64{0.2}, "LLLLLLLLLLLL", 0, 10},
+		{"medium", []float64{0.7}, "RRRRRRRRRRRR", 12, 33},
+		{"high", []float64{0.2, 0.7}, "LRLRLRLRLRLR", 6, 0.2},
+		{"low", []float64{0.7, 0.7, 0.7, 0.2}, "RRRLRRRLRRRL", 9, 1.4},
+	}
+	for _, tt := range tests {
+		req := &services.PlayRequest{
+			GameType:  "plinko",
+			BetAmount: models.MoneyFromMajor(1),
+			Choices:   map[string]interface{}{"risk": tt.risk},
+		}
+		game, err := engine.Play(req, settings, &sequenceSource{values: tt.values})
+		if err != nil {
+			t.Fatalf("Play: %v", err)
+		}
+		if game.ResultData["path"] != tt.path || game.ResultData["bucket"] != tt.bucket || game.Multiplier != tt.multiplier {
+			t.Errorf("%s %v: path %v to bucket %v paying %v, want %s to %d paying %v", tt.risk, tt.values,
+				game.ResultData["path"], game.ResultData["bucket"], game.Multiplier, tt.path, tt.bucket, tt.multiplier)
+		}
+	}
+
+	req := &services.PlayRequest{GameType: "plinko", BetAmount: models.MoneyFromMajor(1), Choices: map[string]interface{}{"risk": "extreme"}}
+	if _, err := engine.Play(req, settings, &sequenceSource{values: []float64{0.5}}); !errors.Is(err, services.ErrInvalidChoice) {
+		t.Errorf("Play(risk extreme) = %v, want ErrInvalidChoice", err)
+	}
+}
+
+// TestPlinkoReturn drops a ball down every one of the 2^rows paths of each
+// default table and checks the average is ExpectedReturn and within the
+// validation tolerance of 1 - house edge
+func TestPlinkoReturn(t *testing.T) {
+	engine := &services.PlinkoEngine{}
+	settings := defaultSettings(t, "plinko")
+	rows := settings.Config["rows"].(int)
+	target := 1 - settings.HouseEdge/100
+
+	average := 0.0
+	for _, risk := range []string{"low", "medium", "high"} {
+		total := 0.0
+		for path := 0; path < 1<<rows; path++ {
+			values := make([]float64, rows)
+			for row := range values {
+				values[row] = 0.25 + 0.5*float64(path>>row&1)
+			}
+			req := &services.PlayRequest{
+				GameType:  "plinko",
+				BetAmount: models.MoneyFromMajor(1),
+				Choices:   map[string]interface{}{"risk": risk},
+			}
+			game, err := engine.Play(req, settings, &sequenceSource{values: values})
+			if err != nil {
+				t.Fatalf("Play: %v", err)
+			}
+			total += game.Multiplier
+		}
+		played := total / float64(int(1)<<rows)
+		if math.Abs(played-target) > 0.005 {
+			t.Errorf("%s table returns %.5f, want %.3f within 0.005", risk, played, target)
+		}
+		average += played / 3
+	}
+
+	expected, err := engine.ExpectedReturn(settings)
+	if err != nil {
+		t.Fatalf("ExpectedReturn: %v", err)
+	}
+	if math.Abs(expected-average) > 1e-9 {
+		t.Errorf("ExpectedReturn = %.9f, every path played returns %.9f", expected, average)
+	}
+}
@@ -0,0 +1,389 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"betting-app-backend-go/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrAlertNotFound is returned for an unknown RTP alert
+var ErrAlertNotFound = errors.New("alert not found")
+
+const (
+	// rtpAlertZ is how many standard errors realised RTP may be from the
+	// expected RTP before an alert is raised (about 1 in 2000 by chance)
+	rtpAlertZ = 3.5
+	// rtpMinRounds is the fewest rounds a window needs to be judged
+	rtpMinRounds = 500
+	// rtpBiggestWins is how many of the largest payouts a window keeps
+	rtpBiggestWins = 5
+)
+
+// rtpWindows are the trailing windows every game is measured over
+var rtpWindows = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"1h", time.Hour},
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+}
+
+// rtpTotals are the sums of one game's rounds over a window. The squared
+// sums give the standard error of the realised RTP.
+type rtpTotals struct {
+	GameType    string          `bson:"_id"`
+	Rounds      int64           `bson:"rounds"`
+	Wagered     models.Money    `bson:"wagered"`
+	Paid        models.Money    `bson:"paid"`
+	BetSq       float64         `bson:"bet_sq"`
+	BetWin      float64         `bson:"bet_win"`
+	WinSq       float64         `bson:"win_sq"`
+	BiggestWins []models.BigWin `bson:"biggest_wins"`
+}
+
+// RTPMonitor tracks the realised return-to-player of every game from the
+// games collection and raises an alert while it is further from the
+// expected return of the game's config than chance explains.
+type RTPMonitor struct {
+	games       *mongo.Collection
+	alerts      *mongo.Collection
+	gameService *GameService
+	notifier    AlertNotifier
+	interval    time.Duration
+
+	refreshMu sync.Mutex // one refresh at a time
+	mu        sync.RWMutex
+	report    *models.RTPReport
+}
+
+func NewRTPMonitor(db *mongo.Database, gameService *GameService, notifier AlertNotifier) *RTPMonitor {
+	return &RTPMonitor{
+		games:       db.Collection("games"),
+		alerts:      db.Collection("rtp_alerts"),
+		gameService: gameService,
+		notifier:    notifier,
+		interval:    5 * time.Minute,
+	}
+}
+
+// Run refreshes the report and alerts until ctx is cancelled
+func (m *RTPMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := m.Refresh(ctx); err != nil {
+			log.Printf("[RTP] ❌ Failed to refresh RTP report: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Report returns the latest report, computing one if there is none yet
+func (m *RTPMonitor) Report(ctx context.Context) (*models.RTPReport, error) {
+	m.mu.RLock()
+	report := m.report
+	m.mu.RUnlock()
+	if report != nil {
+		return report, nil
+	}
+	return m.Refresh(ctx)
+}
+
+// Refresh recomputes every game's RTP over every window and raises or
+// resolves alerts accordingly
+func (m *RTPMonitor) Refresh(ctx context.Context) (*models.RTPReport, error) {
+	m.refreshMu.Lock()
+	defer m.refreshMu.Unlock()
+
+	now := time.Now()
+	report := &models.RTPReport{Windows: []models.RTPWindow{}, GeneratedAt: now}
+	expected := make(map[string]float64)
+
+	for _, window := range rtpWindows {
+		since := now.Add(-window.duration)
+		totals, err := m.aggregate(ctx, since)
+		if err != nil {
+			return nil, fmt.Errorf("failed to aggregate %s window: %w", window.name, err)
+		}
+
+		for _, t := range totals {
+			rtp, ok := expected[t.GameType]
+			if !ok {
+				// A game without settings is reported but never alerts
+				if expectedRTP, err := m.gameService.ExpectedRTP(ctx, t.GameType); err == nil {
+					rtp = expectedRTP
+				}
+				expected[t.GameType] = rtp
+			}
+
+			w := rtpWindowFromTotals(&t, window.name, since, rtp, m.gameService.ExpectedRTPIsCeiling(t.GameType))
+			report.Windows = append(report.Windows, w)
+			if t.Rounds >= rtpMinRounds && w.ExpectedRTP > 0 && w.Band > 0 {
+				m.checkAlert(ctx, &w, now)
+			}
+		}
+	}
+
+	// Windows are built shortest first, so a stable sort groups them by game
+	sort.SliceStable(report.Windows, func(i, j int) bool {
+		return report.Windows[i].GameType < report.Windows[j].GameType
+	})
+
+	m.mu.Lock()
+	m.report = report
+	m.mu.Unlock()
+	return report, nil
+}
+
+// aggregate sums the settled rounds of every game played since a time
+func (m *RTPMonitor) aggregate(ctx context.Context, since time.Time) ([]rtpTotals, error) {
+	bet := bson.M{"$toDouble": "$bet_amount"}
+	win := bson.M{"$toDouble": "$win_amount"}
+
+	cursor, err := m.games.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"settled":    true,
+			"created_at": bson.M{"$gte": since},
+			"bet_amount": bson.M{"$gt": 0},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     "$game_type",
+			"rounds":  bson.M{"$sum": 1},
+			"wagered": bson.M{"$sum": "$bet_amount"},
+			"paid":    bson.M{"$sum": "$win_amount"},
+			"bet_sq":  bson.M{"$sum": bson.M{"$multiply": bson.A{bet, bet}}},
+			"bet_win": bson.M{"$sum": bson.M{"$multiply": bson.A{bet, win}}},
+			"win_sq":  bson.M{"$sum": bson.M{"$multiply": bson.A{win, win}}},
+			"biggest_wins": bson.M{"$topN": bson.M{
+				"n":      rtpBiggestWins,
+				"sortBy": bson.M{"win_amount": -1},
+				"output": bson.M{
+					"_id":        "$_id",
+					"user_id":    "$user_id",
+					"bet_amount": "$bet_amount",
+					"win_amount": "$win_amount",
+					"multiplier": "$multiplier",
+					"created_at": "$created_at",
+				},
+			}},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var totals []rtpTotals
+	if err := cursor.All(ctx, &totals); err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// rtpWindowFromTotals computes the realised RTP of a window and the band it
+// is expected in. RTP is a ratio of sums, so its standard error is
+// sqrt(Σ(win - expected·bet)²) / Σbet, measured around the expected RTP:
+// the band is what chance allows if the game pays what it should. When the
+// expected RTP is a ceiling, players who play worse than best pay the
+// house more, so only an RTP above the band alerts.
+func rtpWindowFromTotals(t *rtpTotals, window string, since time.Time, expectedRTP float64, ceiling bool) models.RTPWindow {
+	w := models.RTPWindow{
+		GameType:    t.GameType,
+		Window:      window,
+		HighOnly:    ceiling,
+		Rounds:      t.Rounds,
+		Wagered:     t.Wagered,
+		Paid:        t.Paid,
+		GGR:         t.Wagered - t.Paid,
+		ExpectedRTP: expectedRTP,
+		BiggestWins: []models.BigWin{},
+		Since:       since,
+	}
+	for _, win := range t.BiggestWins {
+		if win.WinAmount > 0 {
+			w.BiggestWins = append(w.BiggestWins, win)
+		}
+	}
+	if t.Wagered <= 0 {
+		return w
+	}
+
+	wagered := float64(t.Wagered)
+	w.RTP = float64(t.Paid) / wagered
+	if expectedRTP <= 0 {
+		return w
+	}
+
+	squares := t.WinSq - 2*expectedRTP*t.BetWin + expectedRTP*expectedRTP*t.BetSq
+	stdErr := math.Sqrt(max(squares, 0)) / wagered
+	if stdErr == 0 {
+		return w
+	}
+	w.Band = rtpAlertZ * stdErr
+	w.ZScore = (w.RTP - expectedRTP) / stdErr
+	w.Alerting = t.Rounds >= rtpMinRounds && (w.ZScore > rtpAlertZ || (!ceiling && w.ZScore < -rtpAlertZ))
+	return w
+}
+
+// checkAlert raises, updates or resolves the alert of a judged window
+func (m *RTPMonitor) checkAlert(ctx context.Context, w *models.RTPWindow, now time.Time) {
+	var open models.RTPAlert
+	err := m.alerts.FindOne(ctx, bson.M{"game_type": w.GameType, "window": w.Window, "status": "open"}).Decode(&open)
+	hasOpen := err == nil
+	if err != nil && err != mongo.ErrNoDocuments {
+		log.Printf("[RTP] ❌ Failed to load alert for %s %s: %v\n", w.GameType, w.Window, err)
+		return
+	}
+
+	direction := "low"
+	if w.RTP > w.ExpectedRTP {
+		direction = "high"
+	}
+
+	if hasOpen && (!w.Alerting || open.Direction != direction) {
+		m.resolveAlert(ctx, &open, w, now)
+		hasOpen = false
+	}
+	if !w.Alerting {
+		return
+	}
+
+	if hasOpen {
+		set := bson.M{
+			"rtp":          w.RTP,
+			"expected_rtp": w.ExpectedRTP,
+			"band":         w.Band,
+			"z_score":      w.ZScore,
+			"rounds":       w.Rounds,
+			"wagered":      w.Wagered,
+			"ggr":          w.GGR,
+			"updated_at":   now,
+		}
+		if math.Abs(w.RTP-w.ExpectedRTP) > math.Abs(open.PeakRTP-open.ExpectedRTP) {
+			set["peak_rtp"] = w.RTP
+		}
+		if _, err := m.alerts.UpdateOne(ctx, bson.M{"_id": open.ID, "status": "open"}, bson.M{"$set": set}); err != nil {
+			log.Printf("[RTP] ❌ Failed to update alert %s: %v\n", open.ID, err)
+		}
+		return
+	}
+
+	alert := &models.RTPAlert{
+		ID:          fmt.Sprintf("rtpalert_%d", now.UnixNano()),
+		GameType:    w.GameType,
+		Window:      w.Window,
+		Direction:   direction,
+		Status:      "open",
+		RTP:         w.RTP,
+		PeakRTP:     w.RTP,
+		ExpectedRTP: w.ExpectedRTP,
+		Band:        w.Band,
+		ZScore:      w.ZScore,
+		Rounds:      w.Rounds,
+		Wagered:     w.Wagered,
+		GGR:         w.GGR,
+		RaisedAt:    now,
+		UpdatedAt:   now,
+	}
+	// The unique index on open alerts stops other instances raising it twice
+	if _, err := m.alerts.InsertOne(ctx, alert); err != nil {
+		if !mongo.IsDuplicateKeyError(err) {
+			log.Printf("[RTP] ❌ Failed to raise alert for %s %s: %v\n", w.GameType, w.Window, err)
+		}
+		return
+	}
+	m.notify(ctx, alert)
+}
+
+// resolveAlert closes an open alert with the window's latest figures
+func (m *RTPMonitor) resolveAlert(ctx context.Context, alert *models.RTPAlert, w *models.RTPWindow, now time.Time) {
+	res, err := m.alerts.UpdateOne(ctx, bson.M{"_id": alert.ID, "status": "open"}, bson.M{"$set": bson.M{
+		"status":      "resolved",
+		"rtp":         w.RTP,
+		"z_score":     w.ZScore,
+		"rounds":      w.Rounds,
+		"wagered":     w.Wagered,
+		"ggr":         w.GGR,
+		"updated_at":  now,
+		"resolved_at": now,
+	}})
+	if err != nil {
+		log.Printf("[RTP] ❌ Failed to resolve alert %s: %v\n", alert.ID, err)
+		return
+	}
+	if res.ModifiedCount == 0 {
+		return
+	}
+
+	alert.Status = "resolved"
+	alert.RTP = w.RTP
+	alert.ZScore = w.ZScore
+	alert.Rounds = w.Rounds
+	alert.Wagered = w.Wagered
+	alert.GGR = w.GGR
+	alert.UpdatedAt = now
+	alert.ResolvedAt = &now
+	m.notify(ctx, alert)
+}
+
+func (m *RTPMonitor) notify(ctx context.Context, alert *models.RTPAlert) {
+	if err := m.notifier.Notify(ctx, alert); err != nil {
+		log.Printf("[RTP] ❌ Failed to send alert %s: %v\n", alert.ID, err)
+	}
+}
+
+// ListAlerts returns the latest alerts, optionally only those with a status
+func (m *RTPMonitor) ListAlerts(ctx context.Context, status string, limit int64) ([]models.RTPAlert, error) {
+	filter := bson.M{}
+	if status != "" && status != "all" {
+		filter["status"] = status
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "raised_at", Value: -1}}).SetLimit(limit)
+	cursor, err := m.alerts.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alerts: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	alerts := []models.RTPAlert{}
+	if err := cursor.All(ctx, &alerts); err != nil {
+		return nil, fmt.Errorf("failed to decode alerts: %w", err)
+	}
+	return alerts, nil
+}
+
+// AcknowledgeAlert records that an admin has seen an alert. It stays open
+// until the RTP is back within its band.
+func (m *RTPMonitor) AcknowledgeAlert(ctx context.Context, id, adminUID string) (*models.RTPAlert, error) {
+	var alert models.RTPAlert
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := m.alerts.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"acknowledged_by": adminUID,
+		"acknowledged_at": time.Now(),
+	}}, opts).Decode(&alert)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrAlertNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to acknowledge alert: %w", err)
+	}
+	return &alert, nil
+}
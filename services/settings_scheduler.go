@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"betting-app-backend-go/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrScheduleNotPending is returned when cancelling a schedule that has
+// already been applied, finished or cancelled.
+var ErrScheduleNotPending = errors.New("schedule is not pending")
+
+// scheduleRetryAfter is how long a schedule may stay applying or reverting
+// before the next sweep takes it over, e.g. after the instance handling it
+// stopped halfway
+const scheduleRetryAfter = time.Minute
+
+// SettingsScheduler applies scheduled game settings changes when their
+// window starts and restores the previous settings when it ends. All writes
+// go through GameSettingsService.UpdateGameSettings, so validation, audit
+// fields and version history behave exactly like a manual admin change.
+type SettingsScheduler struct {
+	collection *mongo.Collection
+	settings   *GameSettingsService
+	interval   time.Duration
+}
+
+func NewSettingsScheduler(db *mongo.Database, settings *GameSettingsService) *SettingsScheduler {
+	return &SettingsScheduler{
+		collection: db.Collection("game_settings_schedules"),
+		settings:   settings,
+		interval:   30 * time.Second,
+	}
+}
+
+// CreateSchedule validates and stores a pending schedule
+func (s *SettingsScheduler) CreateSchedule(ctx context.Context, schedule *models.GameSettingsSchedule, adminUID string) error {
+	verr := &ValidationError{}
+	if !IsValidGameType(schedule.GameType) {
+		verr.add("game_type", "unknown game type %q", schedule.GameType)
+	}
+	if (schedule.Settings == nil) == (schedule.Enabled == nil) {
+		verr.add("settings", "exactly one of settings or enabled must be set")
+	}
+	if schedule.StartAt.IsZero() {
+		verr.add("start_at", "is required")
+	} else if schedule.StartAt.Before(time.Now().Add(-time.Minute)) {
+		verr.add("start_at", "must not be in the past")
+	}
+	if schedule.EndAt != nil && !schedule.EndAt.After(schedule.StartAt) {
+		verr.add("end_at", "must be after start_at")
+	}
+	if len(verr.Fields) == 0 && schedule.Settings != nil {
+		if err := ValidateGameSettings(schedule.GameType, schedule.Settings); err != nil {
+			return err
+		}
+	}
+	if err := verr.errOrNil(); err != nil {
+		return err
+	}
+
+	schedule.ID = fmt.Sprintf("sched_%d", time.Now().UnixNano())
+	schedule.Status = "pending"
+	schedule.CreatedBy = adminUID
+	schedule.CreatedAt = time.Now()
+	schedule.ClaimedAt = nil
+	schedule.Previous = nil
+	schedule.AppliedVersion = 0
+	schedule.RevertedVersion = 0
+	schedule.Error = ""
+	schedule.AppliedAt = nil
+	schedule.RevertedAt = nil
+	schedule.CancelledBy = ""
+
+	if _, err := s.collection.InsertOne(ctx, schedule); err != nil {
+		return fmt.Errorf("failed to create schedule: %w", err)
+	}
+	return nil
+}
+
+// ListSchedules returns schedules, optionally filtered by status and game
+func (s *SettingsScheduler) ListSchedules(ctx context.Context, status string, gameType string) ([]models.GameSettingsSchedule, error) {
+	filter := bson.M{}
+	if status != "" && status != "all" {
+		filter["status"] = status
+	}
+	if gameType != "" {
+		filter["game_type"] = gameType
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "start_at", Value: 1}})
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedules: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var schedules []models.GameSettingsSchedule
+	if err := cursor.All(ctx, &schedules); err != nil {
+		return nil, fmt.Errorf("failed to decode schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// CancelSchedule cancels a schedule that has not been applied yet
+func (s *SettingsScheduler) CancelSchedule(ctx context.Context, id string, adminUID string) error {
+	res, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "status": "pending"},
+		bson.M{"$set": bson.M{"status": "cancelled", "cancelled_by": adminUID}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to cancel schedule: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrScheduleNotPending
+	}
+	return nil
+}
+
+// Run processes due schedules until ctx is cancelled
+func (s *SettingsScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.processDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processDue applies schedules whose window started and reverts those
+// whose window ended. Schedules left applying or reverting by an instance
+// that stopped halfway are taken over once their claim is old enough.
+func (s *SettingsScheduler) processDue(ctx context.Context) {
+	for {
+		schedule, err := s.claim(ctx, "pending", "start_at", "applying")
+		if err != nil || schedule == nil {
+			break
+		}
+		s.apply(ctx, schedule)
+	}
+
+	for {
+		schedule, err := s.claim(ctx, "active", "end_at", "reverting")
+		if err != nil || schedule == nil {
+			break
+		}
+		s.revert(ctx, schedule)
+	}
+}
+
+// claim atomically moves the oldest schedule that is in status from and
+// due by its dueField, or stuck in status to, to status to. Only one server
+// instance holds a claim; the updates that follow are conditional on it.
+func (s *SettingsScheduler) claim(ctx context.Context, from, dueField, to string) (*models.GameSettingsSchedule, error) {
+	now := time.Now()
+	filter := bson.M{"$or": bson.A{
+		bson.M{"status": from, dueField: bson.M{"$lte": now}},
+		bson.M{"status": to, "claimed_at": bson.M{"$lt": now.Add(-scheduleRetryAfter)}},
+	}}
+
+	var schedule models.GameSettingsSchedule
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "start_at", Value: 1}}).
+		SetReturnDocument(options.After)
+	err := s.collection.FindOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{"status": to, "claimed_at": now}}, opts).Decode(&schedule)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("[Scheduler] ❌ Failed to claim schedule: %v\n", err)
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// update sets fields of a claimed schedule. It reports false if another
+// instance has taken the claim over since.
+func (s *SettingsScheduler) update(ctx context.Context, schedule *models.GameSettingsSchedule, set bson.M) bool {
+	res, err := s.collection.UpdateOne(ctx, bson.M{"_id": schedule.ID, "claimed_at": schedule.ClaimedAt}, bson.M{"$set": set})
+	if err != nil {
+		log.Printf("[Scheduler] ❌ Failed to update schedule %s: %v\n", schedule.ID, err)
+		return false
+	}
+	return res.MatchedCount == 1
+}
+
+// apply writes the schedule's settings. The settings it replaces and the
+// version it will write are recorded first, so that a retry knows whether
+// the write happened and what to revert to.
+func (s *SettingsScheduler) apply(ctx context.Context, schedule *models.GameSettingsSchedule) {
+	current, err := s.settings.GetGameSettings(ctx, schedule.GameType)
+	if err != nil {
+		s.fail(ctx, schedule, err)
+		return
+	}
+
+	switch {
+	case schedule.Previous == nil:
+		if !s.update(ctx, schedule, bson.M{"previous": current, "applied_version": current.Version + 1}) {
+			return
+		}
+		schedule.Previous, schedule.AppliedVersion = current, current.Version+1
+	case current.Version == schedule.AppliedVersion:
+		// Written by the instance that claimed it before
+	case current.Version != schedule.Previous.Version:
+		s.fail(ctx, schedule, fmt.Errorf("settings changed to version %d while the schedule was being applied, not applied", current.Version))
+		return
+	}
+
+	if current.Version != schedule.AppliedVersion {
+		var next models.GameSettings
+		if schedule.Settings != nil {
+			next = *schedule.Settings
+		} else {
+			next = *current
+			next.Enabled = *schedule.Enabled
+		}
+		if err := s.settings.UpdateGameSettings(ctx, schedule.GameType, &next, schedule.CreatedBy); err != nil {
+			s.fail(ctx, schedule, err)
+			return
+		}
+	}
+
+	status := "active"
+	if schedule.EndAt == nil {
+		status = "completed"
+	}
+	if !s.update(ctx, schedule, bson.M{"status": status, "applied_at": time.Now()}) {
+		return
+	}
+
+	log.Printf("[Scheduler] ✅ Applied schedule %s to %s (version %d)\n", schedule.ID, schedule.GameType, schedule.AppliedVersion)
+}
+
+// revert restores the settings the schedule replaced. Like apply, it
+// records the version it will write first.
+func (s *SettingsScheduler) revert(ctx context.Context, schedule *models.GameSettingsSchedule) {
+	if schedule.Previous == nil {
+		s.fail(ctx, schedule, fmt.Errorf("previous settings were not recorded, not reverted"))
+		return
+	}
+
+	// Don't clobber changes an admin made while the schedule was active
+	current, err := s.settings.GetGameSettings(ctx, schedule.GameType)
+	if err != nil {
+		s.fail(ctx, schedule, err)
+		return
+	}
+	reverted := schedule.RevertedVersion != 0 && current.Version == schedule.RevertedVersion
+	if !reverted && current.Version != schedule.AppliedVersion {
+		s.fail(ctx, schedule, fmt.Errorf("settings changed to version %d after the schedule applied version %d, not reverted", current.Version, schedule.AppliedVersion))
+		return
+	}
+
+	if !reverted {
+		if !s.update(ctx, schedule, bson.M{"reverted_version": current.Version + 1}) {
+			return
+		}
+		previous := *schedule.Previous
+		if err := s.settings.UpdateGameSettings(ctx, schedule.GameType, &previous, schedule.CreatedBy); err != nil {
+			s.fail(ctx, schedule, err)
+			return
+		}
+		schedule.RevertedVersion = previous.Version
+	}
+
+	if !s.update(ctx, schedule, bson.M{"status": "completed", "reverted_at": time.Now()}) {
+		return
+	}
+
+	log.Printf("[Scheduler] ✅ Reverted schedule %s on %s (version %d)\n", schedule.ID, schedule.GameType, schedule.RevertedVersion)
+}
+
+// fail marks a schedule as failed so it shows up for admins
+func (s *SettingsScheduler) fail(ctx context.Context, schedule *models.GameSettingsSchedule, cause error) {
+	log.Printf("[Scheduler] ❌ Schedule %s failed: %v\n", schedule.ID, cause)
+	_, err := s.collection.UpdateOne(ctx, bson.M{"_id": schedule.ID}, bson.M{"$set": bson.M{
+		"status": "failed",
+		"error":  cause.Error(),
+	}})
+	if err != nil {
+		log.Printf("[Scheduler] ❌ Failed to update schedule %s: %v\n", schedule.ID, err)
+	}
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"time"
+
+	"betting-app-backend-go/models"
+)
+
+// simulationChunk is how many rounds share one random stream. Chunks are
+// seeded by their index, so a seed reproduces the same report whatever the
+// number of workers.
+const simulationChunk = 10000
+
+// simulationEdges are the lower bounds of the histogram buckets after the
+// loss bucket
+var simulationEdges = []float64{0, 1, 2, 5, 10, 25, 100, 1000}
+
+// SimulationOptions controls a simulation run
+type SimulationOptions struct {
+	Rounds  int64
+	Choices map[string]interface{}
+	Seed    uint64
+	Workers int // Goroutines playing rounds, GOMAXPROCS if 0
+}
+
+// simulationStats accumulates the outcome of a chunk of rounds. Payouts
+// and stakes are multiples of the base stake; a round stakes more than 1
+// when the strategy doubles or splits.
+type simulationStats struct {
+	rounds     int64
+	sum        float64
+	sumSq      float64
+	staked     float64
+	stakedSq   float64
+	sumStakedX float64 // sum of payout * staked
+	hits       int64
+	wins       int64
+	max        float64
+	buckets    []int64
+	returns    []float64
+}
+
+func newSimulationStats() *simulationStats {
+	return &simulationStats{
+		buckets: make([]int64, len(simulationEdges)+1),
+		returns: make([]float64, len(simulationEdges)+1),
+	}
+}
+
+func (st *simulationStats) add(multiplier, staked float64) {
+	st.rounds++
+	st.sum += multiplier
+	st.sumSq += multiplier * multiplier
+	st.staked += staked
+	st.stakedSq += staked * staked
+	st.sumStakedX += multiplier * staked
+	st.max = max(st.max, multiplier)
+	if multiplier > 0 {
+		st.hits++
+	}
+	if multiplier > staked {
+		st.wins++
+	}
+
+	bucket := 0
+	if multiplier > 0 {
+		for bucket < len(simulationEdges) && multiplier >= simulationEdges[bucket] {
+			bucket++
+		}
+	}
+	st.buckets[bucket]++
+	st.returns[bucket] += multiplier
+}
+
+func (st *simulationStats) merge(other *simulationStats) {
+	st.rounds += other.rounds
+	st.sum += other.sum
+	st.sumSq += other.sumSq
+	st.staked += other.staked
+	st.stakedSq += other.stakedSq
+	st.sumStakedX += other.sumStakedX
+	st.hits += other.hits
+	st.wins += other.wins
+	st.max = max(st.max, other.max)
+	for i := range st.buckets {
+		st.buckets[i] += other.buckets[i]
+		st.returns[i] += other.returns[i]
+	}
+}
+
+// Simulate plays opts.Rounds rounds of settings through the game's engine,
+// exactly as a real bet would but with a fast non-cryptographic random
+// source and without touching the wallet or the database. settings need not
+// be saved, so a proposed config can be checked before it goes live.
+// Session games and aviation are played with a fixed strategy taken from
+// opts.Choices (see simulatedRounds).
+func (s *GameService) Simulate(ctx context.Context, settings *models.GameSettings, opts SimulationOptions) (*models.SimulationReport, error) {
+	if opts.Rounds <= 0 {
+		return nil, fmt.Errorf("rounds must be positive")
+	}
+
+	stake := settings.MinBet
+	if stake <= 0 {
+		stake = models.MoneyFromMajor(1)
+	}
+	play, err := s.simulatedRounds(settings, stake, opts.Choices)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	started := time.Now()
+	chunks := int((opts.Rounds + simulationChunk - 1) / simulationChunk)
+	results := make([]*simulationStats, chunks)
+	next := make(chan int)
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range next {
+				rounds := min(int64(simulationChunk), opts.Rounds-int64(chunk)*simulationChunk)
+				rng := rand.New(rand.NewPCG(opts.Seed, uint64(chunk)))
+				stats := newSimulationStats()
+				for i := int64(0); i < rounds; i++ {
+					payout, staked, err := play(rng)
+					if err != nil {
+						fail(err)
+						return
+					}
+					stats.add(payout, staked)
+				}
+				results[chunk] = stats
+			}
+		}()
+	}
+
+feed:
+	for chunk := 0; chunk < chunks; chunk++ {
+		select {
+		case next <- chunk:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Merging in chunk order keeps float sums identical between runs
+	total := newSimulationStats()
+	for _, stats := range results {
+		total.merge(stats)
+	}
+
+	// RTP is paid per unit staked; its margin comes from the variance of
+	// payout - rtp * staked, which is the payout's variance when every
+	// round stakes 1
+	n := float64(total.rounds)
+	rtp := total.sum / total.staked
+	avgStake := total.staked / n
+	mean := total.sum / n
+	variance := max(total.sumSq/n-mean*mean, 0)
+	residual := max((total.sumSq-2*rtp*total.sumStakedX+rtp*rtp*total.stakedSq)/n, 0)
+
+	report := &models.SimulationReport{
+		GameType:        settings.GameType,
+		SettingsVersion: settings.Version,
+		HouseEdge:       settings.HouseEdge,
+		Choices:         opts.Choices,
+		Rounds:          total.rounds,
+		Seed:            opts.Seed,
+		RTP:             rtp,
+		RTPMargin:       1.96 * math.Sqrt(residual/n) / avgStake,
+		AvgStake:        avgStake,
+		TargetRTP:       1 - settings.HouseEdge/100,
+		HitFrequency:    float64(total.hits) / n,
+		WinFrequency:    float64(total.wins) / n,
+		Variance:        variance,
+		StdDev:          math.Sqrt(variance),
+		MaxMultiplier:   total.max,
+		DurationMs:      time.Since(started).Milliseconds(),
+	}
+	for i, count := range total.buckets {
+		bucket := models.SimulationBucket{
+			Rounds: count,
+			Share:  float64(count) / n,
+			Return: total.returns[i] / n,
+		}
+		if i == 0 {
+			bucket.To = &simulationEdges[0]
+		} else {
+			bucket.From = simulationEdges[i-1]
+			if i < len(simulationEdges) {
+				bucket.To = &simulationEdges[i]
+			}
+		}
+		report.Histogram = append(report.Histogram, bucket)
+	}
+	return report, nil
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"fmt"
+
+	"betting-app-backend-go/models"
+)
+
+// simulatedRound plays one simulated round and returns what it paid and
+// what it staked, both as multiples of the base stake
+type simulatedRound func(rng RandomSource) (payout, staked float64, err error)
+
+// simulatedRounds returns how a game is played in a simulation. Single-round
+// games are played through their engine with choices as the player's
+// choices. The others are played with a fixed strategy:
+//
+//   - mines: choices "mines" as in a session, then "reveals" cells before
+//     cashing out
+//   - hilo: guess the likelier direction, skipping cards where it returns
+//     less than 1 - house edge, and cash out once the multiplier reaches
+//     "cashout_at"
+//   - blackjack: basic strategy, never taking insurance
+//   - aviation: cash out automatically at "cashout_at"
+func (s *GameService) simulatedRounds(settings *models.GameSettings, stake models.Money, choices map[string]interface{}) (simulatedRound, error) {
+	if engine, ok := s.engines[settings.GameType]; ok {
+		return func(rng RandomSource) (float64, float64, error) {
+			game, err := engine.Play(&PlayRequest{
+				GameType:  settings.GameType,
+				BetAmount: stake,
+				Choices:   choices,
+			}, settings, rng)
+			if err != nil {
+				return 0, 0, err
+			}
+			return game.Multiplier, 1, nil
+		}, nil
+	}
+
+	if settings.GameType == "aviation" {
+		var cfg models.AviationConfig
+		if err := decodeConfig(settings.Config, &cfg); err != nil {
+			return nil, err
+		}
+		target, err := choiceFloat(choices, "cashout_at")
+		if err != nil {
+			return nil, err
+		}
+		target = models.RoundMultiplier(target)
+		if target <= 1 || target > cfg.MaxMultiplier {
+			return nil, fmt.Errorf("%w: cashout_at must be above 1 and at most %g", ErrInvalidChoice, cfg.MaxMultiplier)
+		}
+		return func(rng RandomSource) (float64, float64, error) {
+			if target <= aviationCrashPoint(&cfg, settings.HouseEdge, rng) {
+				return target, 1, nil
+			}
+			return 0, 1, nil
+		}, nil
+	}
+
+	var engine SessionEngine
+	for _, e := range defaultSessionEngines() {
+		if e.GameType() == settings.GameType {
+			engine = e
+		}
+	}
+	if engine == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoEngine, settings.GameType)
+	}
+	next, err := sessionStrategy(settings, choices)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(rng RandomSource) (float64, float64, error) {
+		session := &models.GameSession{
+			GameType:  settings.GameType,
+			BetAmount: stake,
+			Stake:     stake,
+			Choices:   choices,
+		}
+		if err := engine.Start(session, settings, choices, rng); err != nil {
+			return 0, 0, err
+		}
+		for session.Outcome == "" {
+			action, params := next(session)
+			if err := engine.Act(session, settings, action, params, rng); err != nil {
+				return 0, 0, fmt.Errorf("strategy played %s: %w", action, err)
+			}
+		}
+
+		win := session.BetAmount.MulMultiplier(session.Multiplier)
+		if p, ok := engine.(payoutEngine); ok {
+			win = p.Payout(session)
+		}
+		return float64(win) / float64(stake), float64(session.BetAmount) / float64(stake), nil
+	}, nil
+}
+
+// sessionStrategy returns the next action a simulated player takes in a
+// session of settings' game
+func sessionStrategy(settings *models.GameSettings, choices map[string]interface{}) (func(*models.GameSession) (string, map[string]interface{}), error) {
+	switch settings.GameType {
+	case "mines":
+		var cfg models.MinesConfig
+		if err := decodeConfig(settings.Config, &cfg); err != nil {
+			return nil, err
+		}
+		mines := cfg.DefaultMines
+		if _, ok := choices["mines"]; ok {
+			n, err := choiceInt(choices, "mines")
+			if err != nil {
+				return nil, err
+			}
+			mines = n
+		}
+		reveals, err := choiceInt(choices, "reveals")
+		if err != nil {
+			return nil, err
+		}
+		if safe := cfg.GridSize*cfg.GridSize - mines; reveals < 1 || reveals > safe {
+			return nil, fmt.Errorf("%w: reveals must be between 1 and %d", ErrInvalidChoice, safe)
+		}
+		// Mines are placed uniformly, so which cells are revealed doesn't
+		// matter
+		return func(session *models.GameSession) (string, map[string]interface{}) {
+			revealed := len(session.Mines.Revealed)
+			if revealed >= reveals {
+				return "cashout", nil
+			}
+			return "reveal", map[string]interface{}{"cell": revealed}
+		}, nil
+
+	case "hilo":
+		var cfg models.HiLoConfig
+		if err := decodeConfig(settings.Config, &cfg); err != nil {
+			return nil, err
+		}
+		target, err := choiceFloat(choices, "cashout_at")
+		if err != nil {
+			return nil, err
+		}
+		if target <= 1 {
+			return nil, fmt.Errorf("%w: cashout_at must be above 1", ErrInvalidChoice)
+		}
+
+		// Guess the direction more cards win and skip the cards where that
+		// guess returns less than 1 - house edge because multiplier_per_win
+		// caps it. The tolerance covers hiloFactor rounding down.
+		guesses := make(map[int]string)
+		for rank := 1; rank <= 13; rank++ {
+			guess, winning := "higher", 13-rank
+			if rank-1 > winning {
+				guess, winning = "lower", rank-1
+			}
+			current := string(hiloRanks[rank-1])
+			factor, err := hiloFactor(current, guess, settings.HouseEdge, cfg.MultiplierPerWin)
+			if err == nil && factor*float64(winning)/13 >= 1-settings.HouseEdge/100-0.001 {
+				guesses[rank] = guess
+			}
+		}
+		return func(session *models.GameSession) (string, map[string]interface{}) {
+			state := session.HiLo
+			if state.Streak > 0 && session.Multiplier >= target {
+				return "cashout", nil
+			}
+			rank := hiloRank(state.Cards[len(state.Cards)-1])
+			if guess, ok := guesses[rank]; ok {
+				return guess, nil
+			}
+			// Without any card worth guessing on, guess anyway
+			if len(guesses) == 0 {
+				if rank > 7 {
+					return "lower", nil
+				}
+				return "higher", nil
+			}
+			return "skip", nil
+		}, nil
+
+	case "blackjack":
+		var cfg models.BlackjackConfig
+		if err := decodeConfig(settings.Config, &cfg); err != nil {
+			return nil, err
+		}
+		return func(session *models.GameSession) (string, map[string]interface{}) {
+			table := session.Blackjack
+			if table.InsuranceOffered {
+				return "decline_insurance", nil
+			}
+			canSplit := cfg.AllowSplit && len(table.Hands) < maxBlackjackHands
+			return blackjackBasicStrategy(table.Hands[table.ActiveHand].Cards, table.Dealer[0], cfg.AllowDoubleDown, canSplit), nil
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNoEngine, settings.GameType)
+}
+
+// blackjackBasicStrategy is the textbook multi-deck strategy for a dealer
+// hitting soft 17, without surrender
+func blackjackBasicStrategy(cards []string, upCard string, canDouble, canSplit bool) string {
+	up := cardValue(upCard)
+	between := func(lo, hi int) bool { return up >= lo && up <= hi }
+	double := func(otherwise string) string {
+		if canDouble && len(cards) == 2 {
+			return "double"
+		}
+		return otherwise
+	}
+
+	if canSplit && len(cards) == 2 && cardValue(cards[0]) == cardValue(cards[1]) {
+		switch cardValue(cards[0]) {
+		case 11, 8:
+			return "split"
+		case 9:
+			if between(2, 6) || between(8, 9) {
+				return "split"
+			}
+		case 7, 3, 2:
+			if between(2, 7) {
+				return "split"
+			}
+		case 6:
+			if between(2, 6) {
+				return "split"
+			}
+		case 4:
+			if between(5, 6) {
+				return "split"
+			}
+		}
+	}
+
+	total, soft := handTotal(cards)
+	if soft {
+		switch {
+		case total >= 20:
+			return "stand"
+		case total == 19:
+			if up == 6 {
+				return double("stand")
+			}
+			return "stand"
+		case total == 18:
+			if between(2, 6) {
+				return double("stand")
+			}
+			if between(7, 8) {
+				return "stand"
+			}
+			return "hit"
+		case total == 17:
+			if between(3, 6) {
+				return double("hit")
+			}
+		case total >= 15:
+			if between(4, 6) {
+				return double("hit")
+			}
+		default:
+			if between(5, 6) {
+				return double("hit")
+			}
+		}
+		return "hit"
+	}
+
+	switch {
+	case total >= 17:
+		return "stand"
+	case total >= 13:
+		if between(2, 6) {
+			return "stand"
+		}
+	case total == 12:
+		if between(4, 6) {
+			return "stand"
+		}
+	case total == 11:
+		return double("hit")
+	case total == 10:
+		if between(2, 9) {
+			return double("hit")
+		}
+	case total == 9:
+		if between(3, 6) {
+			return double("hit")
+		}
+	}
+	return "hit"
+}
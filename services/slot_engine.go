@@ -0,0 +1,368 @@
+package services
+
+import (
+	"fmt"
+
+	"betting-app-backend-go/models"
+)
+
+// maxSlotFreeSpins caps the free spins one paid spin can award, retriggers
+// included
+const maxSlotFreeSpins = 100
+
+// SlotEngine spins weighted reel strips and pays every payline, scatters
+// anywhere in view and any free spins they trigger. Free spins are played
+// within the same round at the same bet. A config with only symbols and
+// multipliers plays the classic three reels with one line paying a full
+// line of a symbol.
+type SlotEngine struct{}
+
+func (e *SlotEngine) GameType() string { return "slot" }
+
+func (e *SlotEngine) Play(req *PlayRequest, settings *models.GameSettings, rng RandomSource) (*models.Game, error) {
+	var cfg models.SlotConfig
+	if err := decodeConfig(settings.Config, &cfg); err != nil {
+		return nil, err
+	}
+	machine, err := newSlotMachine(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	result := machine.spin(rng)
+	multiplier := result["multiplier"].(float64)
+
+	awarded := result["free_spins_awarded"].(int)
+	freeSpins := []map[string]interface{}{}
+	for played := 0; played < awarded; played++ {
+		free := machine.spin(rng)
+		freeSpins = append(freeSpins, free)
+		multiplier += free["multiplier"].(float64)
+		awarded = min(awarded+free["free_spins_awarded"].(int), maxSlotFreeSpins)
+	}
+	if len(freeSpins) > 0 {
+		result["free_spins"] = freeSpins
+	}
+
+	return settle(req, multiplier, result), nil
+}
+
+// ExpectedReturn works out the average payout per unit bet exactly. Free
+// spins pay like the paid spin, so it is one spin's return times the
+// expected number of spins played.
+func (e *SlotEngine) ExpectedReturn(settings *models.GameSettings) (float64, error) {
+	var cfg models.SlotConfig
+	if err := decodeConfig(settings.Config, &cfg); err != nil {
+		return 0, err
+	}
+	machine, err := newSlotMachine(&cfg)
+	if err != nil {
+		return 0, err
+	}
+	return machine.expectedReturn(), nil
+}
+
+// slotMachine is a SlotConfig with its defaults filled in
+type slotMachine struct {
+	cfg      *models.SlotConfig
+	reels    int
+	rows     int
+	strips   [][]models.SlotStop
+	weights  []int // total weight of each strip
+	paylines [][]int
+	paytable map[string][]float64
+}
+
+func newSlotMachine(cfg *models.SlotConfig) (*slotMachine, error) {
+	if len(cfg.Symbols) == 0 {
+		return nil, fmt.Errorf("slot has no symbols configured")
+	}
+	m := &slotMachine{cfg: cfg, reels: cfg.Reels, rows: cfg.Rows, strips: cfg.ReelStrips, paylines: cfg.Paylines, paytable: cfg.Paytable}
+	if m.reels == 0 {
+		m.reels = 3
+	}
+	if m.rows == 0 {
+		m.rows = 1
+	}
+
+	if len(m.strips) == 0 {
+		strip := make([]models.SlotStop, len(cfg.Symbols))
+		for i, sym := range cfg.Symbols {
+			strip[i] = models.SlotStop{Symbol: sym, Weight: 1}
+		}
+		m.strips = make([][]models.SlotStop, m.reels)
+		for i := range m.strips {
+			m.strips[i] = strip
+		}
+	}
+	if len(m.strips) != m.reels {
+		return nil, fmt.Errorf("slot has %d reel strips for %d reels", len(m.strips), m.reels)
+	}
+	m.weights = make([]int, m.reels)
+	for i, strip := range m.strips {
+		for _, stop := range strip {
+			m.weights[i] += stop.Weight
+		}
+		if m.weights[i] <= 0 {
+			return nil, fmt.Errorf("slot reel %d has no weighted stops", i)
+		}
+	}
+
+	if len(m.paylines) == 0 {
+		line := make([]int, m.reels)
+		for i := range line {
+			line[i] = m.rows / 2
+		}
+		m.paylines = [][]int{line}
+	}
+
+	if len(m.paytable) == 0 {
+		m.paytable = make(map[string][]float64, len(cfg.Multipliers))
+		for sym, multiplier := range cfg.Multipliers {
+			pays := make([]float64, m.reels)
+			pays[m.reels-1] = multiplier
+			m.paytable[sym] = pays
+		}
+	}
+	return m, nil
+}
+
+// spin stops every reel and evaluates the view. The returned multiplier is
+// of the total bet, which is split evenly across the paylines.
+func (m *slotMachine) spin(rng RandomSource) map[string]interface{} {
+	stops := make([]int, m.reels)
+	view := make([][]string, m.reels)
+	scatters := 0
+	for i, strip := range m.strips {
+		// Weighted pick of the stop shown on the top row
+		r := pickIndex(rng, m.weights[i])
+		for stops[i] = 0; r >= strip[stops[i]].Weight; stops[i]++ {
+			r -= strip[stops[i]].Weight
+		}
+
+		view[i] = make([]string, m.rows)
+		for row := range view[i] {
+			view[i][row] = strip[(stops[i]+row)%len(strip)].Symbol
+			if m.cfg.Scatter != "" && view[i][row] == m.cfg.Scatter {
+				scatters++
+			}
+		}
+	}
+
+	multiplier := 0.0
+	lines := []map[string]interface{}{}
+	for i, payline := range m.paylines {
+		symbols := make([]string, m.reels)
+		for reel, row := range payline {
+			symbols[reel] = view[reel][row]
+		}
+		symbol, count, pay := m.linePay(symbols)
+		if pay > 0 {
+			lines = append(lines, map[string]interface{}{
+				"line":       i,
+				"symbol":     symbol,
+				"count":      count,
+				"multiplier": pay,
+			})
+			multiplier += pay / float64(len(m.paylines))
+		}
+	}
+
+	scatterPay := 0.0
+	if scatters > 0 && scatters <= len(m.cfg.ScatterPays) {
+		scatterPay = m.cfg.ScatterPays[scatters-1]
+		multiplier += scatterPay
+	}
+	awarded := 0
+	if m.cfg.FreeSpinsTrigger > 0 && scatters >= m.cfg.FreeSpinsTrigger {
+		awarded = m.cfg.FreeSpins
+	}
+
+	return map[string]interface{}{
+		"stops":              stops,
+		"reels":              view,
+		"lines":              lines,
+		"scatters":           scatters,
+		"scatter_multiplier": scatterPay,
+		"free_spins_awarded": awarded,
+		"multiplier":         multiplier,
+	}
+}
+
+// linePay finds the best win on one payline, reading from the leftmost
+// reel. Leading wilds either pay as wilds or stand in for the first other
+// symbol, whichever pays more; a scatter ends the line.
+func (m *slotMachine) linePay(symbols []string) (string, int, float64) {
+	wild := m.cfg.Wild
+	wilds := 0
+	for wilds < len(symbols) && wild != "" && symbols[wilds] == wild {
+		wilds++
+	}
+
+	symbol, count, pay := "", 0, 0.0
+	if wilds > 0 {
+		symbol, count, pay = wild, wilds, m.pay(wild, wilds)
+	}
+	if wilds < len(symbols) && symbols[wilds] != m.cfg.Scatter {
+		sym := symbols[wilds]
+		n := wilds + 1
+		for n < len(symbols) && (symbols[n] == sym || (wild != "" && symbols[n] == wild)) {
+			n++
+		}
+		if p := m.pay(sym, n); p > pay {
+			symbol, count, pay = sym, n, p
+		}
+	}
+	return symbol, count, pay
+}
+
+// pay is the multiplier of the line bet for count of symbol in a row
+func (m *slotMachine) pay(symbol string, count int) float64 {
+	pays := m.paytable[symbol]
+	if count < 1 || count > len(pays) {
+		return 0
+	}
+	return pays[count-1]
+}
+
+// expectedReturn is one spin's return times the expected number of spins
+// played
+func (m *slotMachine) expectedReturn() float64 {
+	spin, trigger := m.spinReturn()
+	return spin * (1 + m.expectedFreeSpins(trigger))
+}
+
+// spinReturn is the average multiplier of one spin and the chance that it
+// awards free spins
+func (m *slotMachine) spinReturn() (float64, float64) {
+	ev := 0.0
+	for _, payline := range m.paylines {
+		ev += m.lineReturn(payline) / float64(len(m.paylines))
+	}
+	trigger := 0.0
+	for n, p := range m.scatterOdds() {
+		if n > 0 && n <= len(m.cfg.ScatterPays) {
+			ev += p * m.cfg.ScatterPays[n-1]
+		}
+		if m.cfg.FreeSpinsTrigger > 0 && n >= m.cfg.FreeSpinsTrigger {
+			trigger += p
+		}
+	}
+	return ev, trigger
+}
+
+// symbolOdds is the chance of each symbol showing in a row of a reel
+func (m *slotMachine) symbolOdds(reel, row int) map[string]float64 {
+	strip := m.strips[reel]
+	odds := make(map[string]float64)
+	for stop, s := range strip {
+		odds[strip[(stop+row)%len(strip)].Symbol] += float64(s.Weight) / float64(m.weights[reel])
+	}
+	return odds
+}
+
+// lineReturn is the average pay of one payline. Reels stop independently,
+// so the line's symbols are too; every prefix that decides the pay is
+// weighed by its chance.
+func (m *slotMachine) lineReturn(payline []int) float64 {
+	odds := make([]map[string]float64, m.reels)
+	for reel, row := range payline {
+		odds[reel] = m.symbolOdds(reel, row)
+	}
+
+	prefix := make([]string, 0, m.reels)
+	var walk func(p float64) float64
+	walk = func(p float64) float64 {
+		if len(prefix) == m.reels || m.lineDecided(prefix) {
+			// Pad with a symbol that matches nothing
+			symbols := make([]string, m.reels)
+			copy(symbols, prefix)
+			_, _, pay := m.linePay(symbols)
+			return p * pay
+		}
+		ev := 0.0
+		for symbol, q := range odds[len(prefix)] {
+			prefix = append(prefix, symbol)
+			ev += walk(p * q)
+			prefix = prefix[:len(prefix)-1]
+		}
+		return ev
+	}
+	return walk(1)
+}
+
+// lineDecided reports whether the symbols after prefix can no longer change
+// what the line pays: the run linePay reads has been broken
+func (m *slotMachine) lineDecided(prefix []string) bool {
+	wild := m.cfg.Wild
+	wilds := 0
+	for wilds < len(prefix) && wild != "" && prefix[wilds] == wild {
+		wilds++
+	}
+	if wilds == len(prefix) {
+		return false
+	}
+	symbol := prefix[wilds]
+	if symbol == m.cfg.Scatter {
+		return true
+	}
+	for _, s := range prefix[wilds+1:] {
+		if s != symbol && (wild == "" || s != wild) {
+			return true
+		}
+	}
+	return false
+}
+
+// scatterOdds is the chance of each number of scatters in view, convolved
+// reel by reel
+func (m *slotMachine) scatterOdds() []float64 {
+	odds := []float64{1}
+	for reel, strip := range m.strips {
+		onReel := make([]float64, m.rows+1)
+		for stop, s := range strip {
+			n := 0
+			for row := 0; row < m.rows; row++ {
+				if m.cfg.Scatter != "" && strip[(stop+row)%len(strip)].Symbol == m.cfg.Scatter {
+					n++
+				}
+			}
+			onReel[n] += float64(s.Weight) / float64(m.weights[reel])
+		}
+
+		next := make([]float64, len(odds)+m.rows)
+		for i, p := range odds {
+			for j, q := range onReel {
+				next[i+j] += p * q
+			}
+		}
+		odds = next
+	}
+	return odds
+}
+
+// expectedFreeSpins is the average number of free spins a paid spin leads
+// to. Each spin awards FreeSpins more with probability trigger, capped as
+// in Play, and the round ends once it has played every spin awarded.
+func (m *slotMachine) expectedFreeSpins(trigger float64) float64 {
+	award := m.cfg.FreeSpins
+	if award <= 0 || trigger == 0 {
+		return 0
+	}
+
+	memo := make(map[[2]int]float64)
+	var played func(awarded, done int) float64
+	played = func(awarded, done int) float64 {
+		if done >= awarded {
+			return float64(awarded)
+		}
+		key := [2]int{awarded, done}
+		if v, ok := memo[key]; ok {
+			return v
+		}
+		v := trigger*played(min(awarded+award, maxSlotFreeSpins), done+1) + (1-trigger)*played(awarded, done+1)
+		memo[key] = v
+		return v
+	}
+	return trigger * played(award, 0)
+}
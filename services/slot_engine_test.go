@@ -0,0 +1,268 @@
+package services_test
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"betting-app-backend-go/models"
+	"betting-app-backend-go/services"
+)
+
+// sequenceSource replays fixed values, so a test can pick every outcome
+type sequenceSource struct {
+	values []float64
+	next   int
+}
+
+func (s *sequenceSource) Float64() float64 {
+	v := s.values[s.next%len(s.values)]
+	s.next++
+	return v
+}
+
+// testSlotConfig is a small machine with a wild, a scatter, four lines
+// over two rows and uneven weights. Each strip weighs 6 in total, and
+// every line pay splits evenly into cents across the lines.
+func testSlotConfig() map[string]interface{} {
+	strip := []models.SlotStop{
+		{Symbol: "A", Weight: 1}, {Symbol: "B", Weight: 2}, {Symbol: "W", Weight: 1},
+		{Symbol: "S", Weight: 1}, {Symbol: "C", Weight: 1},
+	}
+	return map[string]interface{}{
+		"symbols":     []string{"A", "B", "C", "W", "S"},
+		"reels":       3,
+		"rows":        2,
+		"reel_strips": [][]models.SlotStop{strip, strip, strip},
+		"paytable": map[string][]float64{
+			"A": {0, 2, 10},
+			"B": {0, 1, 4},
+			"C": {0, 0, 2},
+			"W": {0, 0, 25},
+		},
+		"paylines":     [][]int{{0, 0, 0}, {1, 1, 1}, {0, 1, 0}, {1, 0, 1}},
+		"wild":         "W",
+		"scatter":      "S",
+		"scatter_pays": []float64{0, 1, 5},
+	}
+}
+
+// TestSlotLinePay pins the best win read from the left of one payline
+func TestSlotLinePay(t *testing.T) {
+	cfg := &models.SlotConfig{
+		Symbols: []string{"A", "B", "C", "W", "S"},
+		Paytable: map[string][]float64{
+			"A": {0, 2, 10},
+			"B": {0, 1, 4},
+			"C": {0, 0, 2},
+			"W": {0, 0, 25},
+		},
+		Wild:    "W",
+		Scatter: "S",
+	}
+
+	tests := []struct {
+		symbols []string
+		symbol  string
+		count   int
+		pay     float64
+	}{
+		{[]string{"A", "A", "A"}, "A", 3, 10},
+		{[]string{"A", "A", "B"}, "A", 2, 2},
+		{[]string{"B", "A", "A"}, "", 0, 0},
+		{[]string{"W", "W", "W"}, "W", 3, 25},
+		{[]string{"W", "A", "A"}, "A", 3, 10},
+		{[]string{"W", "W", "C"}, "C", 3, 2},
+		{[]string{"A", "W", "B"}, "A", 2, 2},
+		{[]string{"B", "W", "B"}, "B", 3, 4},
+		{[]string{"S", "A", "A"}, "", 0, 0},
+		{[]string{"W", "S", "A"}, "W", 1, 0},
+		{[]string{"A", "S", "A"}, "", 0, 0},
+	}
+	for _, tt := range tests {
+		symbol, count, pay := services.SlotLinePay(cfg, tt.symbols)
+		if symbol != tt.symbol || count != tt.count || pay != tt.pay {
+			t.Errorf("linePay(%v) = %q x%d paying %v, want %q x%d paying %v",
+				tt.symbols, symbol, count, pay, tt.symbol, tt.count, tt.pay)
+		}
+	}
+}
+
+// TestSlotSpin stops the reels of testSlotConfig on chosen symbols and
+// checks the lines and scatters paid
+func TestSlotSpin(t *testing.T) {
+	engine := &services.SlotEngine{}
+	settings := &models.GameSettings{GameType: "slot", Config: testSlotConfig()}
+	// The value that stops a reel with the symbol on the top row
+	stop := map[string]float64{"A": 0.5 / 6, "B": 1.5 / 6, "W": 3.5 / 6, "S": 4.5 / 6, "C": 5.5 / 6}
+
+	tests := []struct {
+		name       string
+		stops      []string
+		lines      []int
+		scatters   int
+		multiplier float64
+	}{
+		// Views A/B, A/B, A/B: AAA on the top line, BBB on the bottom one
+		{"two lines", []string{"A", "A", "A"}, []int{0, 1}, 0, (10 + 4) / 4.0},
+		// Views W/S, S/C, C/A: the zigzag reads W C C, and two scatters pay
+		{"wild line and scatters", []string{"W", "S", "C"}, []int{2}, 2, 2/4.0 + 1},
+		// Views B/W, C/A, S/C: the wild leads W A C and W C C below
+		{"wild leading", []string{"B", "C", "S"}, []int{1, 3}, 1, (2 + 2) / 4.0},
+		// Views S/C, A/B, B/W: nothing lines up and one scatter pays 0
+		{"no win", []string{"S", "A", "B"}, []int{}, 1, 0},
+	}
+	for _, tt := range tests {
+		values := make([]float64, len(tt.stops))
+		for i, symbol := range tt.stops {
+			values[i] = stop[symbol]
+		}
+		game, err := engine.Play(&services.PlayRequest{GameType: "slot", BetAmount: models.MoneyFromMajor(1)}, settings, &sequenceSource{values: values})
+		if err != nil {
+			t.Fatalf("%s: Play: %v", tt.name, err)
+		}
+
+		var lines []int
+		for _, line := range game.ResultData["lines"].([]map[string]interface{}) {
+			lines = append(lines, line["line"].(int))
+		}
+		if fmt.Sprint(lines) != fmt.Sprint(tt.lines) || game.ResultData["scatters"] != tt.scatters || game.Multiplier != tt.multiplier {
+			t.Errorf("%s: lines %v, %v scatters paying %v, want lines %v, %d scatters paying %v", tt.name,
+				lines, game.ResultData["scatters"], game.Multiplier, tt.lines, tt.scatters, tt.multiplier)
+		}
+	}
+}
+
+// TestSlotExpectedReturnExact plays every combination of reel stops once,
+// each weighted by its chance, and checks the average is what
+// ExpectedReturn works out.
+func TestSlotExpectedReturnExact(t *testing.T) {
+	engine := &services.SlotEngine{}
+	settings := &models.GameSettings{GameType: "slot", Config: testSlotConfig()}
+	const weight, reels = 6, 3
+
+	total := 0.0
+	combinations := int(math.Pow(weight, reels))
+	for c := 0; c < combinations; c++ {
+		// Each value lands on one unit of weight on its reel
+		values := make([]float64, reels)
+		for reel, n := 0, c; reel < reels; reel, n = reel+1, n/weight {
+			values[reel] = (float64(n%weight) + 0.5) / weight
+		}
+		game, err := engine.Play(&services.PlayRequest{GameType: "slot", BetAmount: models.MoneyFromMajor(1)}, settings, &sequenceSource{values: values})
+		if err != nil {
+			t.Fatalf("Play: %v", err)
+		}
+		total += game.Multiplier
+	}
+	played := total / float64(combinations)
+
+	expected, err := engine.ExpectedReturn(settings)
+	if err != nil {
+		t.Fatalf("ExpectedReturn: %v", err)
+	}
+	if math.Abs(expected-played) > 1e-9 {
+		t.Errorf("ExpectedReturn = %.9f, every combination played returns %.9f", expected, played)
+	}
+}
+
+// TestSlotExpectedReturnSimulated checks ExpectedReturn against a seeded
+// run of Play on a machine with free spins, which enumerating can't cover
+func TestSlotExpectedReturnSimulated(t *testing.T) {
+	engine := &services.SlotEngine{}
+	config := testSlotConfig()
+	config["rows"] = 3
+	config["paylines"] = [][]int{{0, 0, 0}, {1, 1, 1}, {2, 2, 2}, {0, 1, 2}, {2, 1, 0}}
+	config["free_spins_trigger"] = 3
+	config["free_spins"] = 5
+	settings := &models.GameSettings{GameType: "slot", Config: config}
+
+	expected, err := engine.ExpectedReturn(settings)
+	if err != nil {
+		t.Fatalf("ExpectedReturn: %v", err)
+	}
+
+	const rounds = 20000
+	rng := rand.New(rand.NewSource(1))
+	sum, sumSq := 0.0, 0.0
+	for i := 0; i < rounds; i++ {
+		game, err := engine.Play(&services.PlayRequest{GameType: "slot", BetAmount: models.MoneyFromMajor(1)}, settings, rng)
+		if err != nil {
+			t.Fatalf("Play: %v", err)
+		}
+		sum += game.Multiplier
+		sumSq += game.Multiplier * game.Multiplier
+	}
+	mean := sum / rounds
+	stdErr := math.Sqrt((sumSq/rounds - mean*mean) / rounds)
+	if math.Abs(mean-expected) > 4*stdErr {
+		t.Errorf("ExpectedReturn = %.4f, %d simulated rounds returned %.4f ± %.4f", expected, rounds, mean, stdErr)
+	}
+}
+
+// TestSlotValidateReturn checks settings are refused once the machine
+// returns more than 1 - house_edge, beyond the tolerance
+func TestSlotValidateReturn(t *testing.T) {
+	engine := &services.SlotEngine{}
+	expected, err := engine.ExpectedReturn(&models.GameSettings{GameType: "slot", Config: testSlotConfig()})
+	if err != nil {
+		t.Fatalf("ExpectedReturn: %v", err)
+	}
+
+	// Without free spins the return scales with the pays
+	scaled := func(ev float64) map[string]interface{} {
+		config := testSlotConfig()
+		f := ev / expected
+		paytable := config["paytable"].(map[string][]float64)
+		for sym, pays := range paytable {
+			for i := range pays {
+				pays[i] *= f
+			}
+			paytable[sym] = pays
+		}
+		scatterPays := config["scatter_pays"].([]float64)
+		for i := range scatterPays {
+			scatterPays[i] *= f
+		}
+		return config
+	}
+
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		valid  bool
+	}{
+		{"at the target", scaled(0.97), true},
+		{"within the tolerance", scaled(0.974), true},
+		{"above the tolerance", scaled(0.976), false},
+		{"unscaled", testSlotConfig(), false},
+		{"full-line multipliers", map[string]interface{}{
+			"symbols":     []string{"A", "B", "C"},
+			"multipliers": map[string]float64{"A": 20, "B": 10, "C": 5},
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := &models.GameSettings{
+				GameType: "slot", MinBet: models.MoneyFromMajor(1), MaxBet: models.MoneyFromMajor(100),
+				HouseEdge: 3, Config: tt.config,
+			}
+			err := services.ValidateGameSettings("slot", settings)
+			if tt.valid {
+				if err != nil {
+					t.Fatalf("ValidateGameSettings: %v", err)
+				}
+				return
+			}
+			var verr *services.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("ValidateGameSettings = %v, want a *ValidationError", err)
+			}
+			if len(verr.Fields) != 1 || verr.Fields[0].Field != "config.paytable" {
+				t.Errorf("ValidateGameSettings = %v, want only config.paytable", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"fmt"
+
+	"betting-app-backend-go/models"
+)
+
+// SpinWheelEngine spins a wheel whose segments are the configured
+// multipliers, each landing with a chance proportional to its weight
+type SpinWheelEngine struct{}
+
+func (e *SpinWheelEngine) GameType() string { return "spinwheel" }
+
+func (e *SpinWheelEngine) Play(req *PlayRequest, settings *models.GameSettings, rng RandomSource) (*models.Game, error) {
+	var cfg models.SpinWheelConfig
+	if err := decodeConfig(settings.Config, &cfg); err != nil {
+		return nil, err
+	}
+	if len(cfg.Multipliers) == 0 {
+		return nil, fmt.Errorf("spinwheel has no segments configured")
+	}
+	weights := spinWheelWeights(&cfg)
+	if len(weights) != len(cfg.Multipliers) {
+		return nil, fmt.Errorf("spinwheel has %d weights for %d segments", len(weights), len(cfg.Multipliers))
+	}
+
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("spinwheel segments have no weight")
+	}
+
+	// With equal weights this is a uniform pick, as before weights existed
+	r := pickIndex(rng, total)
+	segment := 0
+	for r >= weights[segment] {
+		r -= weights[segment]
+		segment++
+	}
+	multiplier := cfg.Multipliers[segment]
+
+	result := map[string]interface{}{
+		"segment":    segment,
+		"multiplier": multiplier,
+	}
+	if segment < len(cfg.Colors) {
+		result["color"] = cfg.Colors[segment]
+	}
+	return settle(req, multiplier, result), nil
+}
+
+// spinWheelWeights returns the weight of every segment, 1 each if none are
+// configured
+func spinWheelWeights(cfg *models.SpinWheelConfig) []int {
+	if len(cfg.Weights) > 0 {
+		return cfg.Weights
+	}
+	weights := make([]int, len(cfg.Multipliers))
+	for i := range weights {
+		weights[i] = 1
+	}
+	return weights
+}
+
+// ExpectedReturn is the weighted average multiplier of the wheel
+func (e *SpinWheelEngine) ExpectedReturn(settings *models.GameSettings) (float64, error) {
+	var cfg models.SpinWheelConfig
+	if err := decodeConfig(settings.Config, &cfg); err != nil {
+		return 0, err
+	}
+	return spinWheelExpectedReturn(&cfg), nil
+}
+
+// spinWheelExpectedReturn is the average payout of a wheel per unit bet
+func spinWheelExpectedReturn(cfg *models.SpinWheelConfig) float64 {
+	weights := spinWheelWeights(cfg)
+	total, paid := 0.0, 0.0
+	for i, m := range cfg.Multipliers {
+		total += float64(weights[i])
+		paid += m * float64(weights[i])
+	}
+	if total == 0 {
+		return 0
+	}
+	return paid / total
+}
@@ -2,7 +2,10 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"sort"
 	"time"
 
 	"betting-app-backend-go/models"
@@ -12,39 +15,58 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ErrInsufficientBalance is returned when a wallet can't cover a debit
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// errAlreadyApplied is returned by applyDelta when a change made at most
+// once per reference has already been applied
+var errAlreadyApplied = errors.New("wallet change already applied")
+
+// maxAppliedRefs is how many references of at-most-once changes a wallet
+// remembers. The list guards changes whose ledger entry may not be posted
+// yet; a reference that has dropped off it is looked up in the ledger,
+// which remembers them for good.
+const maxAppliedRefs = 100
+
+// ledgerAttempts is how often a ledger entry is posted before giving up,
+// waiting ledgerRetryDelay longer after each failure
+const (
+	ledgerAttempts   = 3
+	ledgerRetryDelay = 200 * time.Millisecond
+)
+
+// ErrPaymentRequestProcessed is returned when a payment request is no longer
+// pending, e.g. when two admins act on it at once
+var ErrPaymentRequestProcessed = errors.New("payment request already processed")
+
 type WalletService struct {
-	db *mongo.Database
+	db            *mongo.Database
+	ledger        *LedgerService
+	payoutMethods *PayoutMethodService
+	limits        *LimitService
 }
 
-func NewWalletService(db *mongo.Database) *WalletService {
-	return &WalletService{db: db}
+func NewWalletService(db *mongo.Database, ledger *LedgerService, payoutMethods *PayoutMethodService, limits *LimitService) *WalletService {
+	return &WalletService{db: db, ledger: ledger, payoutMethods: payoutMethods, limits: limits}
 }
 
 // GetOrCreateWallet retrieves user's wallet or creates new one
 func (s *WalletService) GetOrCreateWallet(ctx context.Context, userID string) (*models.Wallet, error) {
 	collection := s.db.Collection("wallets")
 	
+	// Upsert so concurrent first requests can't create two wallets
 	var wallet models.Wallet
-	err := collection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&wallet)
-	
-	if err == mongo.ErrNoDocuments {
-		// Create new wallet
-		wallet = models.Wallet{
-			UserID:        userID,
-			Balance:       0,
-			Currency:      "INR",
-			LockedBalance: 0,
-			LastUpdated:   time.Now(),
-			CreatedAt:     time.Now(),
-		}
-		
-		_, err = collection.InsertOne(ctx, wallet)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create wallet: %w", err)
-		}
-		return &wallet, nil
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"user_id": userID},
+		bson.M{"$setOnInsert": newWalletFields()},
+		opts,
+	).Decode(&wallet)
+	if mongo.IsDuplicateKeyError(err) {
+		// Lost the upsert race; the wallet exists now
+		err = collection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&wallet)
 	}
-	
 	if err != nil {
 		return nil, fmt.Errorf("failed to get wallet: %w", err)
 	}
@@ -52,6 +74,15 @@ func (s *WalletService) GetOrCreateWallet(ctx context.Context, userID string) (*
 	return &wallet, nil
 }
 
+// newWalletFields are the fields set when a wallet is created on first use
+func newWalletFields() bson.M {
+	now := time.Now()
+	return bson.M{
+		"currency":   models.CurrencyINR,
+		"created_at": now,
+	}
+}
+
 // GetBalance returns current wallet balance
 func (s *WalletService) GetBalance(ctx context.Context, userID string) (*models.Wallet, error) {
 	return s.GetOrCreateWallet(ctx, userID)
@@ -61,21 +92,64 @@ func (s *WalletService) GetBalance(ctx context.Context, userID string) (*models.
 func (s *WalletService) CreatePaymentRequest(ctx context.Context, req *models.PaymentRequest) error {
 	collection := s.db.Collection("payment_requests")
 	
+	// A retry with the same Idempotency-Key gets the request already
+	// created, without counting against the deposit limits again
+	if req.IdempotencyKey != "" {
+		err := collection.FindOne(ctx, bson.M{"user_id": req.UserID, "idempotency_key": req.IdempotencyKey}).Decode(req)
+		if err == nil {
+			return nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return fmt.Errorf("failed to get payment request: %w", err)
+		}
+	}
+	
 	req.ID = fmt.Sprintf("req_%d", time.Now().UnixNano())
+	req.Type = "deposit"
+	req.PayoutMethodID = ""
+	req.PayoutDetails = nil
 	req.Status = "pending"
 	req.CreatedAt = time.Now()
 	req.UpdatedAt = time.Now()
 	
+	// The deposit counts against the player's limits from the moment it
+	// is requested; a declined one is given back
+	if s.limits != nil {
+		if err := s.limits.Reserve(ctx, req.UserID, req.CreatedAt, LimitDelta{Deposit: req.Amount}); err != nil {
+			return err
+		}
+	}
+	
 	_, err := collection.InsertOne(ctx, req)
 	if err != nil {
+		s.releaseLimits(ctx, req.UserID, req.CreatedAt, LimitDelta{Deposit: req.Amount})
+		
+		// The unique index keeps a concurrent retry from creating it twice
+		if req.IdempotencyKey != "" && mongo.IsDuplicateKeyError(err) {
+			findErr := collection.FindOne(ctx, bson.M{"user_id": req.UserID, "idempotency_key": req.IdempotencyKey}).Decode(req)
+			if findErr != nil {
+				return fmt.Errorf("failed to get payment request: %w", findErr)
+			}
+			return nil
+		}
 		return fmt.Errorf("failed to create payment request: %w", err)
 	}
 	
+	// Hold the reported deposit in suspense until an admin reviews it
+	s.postEntry(ctx, &models.LedgerEntry{
+		ID:          "led_" + req.ID + "_submitted",
+		UserID:      req.UserID,
+		Category:    "deposit_submitted",
+		Description: fmt.Sprintf("Payment request %s submitted", req.ID),
+		Reference:   req.ID,
+		Postings:    transfer(models.AccountHouseCash, models.AccountPendingDeposits, req.Amount),
+	})
+	
 	return nil
 }
 
 // GetPaymentRequests retrieves payment requests (filtered by user or all for admin)
-func (s *WalletService) GetPaymentRequests(ctx context.Context, userID string, isAdmin bool, status string) ([]models.PaymentRequest, error) {
+func (s *WalletService) GetPaymentRequests(ctx context.Context, userID string, isAdmin bool, status string, requestType string) ([]models.PaymentRequest, error) {
 	collection := s.db.Collection("payment_requests")
 	
 	filter := bson.M{}
@@ -85,6 +159,13 @@ func (s *WalletService) GetPaymentRequests(ctx context.Context, userID string, i
 	if status != "" && status != "all" {
 		filter["status"] = status
 	}
+	switch requestType {
+	case "deposit":
+		// Requests created before withdrawals existed have no type
+		filter["type"] = bson.M{"$in": bson.A{"deposit", nil}}
+	case "withdrawal":
+		filter["type"] = "withdrawal"
+	}
 	
 	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
 	cursor, err := collection.Find(ctx, filter, opts)
@@ -101,7 +182,7 @@ func (s *WalletService) GetPaymentRequests(ctx context.Context, userID string, i
 	return requests, nil
 }
 
-// ProcessPaymentRequest approves or declines a payment request
+// ProcessPaymentRequest approves or declines a deposit or withdrawal request
 func (s *WalletService) ProcessPaymentRequest(ctx context.Context, requestID string, status string, adminNotes string) error {
 	if status != "accepted" && status != "declined" {
 		return fmt.Errorf("invalid status: %s", status)
@@ -117,178 +198,527 @@ func (s *WalletService) ProcessPaymentRequest(ctx context.Context, requestID str
 	}
 	
 	if req.Status != "pending" {
-		return fmt.Errorf("payment request already processed")
+		return ErrPaymentRequestProcessed
+	}
+	
+	// Claim the request by moving it out of pending; only one admin action
+	// can win even if two are processed concurrently
+	err = s.claimPaymentRequest(ctx, bson.M{"_id": requestID, "status": "pending"}, bson.M{
+		"status":      status,
+		"admin_notes": adminNotes,
+	})
+	if err != nil {
+		return err
+	}
+	
+	if req.Type == "withdrawal" {
+		err = s.settleWithdrawal(ctx, &req, status)
+	} else {
+		err = s.settleDeposit(ctx, &req, status)
+	}
+	if err != nil {
+		s.unclaimPaymentRequest(ctx, requestID, status)
+		return err
 	}
 	
-	// Start MongoDB transaction for atomic operation
-	session, err := s.db.Client().StartSession()
+	return nil
+}
+
+// claimPaymentRequest moves a pending request matching filter to a new
+// status with a single conditional update
+func (s *WalletService) claimPaymentRequest(ctx context.Context, filter bson.M, set bson.M) error {
+	set["updated_at"] = time.Now()
+	res, err := s.db.Collection("payment_requests").UpdateOne(ctx, filter, bson.M{"$set": set})
 	if err != nil {
-		return fmt.Errorf("failed to start session: %w", err)
-	}
-	defer session.EndSession(ctx)
-	
-	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
-		// Update payment request status
-		update := bson.M{
-			"$set": bson.M{
-				"status":      status,
-				"admin_notes": adminNotes,
-				"updated_at":  time.Now(),
-			},
+		return fmt.Errorf("failed to update payment request: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrPaymentRequestProcessed
+	}
+	return nil
+}
+
+// unclaimPaymentRequest puts a claimed request back to pending after its
+// wallet change failed, so it can be processed again
+func (s *WalletService) unclaimPaymentRequest(ctx context.Context, requestID string, status string) {
+	_, err := s.db.Collection("payment_requests").UpdateOne(ctx, bson.M{"_id": requestID, "status": status}, bson.M{"$set": bson.M{"status": "pending"}})
+	if err != nil {
+		log.Printf("[Wallet] ❌ Failed to reset payment request %s to pending: %v\n", requestID, err)
+	}
+}
+
+// settleDeposit credits an accepted deposit or releases a declined one
+func (s *WalletService) settleDeposit(ctx context.Context, req *models.PaymentRequest, status string) error {
+	// If declined, release the suspense entry and the deposit limits
+	if status == "declined" {
+		s.postEntry(ctx, &models.LedgerEntry{
+			ID:          "led_" + req.ID + "_declined",
+			UserID:      req.UserID,
+			Category:    "deposit_declined",
+			Description: fmt.Sprintf("Payment request %s declined", req.ID),
+			Reference:   req.ID,
+			Postings:    transfer(models.AccountPendingDeposits, models.AccountHouseCash, req.Amount),
+		})
+		s.releaseLimits(ctx, req.UserID, req.CreatedAt, LimitDelta{Deposit: req.Amount})
+		return nil
+	}
+	
+	// If accepted, credit the wallet
+	_, err := s.changeBalance(ctx, req.UserID, req.Amount, 0, balanceChange{
+		Account:     models.AccountPendingDeposits,
+		Description: fmt.Sprintf("Payment request %s accepted", req.ID),
+		Category:    "deposit",
+		Reference:   req.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to credit wallet: %w", err)
+	}
+	return nil
+}
+
+// CreateWithdrawalRequest locks the amount in the user's wallet and records
+// a pending withdrawal to req.PayoutMethodID, which must be one of the
+// user's verified payout methods. The amount moves from Balance to
+// LockedBalance in one guarded update, so it can't be spent on bets while
+// the request is pending.
+func (s *WalletService) CreateWithdrawalRequest(ctx context.Context, req *models.PaymentRequest) error {
+	if req.Amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	
+	collection := s.db.Collection("payment_requests")
+	
+	// A retried request returns the withdrawal already created
+	if req.IdempotencyKey != "" {
+		err := collection.FindOne(ctx, bson.M{"user_id": req.UserID, "idempotency_key": req.IdempotencyKey}).Decode(req)
+		if err == nil {
+			return nil
 		}
-		
-		_, err := collection.UpdateOne(sessCtx, bson.M{"_id": requestID}, update)
-		if err != nil {
-			return nil, fmt.Errorf("failed to update payment request: %w", err)
+		if err != mongo.ErrNoDocuments {
+			return fmt.Errorf("failed to get payment request: %w", err)
 		}
-		
-		// If accepted, credit the wallet
-		if status == "accepted" {
-			err = s.creditBalanceInTransaction(sessCtx, req.UserID, req.Amount, fmt.Sprintf("Payment request %s accepted", requestID), "deposit")
-			if err != nil {
-				return nil, fmt.Errorf("failed to credit wallet: %w", err)
-			}
+	}
+	
+	method, err := s.payoutMethods.GetVerified(ctx, req.UserID, req.PayoutMethodID)
+	if err != nil {
+		return err
+	}
+	
+	req.ID = fmt.Sprintf("req_%d", time.Now().UnixNano())
+	req.Type = "withdrawal"
+	req.PaymentMethod = method.Type
+	req.PayoutDetails = method.PayoutDetails()
+	req.Status = "pending"
+	req.TransactionID = ""
+	req.ProofURL = ""
+	req.AdminNotes = ""
+	req.CreatedAt = time.Now()
+	req.UpdatedAt = time.Now()
+	
+	_, err = s.changeBalance(ctx, req.UserID, -req.Amount, req.Amount, balanceChange{
+		Account:     models.AccountPendingWithdrawals,
+		Description: fmt.Sprintf("Withdrawal request %s", req.ID),
+		Category:    "withdrawal",
+		Reference:   req.ID,
+	})
+	if err != nil {
+		return err
+	}
+	
+	_, err = collection.InsertOne(ctx, req)
+	if err == nil {
+		return nil
+	}
+	
+	// The request was not stored; give the locked funds back
+	s.releaseWithdrawal(ctx, req, "withdrawal_reversed", fmt.Sprintf("Withdrawal request %s not created", req.ID))
+	
+	// A retry with the same Idempotency-Key gets the request already created
+	if req.IdempotencyKey != "" && mongo.IsDuplicateKeyError(err) {
+		findErr := collection.FindOne(ctx, bson.M{"user_id": req.UserID, "idempotency_key": req.IdempotencyKey}).Decode(req)
+		if findErr != nil {
+			return fmt.Errorf("failed to get payment request: %w", findErr)
 		}
-		
-		return nil, nil
+		return nil
+	}
+	return fmt.Errorf("failed to create withdrawal request: %w", err)
+}
+
+// CancelWithdrawalRequest lets a user cancel their own pending withdrawal;
+// the locked amount returns to their balance
+func (s *WalletService) CancelWithdrawalRequest(ctx context.Context, userID string, requestID string) (*models.PaymentRequest, error) {
+	collection := s.db.Collection("payment_requests")
+	
+	var req models.PaymentRequest
+	err := collection.FindOne(ctx, bson.M{"_id": requestID, "user_id": userID, "type": "withdrawal"}).Decode(&req)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("withdrawal request not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get withdrawal request: %w", err)
+	}
+	
+	err = s.claimPaymentRequest(ctx, bson.M{"_id": requestID, "user_id": userID, "status": "pending"}, bson.M{"status": "cancelled"})
+	if err != nil {
+		return nil, err
+	}
+	
+	if err := s.settleWithdrawal(ctx, &req, "cancelled"); err != nil {
+		s.unclaimPaymentRequest(ctx, requestID, "cancelled")
+		return nil, err
+	}
+	
+	req.Status = "cancelled"
+	req.UpdatedAt = time.Now()
+	return &req, nil
+}
+
+// settleWithdrawal pays out an approved withdrawal from the locked funds, or
+// returns them to the balance when it is declined or cancelled
+func (s *WalletService) settleWithdrawal(ctx context.Context, req *models.PaymentRequest, status string) error {
+	if status != "accepted" {
+		_, err := s.releaseWithdrawal(ctx, req, "withdrawal_"+status, fmt.Sprintf("Withdrawal request %s %s", req.ID, status))
+		return err
+	}
+	
+	_, err := s.changeBalance(ctx, req.UserID, 0, -req.Amount, balanceChange{
+		Description: fmt.Sprintf("Withdrawal request %s paid out", req.ID),
+		Category:    "withdrawal_paid",
+		Reference:   req.ID,
+		Postings:    transfer(models.AccountPendingWithdrawals, models.AccountHouseCash, req.Amount),
 	})
+	if err != nil {
+		return fmt.Errorf("failed to pay out withdrawal: %w", err)
+	}
+	return nil
+}
+
+// releaseWithdrawal moves a withdrawal's locked amount back to the balance
+func (s *WalletService) releaseWithdrawal(ctx context.Context, req *models.PaymentRequest, category string, description string) (*models.Wallet, error) {
+	wallet, err := s.changeBalance(ctx, req.UserID, req.Amount, -req.Amount, balanceChange{
+		Account:     models.AccountPendingWithdrawals,
+		Description: description,
+		Category:    category,
+		Reference:   req.ID,
+	})
+	if err != nil {
+		log.Printf("[Wallet] ❌ Failed to release withdrawal %s: %v\n", req.ID, err)
+		return nil, fmt.Errorf("failed to release locked funds: %w", err)
+	}
+	return wallet, nil
+}
+
+// DeductBalance deducts amount from wallet (for game bets). The balance
+// check and the debit are a single conditional update, so concurrent bets
+// can never overdraw the wallet. A non-empty reference (the game or bet
+// the stake is for) makes the debit idempotent: it is taken at most once
+// per category and reference, and a repeat returns nil.
+//
+// Every bet goes through here, so this is where bets (category
+// "game_loss") are held to the player's wager and loss limits: the stake
+// is reserved against the limits of the periods containing at, the time
+// the bet was placed, and given back if the wallet can't cover it.
+func (s *WalletService) DeductBalance(ctx context.Context, userID string, amount models.Money, description string, category string, reference string, at time.Time) error {
+	if amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
 	
+	bet := LimitDelta{Wager: amount, Loss: amount}
+	if category == "game_loss" && s.limits != nil {
+		if err := s.limits.Reserve(ctx, userID, at, bet); err != nil {
+			return err
+		}
+	}
+	
+	_, err := s.changeBalance(ctx, userID, -amount, 0, balanceChange{
+		Account:     counterAccount(category),
+		Description: description,
+		Category:    category,
+		Reference:   reference,
+		Once:        reference != "",
+	})
+	if err != nil && category == "game_loss" {
+		s.releaseLimits(ctx, userID, at, bet)
+	}
+	if err == errAlreadyApplied {
+		return nil
+	}
 	return err
 }
 
-// DeductBalance deducts amount from wallet (for game bets)
-func (s *WalletService) DeductBalance(ctx context.Context, userID string, amount float64, description string, category string) error {
+// CreditBalance adds amount to wallet (for game wins), creating the wallet
+// if needed. A win takes its amount off the player's losses; a refunded
+// bet no longer counts as wagered either. Both are released in the periods
+// containing at, the time the bet was reserved, so a win paid after a
+// period rolls over comes off the period the stake counted against. Like
+// DeductBalance, a non-empty reference credits at most once, so a failed
+// payout can simply be retried.
+func (s *WalletService) CreditBalance(ctx context.Context, userID string, amount models.Money, description string, category string, reference string, at time.Time) error {
 	if amount <= 0 {
 		return fmt.Errorf("amount must be positive")
 	}
 	
-	walletsCol := s.db.Collection("wallets")
-	transactionsCol := s.db.Collection("transactions")
+	_, err := s.changeBalance(ctx, userID, amount, 0, balanceChange{
+		Account:     counterAccount(category),
+		Description: description,
+		Category:    category,
+		Reference:   reference,
+		Once:        reference != "",
+	})
+	if err == errAlreadyApplied {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
 	
-	// Start transaction
-	session, err := s.db.Client().StartSession()
+	switch category {
+	case "game_win":
+		s.releaseLimits(ctx, userID, at, LimitDelta{Loss: amount})
+	case "game_refund":
+		s.releaseLimits(ctx, userID, at, LimitDelta{Wager: amount, Loss: amount})
+	}
+	return nil
+}
+
+// AppliedUnder returns the ledger entries of the at-most-once changes of
+// category made to the user's wallet under references starting with prefix
+func (s *WalletService) AppliedUnder(ctx context.Context, userID string, category string, prefix string) ([]models.LedgerEntry, error) {
+	return s.ledger.EntriesUnder(ctx, userID, category, prefix)
+}
+
+// Applied reports whether the at-most-once change of category with
+// reference has been made to the user's wallet
+func (s *WalletService) Applied(ctx context.Context, userID string, category string, reference string) (bool, error) {
+	count, err := s.db.Collection("wallets").CountDocuments(ctx, bson.M{"user_id": userID, "applied_refs": appliedRef(category, reference)})
 	if err != nil {
-		return fmt.Errorf("failed to start session: %w", err)
+		return false, fmt.Errorf("failed to get wallet: %w", err)
+	}
+	if count > 0 {
+		return true, nil
 	}
-	defer session.EndSession(ctx)
 	
-	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
-		// Get current wallet
-		var wallet models.Wallet
-		err := walletsCol.FindOne(sessCtx, bson.M{"user_id": userID}).Decode(&wallet)
-		if err != nil {
-			return nil, fmt.Errorf("wallet not found: %w", err)
-		}
-		
-		if wallet.Balance < amount {
-			return nil, fmt.Errorf("insufficient balance")
-		}
-		
-		balanceBefore := wallet.Balance
-		balanceAfter := balanceBefore - amount
+	// Older changes have dropped off the wallet's list but are in the ledger
+	return s.ledger.Referenced(ctx, userID, category, reference)
+}
+
+// appliedRef is how a wallet remembers an at-most-once change
+func appliedRef(category, reference string) string {
+	return category + ":" + reference
+}
+
+// releaseLimits gives usage back to the player's limits. A failure only
+// leaves the limits stricter than they should be, so it is logged.
+func (s *WalletService) releaseLimits(ctx context.Context, userID string, at time.Time, delta LimitDelta) {
+	if s.limits == nil {
+		return
+	}
+	if err := s.limits.Release(ctx, userID, at, delta); err != nil {
+		log.Printf("[Limits] ❌ Failed to release limit usage for user %s: %v\n", userID, err)
+	}
+}
+
+// AdjustBalance applies an admin correction to a wallet. A positive amount
+// credits the user, a negative one debits them. The other side of the entry
+// is the house bankroll or the bonus liability.
+func (s *WalletService) AdjustBalance(ctx context.Context, userID string, amount models.Money, account string, reason string, adminUID string) (*models.Wallet, error) {
+	if amount == 0 {
+		return nil, fmt.Errorf("amount must not be zero")
+	}
+	if account == "" {
+		account = models.AccountHouseBankroll
+	}
+	if account != models.AccountHouseBankroll && account != models.AccountBonusLiability {
+		return nil, fmt.Errorf("invalid account: %s", account)
+	}
+	if reason == "" {
+		return nil, fmt.Errorf("reason is required")
+	}
+	
+	return s.changeBalance(ctx, userID, amount, 0, balanceChange{
+		Account:     account,
+		Description: reason,
+		Category:    "admin_adjustment",
+		CreatedBy:   adminUID,
+	})
+}
+
+// counterAccount is the ledger account on the other side of a wallet
+// change of the given category
+func counterAccount(category string) string {
+	switch category {
+	case "deposit":
+		return models.AccountPendingDeposits
+	case "bonus":
+		return models.AccountBonusLiability
+	}
+	return models.AccountHouseBankroll
+}
+
+// balanceChange describes the ledger side of a wallet change
+type balanceChange struct {
+	Account     string // Counter account
+	Description string
+	Category    string
+	Reference   string
+	CreatedBy   string
+	
+	// Once applies the change at most once per Category and Reference
+	Once bool
+	
+	// Postings replaces the default transfer between the user's account
+	// and Account, for changes that only move locked funds
+	Postings []models.LedgerPosting
+}
+
+// changeBalance applies the deltas to the wallet and posts the matching
+// ledger entry between the user's account and change.Account
+func (s *WalletService) changeBalance(ctx context.Context, userID string, delta models.Money, lockedDelta models.Money, change balanceChange) (*models.Wallet, error) {
+	once := ""
+	if change.Once {
+		once = appliedRef(change.Category, change.Reference)
 		
-		// Update wallet
-		_, err = walletsCol.UpdateOne(
-			sessCtx,
-			bson.M{"user_id": userID},
-			bson.M{
-				"$set": bson.M{
-					"balance":      balanceAfter,
-					"last_updated": time.Now(),
-				},
-			},
-		)
+		// A burst of changes can push a reference off the wallet's list
+		// while its change is still being retried
+		posted, err := s.ledger.Referenced(ctx, userID, change.Category, change.Reference)
 		if err != nil {
-			return nil, fmt.Errorf("failed to update wallet: %w", err)
+			return nil, err
 		}
-		
-		// Create transaction record
-		transaction := models.Transaction{
-			ID:            fmt.Sprintf("txn_%d", time.Now().UnixNano()),
-			UserID:        userID,
-			Type:          "debit",
-			Amount:        amount,
-			Description:   description,
-			Category:      category,
-			BalanceBefore: balanceBefore,
-			BalanceAfter:  balanceAfter,
-			Status:        "completed",
-			CreatedAt:     time.Now(),
+		if posted {
+			return nil, errAlreadyApplied
 		}
-		
-		_, err = transactionsCol.InsertOne(sessCtx, transaction)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	wallet, err := s.applyDelta(ctx, userID, delta, lockedDelta, once)
+	if err != nil {
+		return nil, err
+	}
+	
+	postings := change.Postings
+	if postings == nil {
+		postings = transfer(change.Account, models.UserAccount(userID), delta)
+		if delta < 0 {
+			postings = transfer(models.UserAccount(userID), change.Account, -delta)
 		}
-		
-		return nil, nil
+	}
+	
+	// Each version of a wallet is reached by exactly one change, so the
+	// entry ID can't collide with another entry and a retried post can't
+	// write it twice
+	s.postEntry(ctx, &models.LedgerEntry{
+		ID:            fmt.Sprintf("led_%s_v%d", userID, wallet.Version),
+		UserID:        userID,
+		Category:      change.Category,
+		Description:   change.Description,
+		Reference:     change.Reference,
+		Postings:      postings,
+		BalanceBefore: wallet.Balance - delta,
+		BalanceAfter:  wallet.Balance,
+		WalletVersion: wallet.Version,
+		CreatedBy:     change.CreatedBy,
 	})
 	
-	return err
+	return wallet, nil
 }
 
-// CreditBalance adds amount to wallet (for game wins)
-func (s *WalletService) CreditBalance(ctx context.Context, userID string, amount float64, description string, category string) error {
-	return s.creditBalanceInTransaction(ctx, userID, amount, description, category)
+// postEntry writes a ledger entry for a money movement that has already
+// happened, retrying up to ledgerAttempts times. Entry IDs are derived from
+// the change, so a duplicate key means an earlier attempt was stored. The
+// change can't be undone once the wallet moved, so a post that still fails
+// is logged: the wallet is the source of truth for the balance, and the
+// trial balance reports wallets that no longer match their ledger account.
+func (s *WalletService) postEntry(ctx context.Context, entry *models.LedgerEntry) {
+	var err error
+	for attempt := 1; attempt <= ledgerAttempts; attempt++ {
+		err = s.ledger.Post(ctx, entry)
+		if err == nil || mongo.IsDuplicateKeyError(err) {
+			return
+		}
+		if attempt < ledgerAttempts {
+			time.Sleep(time.Duration(attempt) * ledgerRetryDelay)
+		}
+	}
+	log.Printf("[Ledger] ❌ Failed to post %s entry %s for user %s after %d attempts: %v\n", entry.Category, entry.ID, entry.UserID, ledgerAttempts, err)
 }
 
-// creditBalanceInTransaction is internal method that can be used within transactions
-func (s *WalletService) creditBalanceInTransaction(ctx context.Context, userID string, amount float64, description string, category string) error {
-	if amount <= 0 {
-		return fmt.Errorf("amount must be positive")
-	}
-	
+// applyDelta atomically adds the deltas to a wallet's balance and locked
+// balance with a single $inc. Negative deltas are guarded in the filter so
+// neither balance can go below zero. Every change bumps the wallet version,
+// which orders the wallet's ledger entries; the update is not matched on
+// it, as the guards make a read-modify-write cycle unnecessary. A
+// non-empty once is recorded in the same update and guarded in the
+// filter, so that change is never applied twice; a repeat returns
+// errAlreadyApplied. Returns the wallet as it is after the update.
+func (s *WalletService) applyDelta(ctx context.Context, userID string, balanceDelta, lockedDelta models.Money, once string) (*models.Wallet, error) {
 	walletsCol := s.db.Collection("wallets")
-	transactionsCol := s.db.Collection("transactions")
 	
-	// Get or create wallet
-	wallet, err := s.GetOrCreateWallet(ctx, userID)
-	if err != nil {
-		return fmt.Errorf("failed to get wallet: %w", err)
+	filter := bson.M{"user_id": userID}
+	if balanceDelta < 0 {
+		filter["balance"] = bson.M{"$gte": -balanceDelta}
+	}
+	if lockedDelta < 0 {
+		filter["locked_balance"] = bson.M{"$gte": -lockedDelta}
+	}
+	if once != "" {
+		filter["applied_refs"] = bson.M{"$ne": once}
 	}
 	
-	balanceBefore := wallet.Balance
-	balanceAfter := balanceBefore + amount
+	// Only pure credits may create the wallet; a guarded debit must not
+	// upsert a fresh wallet when the guard fails
+	upsert := balanceDelta >= 0 && lockedDelta >= 0
 	
-	// Update wallet
-	_, err = walletsCol.UpdateOne(
-		ctx,
-		bson.M{"user_id": userID},
-		bson.M{
-			"$set": bson.M{
-				"balance":      balanceAfter,
-				"last_updated": time.Now(),
-			},
+	update := bson.M{
+		"$inc": bson.M{
+			"balance":        balanceDelta,
+			"locked_balance": lockedDelta,
+			"version":        1,
 		},
-	)
-	if err != nil {
-		return fmt.Errorf("failed to update wallet: %w", err)
+		"$set": bson.M{"last_updated": time.Now()},
 	}
-	
-	// Create transaction record
-	transaction := models.Transaction{
-		ID:            fmt.Sprintf("txn_%d", time.Now().UnixNano()),
-		UserID:        userID,
-		Type:          "credit",
-		Amount:        amount,
-		Description:   description,
-		Category:      category,
-		BalanceBefore: balanceBefore,
-		BalanceAfter:  balanceAfter,
-		Status:        "completed",
-		CreatedAt:     time.Now(),
+	if upsert {
+		update["$setOnInsert"] = newWalletFields()
+	}
+	if once != "" {
+		update["$push"] = bson.M{"applied_refs": bson.M{"$each": bson.A{once}, "$slice": -maxAppliedRefs}}
 	}
 	
-	_, err = transactionsCol.InsertOne(ctx, transaction)
+	var wallet models.Wallet
+	opts := options.FindOneAndUpdate().SetUpsert(upsert).SetReturnDocument(options.After)
+	err := walletsCol.FindOneAndUpdate(ctx, filter, update, opts).Decode(&wallet)
+	if upsert && mongo.IsDuplicateKeyError(err) {
+		// Lost the race to create the wallet; it exists now
+		err = walletsCol.FindOneAndUpdate(ctx, filter, update, opts).Decode(&wallet)
+	}
+	if once != "" && (err == mongo.ErrNoDocuments || mongo.IsDuplicateKeyError(err)) {
+		// The filter also fails when the change was already applied; a
+		// credit's upsert then runs into the existing wallet
+		count, countErr := walletsCol.CountDocuments(ctx, bson.M{"user_id": userID, "applied_refs": once})
+		if countErr == nil && count > 0 {
+			return nil, errAlreadyApplied
+		}
+	}
+	if err == mongo.ErrNoDocuments {
+		count, countErr := walletsCol.CountDocuments(ctx, bson.M{"user_id": userID})
+		if countErr == nil && count == 0 {
+			return nil, fmt.Errorf("wallet not found")
+		}
+		return nil, ErrInsufficientBalance
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create transaction: %w", err)
+		return nil, fmt.Errorf("failed to update wallet: %w", err)
 	}
 	
-	return nil
+	return &wallet, nil
 }
 
-// GetTransactions retrieves transaction history for a user
+// GetTransactions retrieves transaction history for a user. History comes
+// from the ledger; entries written to the old transactions collection
+// before the ledger existed are merged in.
 func (s *WalletService) GetTransactions(ctx context.Context, userID string, limit int64) ([]models.Transaction, error) {
+	transactions, err := s.ledger.GetUserTransactions(ctx, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	
 	collection := s.db.Collection("transactions")
 	
 	opts := options.Find().
@@ -301,11 +731,19 @@ func (s *WalletService) GetTransactions(ctx context.Context, userID string, limi
 	}
 	defer cursor.Close(ctx)
 	
-	var transactions []models.Transaction
-	if err = cursor.All(ctx, &transactions); err != nil {
+	var legacy []models.Transaction
+	if err = cursor.All(ctx, &legacy); err != nil {
 		return nil, fmt.Errorf("failed to decode transactions: %w", err)
 	}
 	
+	transactions = append(transactions, legacy...)
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].CreatedAt.After(transactions[j].CreatedAt)
+	})
+	if int64(len(transactions)) > limit {
+		transactions = transactions[:limit]
+	}
+	
 	return transactions, nil
 }
 
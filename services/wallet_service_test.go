@@ -0,0 +1,243 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"betting-app-backend-go/models"
+	"betting-app-backend-go/services"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testDB connects to MONGODB_URI and returns a throwaway database that is
+// dropped when the test ends. A standalone server is enough; no replica
+// set is needed. Tests are skipped when MONGODB_URI is not set.
+func testDB(t *testing.T) *mongo.Database {
+	t.Helper()
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		t.Skip("MONGODB_URI not set")
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	db := client.Database(fmt.Sprintf("betting_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		db.Drop(ctx)
+		client.Disconnect(ctx)
+	})
+
+	_, err = db.Collection("wallets").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		t.Fatalf("failed to create index: %v", err)
+	}
+	return db
+}
+
+func newTestWalletService(db *mongo.Database) *services.WalletService {
+	return services.NewWalletService(db, services.NewLedgerService(db), services.NewPayoutMethodService(db), services.NewLimitService(db))
+}
+
+// TestConcurrentBets fires hundreds of parallel bets, half of them
+// followed by a win, at a wallet that can only cover some of them. The
+// guarded $inc must never overdraw the wallet or lose an update, and the
+// ledger must agree with the wallet afterwards.
+func TestConcurrentBets(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	walletService := newTestWalletService(db)
+
+	const bets = 500
+	userID := fmt.Sprintf("stress_%d", time.Now().UnixNano())
+	bet := models.MoneyFromMajor(10)
+	win := bet / 2
+	funds := models.MoneyFromMajor(2000)
+
+	if err := walletService.CreditBalance(ctx, userID, funds, "stress funding", "deposit", "", time.Now()); err != nil {
+		t.Fatalf("failed to fund wallet: %v", err)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		accepted  int
+		rejected  int
+		credited  int
+		otherErrs []error
+	)
+	start := make(chan struct{})
+	for i := 0; i < bets; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+
+			err := walletService.DeductBalance(ctx, userID, bet, "stress bet", "game_loss", "", time.Now())
+			won := false
+			if err == nil && i%2 == 0 {
+				if err = walletService.CreditBalance(ctx, userID, win, "stress win", "game_win", "", time.Now()); err != nil {
+					err = fmt.Errorf("credit after accepted bet: %w", err)
+				}
+				won = err == nil
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if won {
+				credited++
+			}
+			switch {
+			case err == nil:
+				accepted++
+			case errors.Is(err, services.ErrInsufficientBalance):
+				rejected++
+			default:
+				otherErrs = append(otherErrs, err)
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	if len(otherErrs) > 0 {
+		t.Fatalf("%d unexpected errors, first: %v", len(otherErrs), otherErrs[0])
+	}
+	if rejected == 0 {
+		t.Errorf("expected some bets to be rejected, the wallet can't cover all %d", bets)
+	}
+
+	wallet, err := walletService.GetBalance(ctx, userID)
+	if err != nil {
+		t.Fatalf("failed to read wallet: %v", err)
+	}
+	expected := funds - models.Money(accepted)*bet + models.Money(credited)*win
+	if wallet.Balance < 0 {
+		t.Errorf("balance went negative: %s", wallet.Balance)
+	}
+	if wallet.Balance != expected {
+		t.Errorf("balance %s, expected %s (%d accepted, %d credited)", wallet.Balance, expected, accepted, credited)
+	}
+	changes := int64(1 + accepted + credited)
+	if wallet.Version != changes {
+		t.Errorf("version %d, expected one per change: %d", wallet.Version, changes)
+	}
+
+	// The ledger holds one entry per change and its totals match the wallet
+	account := models.UserAccount(userID)
+	entries, err := db.Collection("ledger_entries").CountDocuments(ctx, bson.M{"postings.account": account})
+	if err != nil {
+		t.Fatalf("failed to count ledger entries: %v", err)
+	}
+	if entries != changes {
+		t.Errorf("%d ledger entries, expected %d", entries, changes)
+	}
+
+	trial, err := services.NewLedgerService(db).TrialBalance(ctx)
+	if err != nil {
+		t.Fatalf("failed to compute trial balance: %v", err)
+	}
+	if !trial.Balanced {
+		t.Errorf("ledger is unbalanced: debit %s, credit %s", trial.TotalDebit, trial.TotalCredit)
+	}
+	var ledgerBalance models.Money
+	for _, line := range trial.Accounts {
+		if line.Account == account {
+			// A user account is a liability: credits add to the wallet
+			ledgerBalance = line.Credit - line.Debit
+		}
+	}
+	if ledgerBalance != wallet.Balance {
+		t.Errorf("ledger balance %s does not match wallet balance %s", ledgerBalance, wallet.Balance)
+	}
+	if len(trial.WalletMismatches) > 0 {
+		t.Errorf("wallet does not match its ledger account: %+v", trial.WalletMismatches)
+	}
+}
+
+// TestCreditWithReferenceIsAppliedOnce retries the same payout in parallel;
+// only one may reach the wallet
+func TestCreditWithReferenceIsAppliedOnce(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	walletService := newTestWalletService(db)
+
+	userID := fmt.Sprintf("once_%d", time.Now().UnixNano())
+	win := models.MoneyFromMajor(25)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- walletService.CreditBalance(ctx, userID, win, "game win", "game_win", "game_1", time.Now())
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("retried credit failed: %v", err)
+		}
+	}
+
+	wallet, err := walletService.GetBalance(ctx, userID)
+	if err != nil {
+		t.Fatalf("failed to read wallet: %v", err)
+	}
+	if wallet.Balance != win {
+		t.Errorf("balance %s, expected a single credit of %s", wallet.Balance, win)
+	}
+	applied, err := walletService.Applied(ctx, userID, "game_win", "game_1")
+	if err != nil || !applied {
+		t.Errorf("credit not reported as applied: %v, %v", applied, err)
+	}
+}
+
+// TestCreditIsAppliedOnceAfterManyChanges retries a payout after enough
+// other bets that its reference has dropped off the wallet's list; the
+// ledger must still stop it from being paid twice
+func TestCreditIsAppliedOnceAfterManyChanges(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	walletService := newTestWalletService(db)
+
+	userID := fmt.Sprintf("evicted_%d", time.Now().UnixNano())
+	win := models.MoneyFromMajor(500)
+	bet := models.MoneyFromMajor(1)
+
+	if err := walletService.CreditBalance(ctx, userID, win, "game win", "game_win", "game_1", time.Now()); err != nil {
+		t.Fatalf("failed to credit win: %v", err)
+	}
+	const bets = 150
+	for i := 0; i < bets; i++ {
+		if err := walletService.DeductBalance(ctx, userID, bet, "game bet", "game_loss", fmt.Sprintf("game_%d", i+2), time.Now()); err != nil {
+			t.Fatalf("bet %d failed: %v", i, err)
+		}
+	}
+
+	if err := walletService.CreditBalance(ctx, userID, win, "game win", "game_win", "game_1", time.Now()); err != nil {
+		t.Fatalf("retried credit failed: %v", err)
+	}
+	wallet, err := walletService.GetBalance(ctx, userID)
+	if err != nil {
+		t.Fatalf("failed to read wallet: %v", err)
+	}
+	if expected := win - bets*bet; wallet.Balance != expected {
+		t.Errorf("balance %s, expected %s with the win paid once", wallet.Balance, expected)
+	}
+}